@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// adPorts are the TCP ports of Active Directory services, by number.
+var adPorts = map[string]string{
+	"88":   "Kerberos",
+	"389":  "LDAP",
+	"445":  "SMB",
+	"636":  "LDAPS",
+	"3268": "Global Catalog",
+	"3269": "Global Catalog (TLS)",
+	"5985": "WinRM",
+	"5986": "WinRM (TLS)",
+}
+
+// adColumns are the default columns of the ad table, one row per host.
+var adColumns = []string{"ad-host", "domain", "ad-role", "ad-ports", "ad-os"}
+
+// isADPort reports whether port is one of the adPorts.
+func isADPort(port *NmapPort) bool {
+	_, ok := adPorts[port.Portid]
+	return ok && port.Protocol == "tcp"
+}
+
+// adHost returns the address of the host of row, followed by its hostname.
+func adHost(row *ReportRow) string {
+	host := row.Hosts[0]
+	if host.Hostname != "" {
+		return fmt.Sprintf("%s (%s)", host.Addr, host.Hostname)
+	}
+	return host.Addr
+}
+
+// adPortList returns the AD services of the host of row, in port order.
+func adPortList(row *ReportRow) string {
+	var ports []string
+	for _, host := range row.Hosts {
+		if name, ok := adPorts[host.Port]; ok && !slices.Contains(ports, host.Port+" "+name) {
+			ports = append(ports, host.Port+" "+name)
+		}
+	}
+	slices.SortFunc(ports, func(a, b string) int {
+		return atoi(strings.Fields(a)[0]) - atoi(strings.Fields(b)[0])
+	})
+	return strings.Join(ports, ", ")
+}
+
+// adRole guesses the role of the host of row from its AD ports, see
+// likelyDomainController.
+func adRole(row *ReportRow) string {
+	has := make(map[string]bool)
+	for _, host := range row.Hosts {
+		has[host.Port] = true
+	}
+	switch {
+	case likelyDomainController(has):
+		return "likely domain controller"
+	case has["5985"] || has["5986"]:
+		return "WinRM"
+	}
+	return ""
+}
+
+// likelyDomainController reports whether a host with the open ports has is
+// likely a domain controller: Kerberos along with LDAP or the global catalog.
+func likelyDomainController(has map[string]bool) bool {
+	return has["88"] && (has["389"] || has["636"] || has["3268"] || has["3269"])
+}
+
+// rowDetail returns the value of a column for the script detail key of the
+// first host of a row that has it, for details of the host rather than port.
+func rowDetail(key string) func(row *ReportRow) string {
+	return func(row *ReportRow) string {
+		for _, host := range row.Hosts {
+			if value := host.Details[key]; value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+}
+
+// serviceDomain returns the AD domain in the extra information nmap reports
+// for LDAP, e.g. "Domain: corp.example.com, Site: Default-First-Site-Name".
+func serviceDomain(extrainfo string) string {
+	for _, field := range strings.Split(extrainfo, ",") {
+		if domain, ok := strings.CutPrefix(strings.TrimSpace(field), "Domain: "); ok {
+			// Older nmap versions end the domain with a root dot and a
+			// trailing zero, e.g. corp.example.com0.
+			return strings.TrimSuffix(strings.TrimSuffix(domain, "0."), ".")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// pseudonymKind is the kind of value a pseudonym replaces.
+type pseudonymKind int
+
+const (
+	pseudonymName pseudonymKind = iota
+	pseudonymIPv4
+	pseudonymIPv6
+	pseudonymMAC
+)
+
+// pseudonymRange is the benchmarking range IPv4 pseudonyms are given from.
+var pseudonymRange = netip.MustParsePrefix("198.18.0.0/15")
+
+// networkPseudonym is an IPv4 network and its pseudonym network, of the same
+// size.
+type networkPseudonym struct {
+	original, pseudonym netip.Prefix
+}
+
+// anonymizeKeyHeader starts the key files written by anonymize.
+const anonymizeKeyHeader = `# nmapTables anonymize key: each original address or hostname and its pseudonym.
+# Keep it private, it reverses the anonymized scans (see anonymize --restore).
+`
+
+// newAnonymizer returns the redactor of anonymize, giving pseudonyms to the
+// addresses and hostnames of the scans. The pseudonyms of the key file at
+// keyFile, if it exists, are kept, so that the pseudonyms of a host are
+// stable from one run to the next.
+func newAnonymizer(keyFile string) (*redactor, error) {
+	r, err := loadRedactor(true, "")
+	if _, statErr := os.Stat(keyFile); statErr == nil {
+		r, err = loadRedactor(true, keyFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.pseudonymize = true
+	r.counts = make(map[string]int)
+	r.used = make(map[string]bool)
+	for original, pseudonym := range r.mapping {
+		r.used[strings.ToLower(pseudonym)] = true
+		if network, err := netip.ParsePrefix(original); err == nil {
+			if pseudonymNetwork, err := netip.ParsePrefix(pseudonym); err == nil && network.Bits() == pseudonymNetwork.Bits() {
+				r.networks = append(r.networks, networkPseudonym{network, pseudonymNetwork})
+			}
+		}
+	}
+	return r, nil
+}
+
+// learnNetworks gives the IPv4 networks of text, such as the targets of the
+// nmap command line, pseudonym networks of the same size, before the
+// addresses in them get their pseudonyms: an address in a network is then
+// given the address at the same offset in its pseudonym network, so that
+// 10.0.0.5 in 10.0.0.0/24 becomes 198.18.0.5 in 198.18.0.0/24. Networks in a
+// network given a pseudonym already, and those that do not fit in the range
+// left, are left to their addresses.
+func (r *redactor) learnNetworks(text string) {
+	var networks []netip.Prefix
+	for _, match := range ipv4Pattern.FindAllString(text, -1) {
+		if network, err := netip.ParsePrefix(match); err == nil && network.Addr().Is4() {
+			networks = append(networks, network.Masked())
+		}
+	}
+	// The largest networks first, for the smaller ones in them to follow.
+	sort.Slice(networks, func(i, j int) bool { return networks[i].Bits() < networks[j].Bits() })
+	for _, network := range networks {
+		if _, ok := r.networkOf(network.Addr()); ok {
+			continue
+		}
+		pseudonym, ok := r.freeNetwork(network.Bits())
+		if !ok {
+			logger.Warn("no pseudonym network left, its addresses are given pseudonyms apart", "network", network)
+			continue
+		}
+		r.networks = append(r.networks, networkPseudonym{network, pseudonym})
+		r.mapping[network.String()] = pseudonym.String()
+		r.used[pseudonym.String()] = true
+	}
+}
+
+// networkOf returns the smallest network given a pseudonym network that addr
+// is in.
+func (r *redactor) networkOf(addr netip.Addr) (networkPseudonym, bool) {
+	var found networkPseudonym
+	for _, network := range r.networks {
+		if network.original.Contains(addr) && (!found.original.IsValid() || network.original.Bits() > found.original.Bits()) {
+			found = network
+		}
+	}
+	return found, found.original.IsValid()
+}
+
+// freeNetwork returns the first network of size bits in pseudonymRange that no
+// pseudonym is in yet.
+func (r *redactor) freeNetwork(bits int) (netip.Prefix, bool) {
+	if bits < pseudonymRange.Bits() {
+		return netip.Prefix{}, false
+	}
+	var used []netip.Addr
+	for pseudonym := range r.used {
+		if addr, err := netip.ParseAddr(pseudonym); err == nil {
+			used = append(used, addr)
+		}
+	}
+	first := ipv4Number(pseudonymRange.Addr())
+	size := uint64(1) << (32 - bits)
+	for start := uint64(first); start < uint64(first)+1<<(32-pseudonymRange.Bits()); start += size {
+		candidate := netip.PrefixFrom(ipv4Addr(uint32(start)), bits)
+		free := !slices.ContainsFunc(r.networks, func(network networkPseudonym) bool { return network.pseudonym.Overlaps(candidate) }) &&
+			!slices.ContainsFunc(used, candidate.Contains)
+		if free {
+			return candidate, true
+		}
+	}
+	return netip.Prefix{}, false
+}
+
+// reserved reports whether addr is in a pseudonym network, whose addresses are
+// only given to those of its network.
+func (r *redactor) reserved(addr netip.Addr) bool {
+	return slices.ContainsFunc(r.networks, func(network networkPseudonym) bool { return network.pseudonym.Contains(addr) })
+}
+
+func ipv4Number(addr netip.Addr) uint32 {
+	octets := addr.As4()
+	return binary.BigEndian.Uint32(octets[:])
+}
+
+func ipv4Addr(n uint32) netip.Addr {
+	var octets [4]byte
+	binary.BigEndian.PutUint32(octets[:], n)
+	return netip.AddrFrom4(octets)
+}
+
+// pseudonym gives original, a value of kind, the next unused pseudonym of its
+// kind and adds it to the mapping:
+//
+//   - IPv4 addresses are numbered from 198.18.0.1, in the benchmarking range,
+//     but for those of the networks of learnNetworks,
+//   - IPv6 addresses from 2001:db8::1, in the documentation range,
+//   - MAC addresses from the locally administered 02:00:00:00:00:01,
+//   - domains are domain1.example and on, and hostnames host1 and on, followed
+//     by the pseudonym of their domain.
+func (r *redactor) pseudonym(kind pseudonymKind, original string) string {
+	key := strings.ToLower(original)
+	var pseudonym string
+	switch kind {
+	case pseudonymIPv4:
+		if addr, err := netip.ParseAddr(original); err == nil {
+			if network, ok := r.networkOf(addr); ok {
+				offset := ipv4Number(addr) - ipv4Number(network.original.Addr())
+				pseudonym = ipv4Addr(ipv4Number(network.pseudonym.Addr()) + offset).String()
+				r.used[pseudonym] = true
+				break
+			}
+		}
+		pseudonym = r.next("ipv4", func(n int) string {
+			addr := netip.AddrFrom4([4]byte{198, byte(18 + n>>16), byte(n >> 8), byte(n)})
+			// Network and broadcast-like addresses would only confuse.
+			if byte(n) == 0 || byte(n) == 255 || r.reserved(addr) {
+				return ""
+			}
+			return addr.String()
+		})
+	case pseudonymIPv6:
+		pseudonym = r.next("ipv6", func(n int) string { return fmt.Sprintf("2001:db8::%x", n) })
+	case pseudonymMAC:
+		pseudonym = r.next("mac", func(n int) string {
+			return fmt.Sprintf("02:00:00:%02x:%02x:%02x", byte(n>>16), byte(n>>8), byte(n))
+		})
+	default:
+		if r.domains[key] {
+			pseudonym = r.next("domain", func(n int) string { return fmt.Sprintf("domain%d.example", n) })
+			break
+		}
+		suffix := ""
+		if _, domain, ok := strings.Cut(key, "."); ok && r.domains[domain] {
+			suffix = "." + r.replace(pseudonymName, domain, "")
+		}
+		pseudonym = r.next("host", func(n int) string { return fmt.Sprintf("host%d%s", n, suffix) })
+	}
+	r.mapping[key] = pseudonym
+	return pseudonym
+}
+
+// next returns the first pseudonym given by format, from the count of
+// pseudonyms called counter, that is not used yet. format returns "" for
+// numbers that make no pseudonym.
+func (r *redactor) next(counter string, format func(n int) string) string {
+	for {
+		r.counts[counter]++
+		if pseudonym := format(r.counts[counter]); pseudonym != "" && !r.used[pseudonym] {
+			r.used[pseudonym] = true
+			return pseudonym
+		}
+	}
+}
+
+// writeKey writes the mapping of r to keyFile, readable only by its owner.
+func (r *redactor) writeKey(keyFile string) error {
+	originals := make([]string, 0, len(r.mapping))
+	for original := range r.mapping {
+		originals = append(originals, original)
+	}
+	sort.Strings(originals)
+	var buf bytes.Buffer
+	buf.WriteString(anonymizeKeyHeader)
+	for _, original := range originals {
+		fmt.Fprintf(&buf, "%s %s\n", original, r.mapping[original])
+	}
+	if err := os.MkdirAll(filepath.Dir(keyFile), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyFile, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	outputManifest.output(keyFile)
+	return nil
+}
+
+// runAnonymize writes the scans of args, or --nmap-dir, to --output-dir with
+// their addresses and hostnames replaced by pseudonyms, and the key of the
+// pseudonyms to --key-file.
+func runAnonymize(f *anonymizeFlags, args []string) error {
+	keyFile, err := resolveAbsPath(f.keyFile)
+	if err != nil {
+		return err
+	}
+	if f.restore {
+		return restoreFiles(keyFile, args)
+	}
+	nmapFiles, err := f.input.nmapFiles(args)
+	if err != nil {
+		return err
+	}
+	// The scans are read twice, to learn their hostnames and then to write
+	// them.
+	if slices.Contains(nmapFiles, stdinPath) {
+		return errors.New("anonymize cannot read standard input, pass the scan files instead")
+	}
+	runs, parseErrors := ParseNmapFiles(nmapFiles)
+	if err := checkParseErrors(parseErrors); err != nil {
+		return err
+	}
+	r, err := newAnonymizer(keyFile)
+	if err != nil {
+		return fmt.Errorf("invalid --key-file: %w", err)
+	}
+	for i := range runs {
+		r.learn(runs[i].Host)
+		r.learnNetworks(runs[i].Args)
+	}
+	outputDir, err := resolveAbsPath(f.outputDir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return err
+	}
+	written := make(map[string]string)
+	for _, file := range nmapFiles {
+		documents, err := readInput(file)
+		if err != nil {
+			return err
+		}
+		for _, document := range documents {
+			if document.err != nil {
+				return document.err
+			}
+			// File names are often those of the hosts they scanned.
+			name := r.text(strings.TrimSuffix(path.Base(filepath.ToSlash(document.name)), ".gz"))
+			if other, ok := written[name]; ok {
+				return fmt.Errorf("%s and %s would both be written to %s", other, document.name, name)
+			}
+			written[name] = document.name
+			outputFile, err := createOutputFile(filepath.Join(outputDir, name), f.force)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(outputFile, r.text(string(document.data)))
+			if closeErr := outputFile.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return fmt.Errorf("Error writing %s: %w", name, err)
+			}
+		}
+	}
+	if err := r.writeKey(keyFile); err != nil {
+		return fmt.Errorf("Error writing the key: %w", err)
+	}
+	statusf("%d scan files anonymized to %s, key written to %s\n", len(written), outputDir, keyFile)
+	return nil
+}
+
+// restoreFiles writes files, such as the findings of a review of anonymized
+// scans, to standard output with the pseudonyms of keyFile replaced by the
+// original addresses and hostnames.
+func restoreFiles(keyFile string, files []string) error {
+	if len(files) == 0 {
+		return errors.New("Please pass the files to restore as arguments, or - for standard input")
+	}
+	key, err := loadRedactor(true, keyFile)
+	if err != nil {
+		return fmt.Errorf("invalid --key-file: %w", err)
+	}
+	originals := make(map[string]string, len(key.mapping))
+	pseudonyms := make([]string, 0, len(key.mapping))
+	for original, pseudonym := range key.mapping {
+		originals[strings.ToLower(pseudonym)] = original
+		pseudonyms = append(pseudonyms, pseudonym)
+	}
+	pattern := wordsPattern(pseudonyms)
+	for _, file := range files {
+		var data []byte
+		if file == stdinPath {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(file)
+		}
+		if err != nil {
+			return err
+		}
+		text := string(data)
+		if pattern != nil {
+			text = pattern.ReplaceAllStringFunc(text, func(pseudonym string) string {
+				return originals[strings.ToLower(pseudonym)]
+			})
+		}
+		if _, err := io.WriteString(os.Stdout, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
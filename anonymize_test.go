@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestAnonymizeNetworkTarget(t *testing.T) {
+	dir := t.TempDir()
+	scan := strings.Replace(redactTestScan, `args="nmap -sV 10.0.0.5 10.0.0.6"`, `args="nmap -sV 10.0.0.0/24"`, 1)
+	// An address outside the target network, as script output would give.
+	scan = strings.Replace(scan, `<hostnames>`, `<hostscript><script id="traceroute" output="hop 10.1.2.3"/></hostscript><hostnames>`, 1)
+	writeFile(t, dir, "scan.xml", scan)
+	outputDir := filepath.Join(dir, "out")
+	keyFile := filepath.Join(dir, "key.txt")
+	anonymize := []string{"anonymize", filepath.Join(dir, "scan.xml"), "--output-dir", outputDir, "--key-file", keyFile, "--quiet"}
+	if _, err := runCLI(t, anonymize...); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(outputDir, "scan.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	anonymized := string(data)
+
+	args := regexp.MustCompile(`args="nmap -sV ([^"]*)"`).FindStringSubmatch(anonymized)
+	if args == nil {
+		t.Fatalf("no nmap command line in:\n%s", anonymized)
+	}
+	network, err := netip.ParsePrefix(args[1])
+	if err != nil || network.Bits() != 24 || network.Masked() != network || !pseudonymRange.Contains(network.Addr()) {
+		t.Fatalf("target 10.0.0.0/24 anonymized to %q, want a /24 network in %s", args[1], pseudonymRange)
+	}
+	for original, offset := range map[string]int{"10.0.0.5": 5, "10.0.0.6": 6} {
+		want := ipv4Addr(ipv4Number(network.Addr()) + uint32(offset)).String()
+		if !strings.Contains(anonymized, `addr="`+want+`"`) {
+			t.Errorf("%s not anonymized to %s in %s:\n%s", original, want, network, anonymized)
+		}
+	}
+	outside := regexp.MustCompile(`hop ([0-9.]+)`).FindStringSubmatch(anonymized)
+	if outside == nil || outside[1] == "10.1.2.3" || network.Contains(netip.MustParseAddr(outside[1])) {
+		t.Errorf("10.1.2.3 anonymized to %q, want an address outside %s", outside, network)
+	}
+
+	// The key gives the network the same pseudonym the next time.
+	if _, err := runCLI(t, append(anonymize, "--force")...); err != nil {
+		t.Fatal(err)
+	}
+	again, err := os.ReadFile(filepath.Join(outputDir, "scan.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != anonymized {
+		t.Errorf("anonymized again with the key:\n%s\nwant:\n%s", again, anonymized)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// APIHost is a host as returned by the /hosts endpoints of the api command.
+type APIHost struct {
+	Addr      string    `json:"addr"`
+	MAC       string    `json:"mac,omitempty"`
+	Vendor    string    `json:"vendor,omitempty"`
+	Hostnames []string  `json:"hostnames"`
+	Status    string    `json:"status"`
+	OS        string    `json:"os,omitempty"`
+	Ports     []APIPort `json:"ports"`
+}
+
+// APIPort is a port of an APIHost.
+type APIPort struct {
+	Port      string `json:"port"`
+	Protocol  string `json:"protocol"`
+	State     string `json:"state"`
+	Service   string `json:"service"`
+	Product   string `json:"product,omitempty"`
+	Version   string `json:"version,omitempty"`
+	ExtraInfo string `json:"extrainfo,omitempty"`
+	CPE       string `json:"cpe,omitempty"`
+}
+
+// apiServer serves the scans of a reportServer as JSON.
+type apiServer struct {
+	*reportServer
+	// cors is the Access-Control-Allow-Origin of every response, if set.
+	cors string
+}
+
+// apiHost returns host with the ports whose state is in states and, when
+// service is set, that run service. ok is false when no port is left and a
+// service was asked for.
+func apiHost(host *NmapHost, preferIPv6 bool, states map[string]bool, service string) (APIHost, bool) {
+	result := APIHost{Addr: hostAddress(host, preferIPv6), Status: host.Status.State, OS: hostOS(host), Hostnames: []string{}, Ports: []APIPort{}}
+	result.MAC, result.Vendor = hostMAC(host)
+	for _, hostname := range host.Hostnames.Hostname {
+		result.Hostnames = append(result.Hostnames, hostname.Name)
+	}
+	for i := range host.Ports.Port {
+		port := &host.Ports.Port[i]
+		if !states[port.State.State] || (service != "" && !matchesService(port, service)) {
+			continue
+		}
+		result.Ports = append(result.Ports, APIPort{
+			Port:      port.Portid,
+			Protocol:  port.Protocol,
+			State:     port.State.State,
+			Service:   tunnelService(port.Service.Name, port.Service.Tunnel),
+			Product:   port.Service.Product,
+			Version:   port.Service.Version,
+			ExtraInfo: port.Service.Extrainfo,
+			CPE:       port.Service.Cpe,
+		})
+	}
+	return result, service == "" || len(result.Ports) > 0
+}
+
+// writeJSON writes value to w as the JSON response of a request.
+func (s *apiServer) writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.cors != "" {
+		w.Header().Set("Access-Control-Allow-Origin", s.cors)
+	}
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		logger.Debug("could not write API response", "err", err)
+	}
+}
+
+// writeError writes an error response of status with message.
+func (s *apiServer) writeError(w http.ResponseWriter, status int, message string) {
+	s.writeJSON(w, status, map[string]string{"error": message})
+}
+
+// handleHosts serves every host, or with ?service= the hosts running a
+// service with only those ports.
+func (s *apiServer) handleHosts(w http.ResponseWriter, r *http.Request) {
+	service := r.URL.Query().Get("service")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	hosts := []APIHost{}
+	for i := range s.hosts {
+		if host, ok := apiHost(&s.hosts[i], s.opts.PreferIPv6, s.opts.States, service); ok {
+			hosts = append(hosts, host)
+		}
+	}
+	s.writeJSON(w, http.StatusOK, hosts)
+}
+
+// handleHost serves the host of the address in the path.
+func (s *apiServer) handleHost(w http.ResponseWriter, r *http.Request) {
+	addr := r.PathValue("addr")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := range s.hosts {
+		if hostAddress(&s.hosts[i], s.opts.PreferIPv6) == addr {
+			host, _ := apiHost(&s.hosts[i], s.opts.PreferIPv6, s.opts.States, "")
+			s.writeJSON(w, http.StatusOK, host)
+			return
+		}
+	}
+	s.writeError(w, http.StatusNotFound, fmt.Sprintf("host %s not found", addr))
+}
+
+// handleServices serves the host and port counts of every service.
+func (s *apiServer) handleServices(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.writeJSON(w, http.StatusOK, CountServices(s.runs, s.opts))
+}
+
+// handleDiff serves the differences between the baseline and the scans, of
+// one service with ?service=.
+func (s *apiServer) handleDiff(w http.ResponseWriter, r *http.Request) {
+	if s.baseline == nil {
+		s.writeError(w, http.StatusNotFound, "no baseline to diff against, start the api with --baseline")
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	opts := s.opts
+	opts.ServiceName = r.URL.Query().Get("service")
+	entries := DiffScans(s.baseline, s.runs, opts)
+	if entries == nil {
+		entries = []DiffEntry{}
+	}
+	s.writeJSON(w, http.StatusOK, entries)
+}
+
+// handleSearch serves the matches of the query ?q=, the best ?limit= only
+// when given.
+func (s *apiServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if strings.TrimSpace(query) == "" {
+		s.writeError(w, http.StatusBadRequest, "missing query parameter q")
+		return
+	}
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit: %s", value))
+			return
+		}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	matches := SearchPorts(s.runs, s.opts, query)
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	if matches == nil {
+		matches = []QueryMatch{}
+	}
+	s.writeJSON(w, http.StatusOK, matches)
+}
+
+// runAPI implements the api subcommand, which serves the scans of nmapFiles as
+// a JSON REST API on listenAddr, and Prometheus metrics at /metrics. When
+// watchDir is set the directory is watched and the API answers from the scans
+// as they change. /diff compares the scans with baselineFiles.
+func runAPI(nmapFiles []string, opts TableOptions, listenAddr, watchDir string, walkOpts WalkOptions, baselineFiles []string, cors string) error {
+	srv := &apiServer{reportServer: &reportServer{opts: opts}, cors: cors}
+	if err := srv.loadBaseline(baselineFiles); err != nil {
+		return err
+	}
+	if err := srv.load(nmapFiles, watchDir, walkOpts); err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /hosts", srv.handleHosts)
+	mux.HandleFunc("GET /hosts/{addr}", srv.handleHost)
+	mux.HandleFunc("GET /services", srv.handleServices)
+	mux.HandleFunc("GET /diff", srv.handleDiff)
+	mux.HandleFunc("GET /search", srv.handleSearch)
+	mux.HandleFunc("GET /metrics", srv.handleMetrics)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		srv.writeError(w, http.StatusNotFound, "unknown endpoint, use /hosts, /hosts/{addr}, /services, /diff, /search or /metrics")
+	})
+
+	statusf("Serving the API for %d files at http://%s/\n", len(nmapFiles), listenAddr)
+	return http.ListenAndServe(listenAddr, mux)
+}
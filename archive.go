@@ -0,0 +1,175 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// inputDocument is a scan read from an input file or archive member, named as
+// it is reported in parse errors. err is set when the member could not be read.
+type inputDocument struct {
+	name string
+	data []byte
+	err  error
+}
+
+// isArchive reports whether a file called name is an archive of scans that is
+// read when walking directories.
+func isArchive(name string) bool {
+	name = strings.ToLower(name)
+	for _, extension := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(name, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// isGzip reports whether data is gzip compressed.
+func isGzip(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+}
+
+// isTar reports whether header, the first block of a file, is a tar header.
+func isTar(header []byte) bool {
+	return len(header) >= 262 && string(header[257:262]) == "ustar"
+}
+
+// isZip reports whether data is a zip archive.
+func isZip(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
+
+// gunzip decompresses data if it is gzip compressed, or returns it as is.
+func gunzip(data []byte) ([]byte, error) {
+	if !isGzip(data) {
+		return data, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// isScanMember reports whether the archive member called name is an XML scan,
+// leaving out hidden files such as the __MACOSX resource forks.
+func isScanMember(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if isHidden(part) || part == "__MACOSX" {
+			return false
+		}
+	}
+	name = strings.TrimSuffix(strings.ToLower(name), ".gz")
+	return strings.HasSuffix(name, ".xml")
+}
+
+// readInput reads filePath, or standard input, and returns the scans it
+// holds: the file itself, decompressed if gzipped, or the XML members of a zip
+// or (optionally gzipped) tar archive. Archive members are named
+// archive/member.
+func readInput(filePath string) ([]inputDocument, error) {
+	var r io.Reader = os.Stdin
+	if filePath == stdinPath {
+		filePath = "standard input"
+	} else {
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, &ParseError{File: filePath, Reason: err.Error()}
+		}
+		defer file.Close()
+		r = file
+	}
+
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); isGzip(magic) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, &ParseError{File: filePath, Reason: err.Error()}
+		}
+		defer zr.Close()
+		br = bufio.NewReader(zr)
+	}
+	// Tar archives are read as a stream, so that large engagement bundles are
+	// never held in memory as a whole.
+	if header, _ := br.Peek(262); isTar(header) {
+		return tarDocuments(filePath, tar.NewReader(br))
+	}
+	data, err := io.ReadAll(br)
+	if err != nil {
+		return nil, &ParseError{File: filePath, Reason: err.Error()}
+	}
+	if !isZip(data) {
+		return []inputDocument{{name: filePath, data: data}}, nil
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, &ParseError{File: filePath, Reason: fmt.Sprintf("invalid zip archive: %v", err)}
+	}
+	var documents []inputDocument
+	for _, file := range zr.File {
+		if file.FileInfo().IsDir() || !isScanMember(file.Name) {
+			continue
+		}
+		member, err := readZipMember(file)
+		documents = append(documents, inputDocument{name: path.Join(filePath, file.Name), data: member, err: err})
+	}
+	if len(documents) == 0 {
+		return nil, &ParseError{File: filePath, Reason: "no XML files in zip archive"}
+	}
+	return documents, nil
+}
+
+// readZipMember reads and decompresses a member of a zip archive.
+func readZipMember(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	return gunzip(data)
+}
+
+// tarDocuments reads the XML members of the tar archive filePath from tr.
+func tarDocuments(filePath string, tr *tar.Reader) ([]inputDocument, error) {
+	var documents []inputDocument
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if len(documents) > 0 {
+				// Keep the members read before the archive was cut short.
+				return append(documents, inputDocument{name: filePath, err: fmt.Errorf("invalid tar archive: %w", err)}), nil
+			}
+			return nil, &ParseError{File: filePath, Reason: fmt.Sprintf("invalid tar archive: %v", err)}
+		}
+		if header.Typeflag != tar.TypeReg || !isScanMember(header.Name) {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err == nil {
+			data, err = gunzip(data)
+		}
+		documents = append(documents, inputDocument{name: path.Join(filePath, header.Name), data: data, err: err})
+	}
+	if len(documents) == 0 {
+		return nil, &ParseError{File: filePath, Reason: "no XML files in tar archive"}
+	}
+	return documents, nil
+}
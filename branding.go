@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// logoTypes are the image types of --logo, by media type, with their fpdf
+// image type: those that PDF reports can embed.
+var logoTypes = map[string]string{"image/png": "PNG", "image/jpeg": "JPG", "image/gif": "GIF"}
+
+// Branding is the client branding of the reports, see --title, --client,
+// --logo and --classification.
+type Branding struct {
+	// Title replaces "Nmap Service Report" as the title of the reports.
+	Title string
+	// Client is the name of the client the reports are written for.
+	Client string
+	// Classification is the marking shown in a banner at the top and bottom
+	// of the reports, e.g. CONFIDENTIAL or TLP:AMBER.
+	Classification string
+	// logo is the image shown above the title, a PNG, JPEG or GIF, and
+	// logoType its media type.
+	logo     []byte
+	logoType string
+}
+
+// loadBranding returns the branding of the reports, reading the logo image at
+// logoPath when it is not empty.
+func loadBranding(title, client, logoPath, classification string) (Branding, error) {
+	branding := Branding{
+		Title:          strings.TrimSpace(title),
+		Client:         strings.TrimSpace(client),
+		Classification: strings.TrimSpace(classification),
+	}
+	if logoPath == "" {
+		return branding, nil
+	}
+	logoPath, err := resolveAbsPath(logoPath)
+	if err != nil {
+		return branding, err
+	}
+	if branding.logo, err = os.ReadFile(logoPath); err != nil {
+		return branding, err
+	}
+	outputManifest.input(logoPath)
+	branding.logoType = http.DetectContentType(branding.logo)
+	if _, ok := logoTypes[branding.logoType]; !ok {
+		return branding, fmt.Errorf("%s is %s, not a PNG, JPEG or GIF image", logoPath, branding.logoType)
+	}
+	return branding, nil
+}
+
+// TitleOr returns the title of the reports, or title when none was given.
+func (b Branding) TitleOr(title string) string {
+	if b.Title != "" {
+		return b.Title
+	}
+	return title
+}
+
+// Logo returns the logo as a data URI to embed in HTML reports, or "" when
+// there is none.
+func (b Branding) Logo() template.URL {
+	if len(b.logo) == 0 {
+		return ""
+	}
+	return template.URL("data:" + b.logoType + ";base64," + base64.StdEncoding.EncodeToString(b.logo))
+}
+
+// logoReader returns the logo and its fpdf image type, for PDF reports.
+func (b Branding) logoReader() (*bytes.Reader, string) {
+	return bytes.NewReader(b.logo), logoTypes[b.logoType]
+}
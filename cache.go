@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// scanCacheVersion is stored in every cache entry; entries of another version
+// are parsed again, so it must be bumped whenever Nmaprun changes.
+const scanCacheVersion = 4
+
+// inputCache is the parse cache of --cache, nil when it is off.
+var inputCache *scanCache
+
+// scanCache stores the scans parsed from input files in a directory, one gob
+// file per input, so that repeated runs over the same scans only parse the
+// files that are new or changed.
+type scanCache struct {
+	dir string
+}
+
+// scanCacheEntry is the cache file of an input file.
+type scanCacheEntry struct {
+	Version int
+	Path    string
+	ModTime time.Time
+	Size    int64
+	// Digest is the SHA-256 of the file, which lets a file whose modification
+	// time changed but whose content did not, e.g. a fresh copy, be reused.
+	Digest [sha256.Size]byte
+	Scans  []scanCacheScan
+}
+
+// scanCacheScan is a parsedScan in a cache entry.
+type scanCacheScan struct {
+	Run    Nmaprun
+	File   string
+	Digest [sha256.Size]byte
+}
+
+// defaultScanCacheDir returns the nmaptables/scans directory of the user's
+// cache directory.
+func defaultScanCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "nmaptables", "scans"), nil
+}
+
+// openScanCache returns the cache in dir, or in the default cache directory
+// when dir is empty, creating it if needed.
+func openScanCache(dir string) (*scanCache, error) {
+	var err error
+	if dir == "" {
+		dir, err = defaultScanCacheDir()
+	} else {
+		dir, err = resolveAbsPath(dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &scanCache{dir: dir}, nil
+}
+
+// entryPath returns the cache file of the input file path.
+func (c *scanCache) entryPath(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".gob")
+}
+
+// parseInput returns the scans of filePath from the cache when the file is
+// unchanged since they were stored, and otherwise parses it and stores the
+// result. cached reports whether the scans came from the cache. Files with
+// parse errors are not stored, so their errors are reported on every run.
+func (c *scanCache) parseInput(filePath string) (scans []parsedScan, parseErrors []ParseError, cached bool) {
+	info, err := os.Stat(filePath)
+	if filePath == stdinPath || err != nil || !info.Mode().IsRegular() {
+		scans, parseErrors = parseInput(filePath)
+		return scans, parseErrors, false
+	}
+	entry, ok := c.load(filePath)
+	sameFile := ok && entry.ModTime.Equal(info.ModTime()) && entry.Size == info.Size()
+	var digest [sha256.Size]byte
+	if !sameFile {
+		// A file with a new modification time may still hold the same scan.
+		digest, err = fileDigest(filePath)
+		sameFile = err == nil && ok && entry.Size == info.Size() && entry.Digest == digest
+		if sameFile {
+			entry.ModTime = info.ModTime()
+			c.store(entry)
+		}
+	}
+	if sameFile {
+		for _, scan := range entry.Scans {
+			scans = append(scans, parsedScan{run: scan.Run, file: scan.File, digest: scan.Digest})
+		}
+		return scans, nil, true
+	}
+
+	scans, parseErrors = parseInput(filePath)
+	if len(parseErrors) == 0 && err == nil {
+		entry = scanCacheEntry{Version: scanCacheVersion, Path: filePath, ModTime: info.ModTime(), Size: info.Size(), Digest: digest}
+		for _, scan := range scans {
+			entry.Scans = append(entry.Scans, scanCacheScan{Run: scan.run, File: scan.file, Digest: scan.digest})
+		}
+		c.store(entry)
+	}
+	return scans, parseErrors, false
+}
+
+// load returns the cache entry of path. Missing, unreadable and outdated
+// entries are misses.
+func (c *scanCache) load(path string) (scanCacheEntry, bool) {
+	var entry scanCacheEntry
+	data, err := os.ReadFile(c.entryPath(path))
+	if err != nil {
+		return entry, false
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entry); err != nil {
+		logger.Debug("ignoring unreadable cache entry", "file", path, "error", err)
+		return entry, false
+	}
+	return entry, entry.Version == scanCacheVersion && entry.Path == path
+}
+
+// store writes entry to the cache. The entry is written to a temporary file
+// that is renamed into place, so an interrupted run never leaves a partial
+// entry behind. Errors are logged rather than returned: the cache only saves
+// work.
+func (c *scanCache) store(entry scanCacheEntry) {
+	var data bytes.Buffer
+	if err := gob.NewEncoder(&data).Encode(entry); err != nil {
+		logger.Warn("could not cache scan", "file", entry.Path, "error", err)
+		return
+	}
+	tmp, err := os.CreateTemp(c.dir, ".entry-*")
+	if err == nil {
+		_, err = tmp.Write(data.Bytes())
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), c.entryPath(entry.Path))
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}
+	if err != nil {
+		logger.Warn("could not cache scan", "file", entry.Path, "error", err)
+	}
+}
+
+// fileDigest returns the SHA-256 of the file at path.
+func fileDigest(path string) ([sha256.Size]byte, error) {
+	var digest [sha256.Size]byte
+	file, err := os.Open(path)
+	if err != nil {
+		return digest, err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return digest, err
+	}
+	copy(digest[:], hash.Sum(nil))
+	return digest, nil
+}
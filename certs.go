@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Certificate is a TLS certificate reported by the ssl-cert NSE script on a
+// host port.
+type Certificate struct {
+	Host      HostPort  `json:"host"`
+	Service   string    `json:"service"`
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	SANs      []string  `json:"sans,omitempty"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	// SelfSigned is set when the issuer is the subject.
+	SelfSigned bool   `json:"self_signed"`
+	Signature  string `json:"signature"`
+	KeyType    string `json:"key_type,omitempty"`
+	KeyBits    int    `json:"key_bits,omitempty"`
+	// Warnings flag weak signatures and keys and expired certificates.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// weakSignatures are the digests of signature algorithms that no longer
+// protect a certificate from forgery.
+var weakSignatures = []string{"md2", "md4", "md5", "sha1"}
+
+// minRSABits is the smallest RSA key size that is not flagged as weak.
+const minRSABits = 2048
+
+// CollectCertificates returns the certificates of the ports of runs matching
+// the state and exclusion filters of opts, soonest expiry first. Ports of
+// opts.ServiceName, when set, are the only ones reported.
+func CollectCertificates(runs []Nmaprun, opts TableOptions, now time.Time) []Certificate {
+	var certs []Certificate
+	hosts := opts.mergeHosts(runs)
+	for i := range hosts {
+		host := &hosts[i]
+		addr := hostAddress(host, opts.PreferIPv6)
+		if opts.excluded(addr) {
+			continue
+		}
+		for j := range host.Ports.Port {
+			port := &host.Ports.Port[j]
+			if !opts.States[port.State.State] || (opts.ServiceName != "" && port.Service.Name != opts.ServiceName) {
+				continue
+			}
+			for k := range port.Script {
+				if port.Script[k].ID != "ssl-cert" {
+					continue
+				}
+				cert := parseCertificate(&port.Script[k], now)
+				cert.Host = HostPort{Addr: addr, Port: port.Portid}
+				if len(host.Hostnames.Hostname) > 0 {
+					cert.Host.Hostname = host.Hostnames.Hostname[0].Name
+				}
+				cert.Service = port.Service.Name
+				certs = append(certs, cert)
+			}
+		}
+	}
+	sort.SliceStable(certs, func(i, j int) bool {
+		a, b := certs[i].NotAfter, certs[j].NotAfter
+		if a.IsZero() != b.IsZero() {
+			return b.IsZero()
+		}
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return certs[i].Host.String() < certs[j].Host.String()
+	})
+	return certs
+}
+
+// parseCertificate reads the structured output of the ssl-cert script.
+func parseCertificate(script *NmapScript, now time.Time) Certificate {
+	subject := tableElems(script.Table, "subject")
+	issuer := tableElems(script.Table, "issuer")
+	validity := tableElems(script.Table, "validity")
+	pubkey := tableElems(script.Table, "pubkey")
+	cert := Certificate{
+		Subject:    distinguishedName(subject),
+		Issuer:     distinguishedName(issuer),
+		NotBefore:  parseCertTime(elemValue(validity, "notBefore")),
+		NotAfter:   parseCertTime(elemValue(validity, "notAfter")),
+		SelfSigned: len(subject) > 0 && distinguishedName(subject) == distinguishedName(issuer),
+		Signature:  elemValue(script.Elem, "sig_algo"),
+		KeyType:    elemValue(pubkey, "type"),
+		KeyBits:    atoi(elemValue(pubkey, "bits")),
+	}
+	for _, extension := range tableTables(script.Table, "extensions") {
+		if elemValue(extension.Elem, "name") == "X509v3 Subject Alternative Name" {
+			for _, name := range strings.Split(elemValue(extension.Elem, "value"), ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					cert.SANs = append(cert.SANs, name)
+				}
+			}
+		}
+	}
+
+	signature := strings.ToLower(cert.Signature)
+	for _, digest := range weakSignatures {
+		if strings.Contains(signature, digest+"with") || strings.HasSuffix(signature, "with-"+digest) {
+			cert.Warnings = append(cert.Warnings, "weak signature "+cert.Signature)
+			break
+		}
+	}
+	if cert.KeyType == "rsa" && cert.KeyBits > 0 && cert.KeyBits < minRSABits {
+		cert.Warnings = append(cert.Warnings, fmt.Sprintf("weak %d-bit RSA key", cert.KeyBits))
+	}
+	if !cert.NotAfter.IsZero() && cert.NotAfter.Before(now) {
+		cert.Warnings = append(cert.Warnings, "expired")
+	}
+	return cert
+}
+
+// tableTables returns the nested tables of the table keyed key among tables.
+func tableTables(tables []NmapTable, key string) []NmapTable {
+	for _, table := range tables {
+		if table.Key == key {
+			return table.Table
+		}
+	}
+	return nil
+}
+
+// distinguishedName formats the elements of a subject or issuer table as
+// key=value pairs, e.g. commonName=example.com, organizationName=Example.
+func distinguishedName(elems []NmapElem) string {
+	parts := make([]string, 0, len(elems))
+	for _, elem := range elems {
+		parts = append(parts, elem.Key+"="+elem.Text)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// certTimeLayouts are the formats ssl-cert writes validity dates in, depending
+// on the nmap version.
+var certTimeLayouts = []string{"2006-01-02T15:04:05", time.RFC3339, "2006-01-02T15:04:05-0700"}
+
+// parseCertTime parses a validity date of ssl-cert, or returns the zero time.
+func parseCertTime(value string) time.Time {
+	for _, layout := range certTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// writeCertificates writes certs to w in format: an aligned text table, csv
+// or json.
+func writeCertificates(w io.Writer, certs []Certificate, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if certs == nil {
+			certs = []Certificate{}
+		}
+		return encoder.Encode(certs)
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"host", "port", "service", "subject", "issuer", "sans", "not_before", "not_after", "self_signed", "signature", "warnings"}); err != nil {
+			return err
+		}
+		for _, cert := range certs {
+			record := []string{cert.Host.Addr, cert.Host.Port, cert.Service, cert.Subject, cert.Issuer, strings.Join(cert.SANs, " "),
+				formatDate(cert.NotBefore, time.DateOnly), formatDate(cert.NotAfter, time.DateOnly), strconv.FormatBool(cert.SelfSigned),
+				cert.Signature, strings.Join(cert.Warnings, "; ")}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "", "text":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "HOST\tSUBJECT\tISSUER\tSANS\tEXPIRES\tSELF-SIGNED\tWARNINGS")
+		for _, cert := range certs {
+			selfSigned := ""
+			if cert.SelfSigned {
+				selfSigned = "yes"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", cert.Host, cert.Subject, cert.Issuer, strings.Join(cert.SANs, ", "),
+				formatDate(cert.NotAfter, time.DateOnly), selfSigned, strings.Join(cert.Warnings, "; "))
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unsupported certs format: %s (use text, csv or json)", format)
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"sort"
+	"strings"
+)
+
+// chartServices is the number of services in the top services chart, and
+// chartVersions the number of versions in the version chart before the rest
+// are grouped as "other".
+const (
+	chartServices = 10
+	chartVersions = 8
+)
+
+// chartColors are the fill colors of the chart bars and slices, in order.
+var chartColors = []string{"#1976d2", "#388e3c", "#f57c00", "#7b1fa2", "#d32f2f", "#0097a7", "#fbc02d", "#5d4037", "#c2185b", "#616161"}
+
+// portBuckets are the upper bounds of the open ports per host histogram
+// buckets, the last one being unbounded.
+var portBuckets = []int{1, 2, 3, 4, 5, 10, 20, 50}
+
+// ChartBar is a labelled value of a chart: a bar, column or pie slice.
+type ChartBar struct {
+	Label string `json:"label"`
+	Value int    `json:"value"`
+}
+
+// ReportCharts are the data of the charts of HTML reports.
+type ReportCharts struct {
+	// Services are the most common services of the whole dataset by ports.
+	Services []ChartBar `json:"services"`
+	// Versions are the hosts running each version of the report's service.
+	Versions []ChartBar `json:"versions"`
+	// PortsPerHost are the number of hosts by how many ports they have open.
+	PortsPerHost []ChartBar `json:"ports_per_host"`
+}
+
+// BuildCharts returns the charts of data, the report of runs for opts.
+func BuildCharts(runs []Nmaprun, opts TableOptions, data ReportData) ReportCharts {
+	var charts ReportCharts
+	for _, count := range CountServices(runs, opts) {
+		if len(charts.Services) == chartServices {
+			break
+		}
+		charts.Services = append(charts.Services, ChartBar{Label: count.Service, Value: count.Ports})
+	}
+
+	versions := make(map[string]int)
+	for _, rows := range [][]ReportRow{data.Rows, data.Unverified} {
+		for _, row := range rows {
+			label := strings.TrimSpace(row.Product + " " + row.ProductVersion)
+			if label == "" {
+				label = "unknown"
+			}
+			versions[label] += len(row.Hosts)
+		}
+	}
+	for label, hosts := range versions {
+		charts.Versions = append(charts.Versions, ChartBar{Label: label, Value: hosts})
+	}
+	sortBars(charts.Versions)
+	if len(charts.Versions) > chartVersions {
+		other := ChartBar{Label: "other"}
+		for _, bar := range charts.Versions[chartVersions-1:] {
+			other.Value += bar.Value
+		}
+		charts.Versions = append(charts.Versions[:chartVersions-1], other)
+	}
+
+	buckets := make([]int, len(portBuckets)+1)
+	hosts := MergeHosts(runs, opts.PreferIPv6)
+	for i := range hosts {
+		host := &hosts[i]
+		if opts.excluded(hostAddress(host, opts.PreferIPv6)) {
+			continue
+		}
+		open := 0
+		for _, port := range host.Ports.Port {
+			if opts.States[port.State.State] {
+				open++
+			}
+		}
+		if open == 0 {
+			continue
+		}
+		bucket := sort.SearchInts(portBuckets, open)
+		buckets[bucket]++
+	}
+	last := -1
+	for i, hosts := range buckets {
+		if hosts > 0 {
+			last = i
+		}
+	}
+	for i := 0; i <= last; i++ {
+		charts.PortsPerHost = append(charts.PortsPerHost, ChartBar{Label: bucketLabel(i), Value: buckets[i]})
+	}
+	return charts
+}
+
+// sortBars orders bars by value, largest first, and then by label.
+func sortBars(bars []ChartBar) {
+	sort.Slice(bars, func(i, j int) bool {
+		if bars[i].Value != bars[j].Value {
+			return bars[i].Value > bars[j].Value
+		}
+		return bars[i].Label < bars[j].Label
+	})
+}
+
+// bucketLabel returns the range of open ports of histogram bucket i.
+func bucketLabel(i int) string {
+	if i == len(portBuckets) {
+		return fmt.Sprintf("%d+", portBuckets[i-1]+1)
+	}
+	low := 1
+	if i > 0 {
+		low = portBuckets[i-1] + 1
+	}
+	if low == portBuckets[i] {
+		return fmt.Sprint(low)
+	}
+	return fmt.Sprintf("%d-%d", low, portBuckets[i])
+}
+
+// barChart returns an SVG chart of bars as horizontal bars, e.g.
+// {{barChart .Charts.Services}}.
+func barChart(bars []ChartBar) template.HTML {
+	const labelWidth, barWidth, rowHeight = 160, 280, 22
+	maxValue := maxBar(bars)
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="chart" role="img" width="%d" height="%d" viewBox="0 0 %[1]d %[2]d">`, labelWidth+barWidth+50, len(bars)*rowHeight)
+	for i, bar := range bars {
+		y := i * rowHeight
+		width := max(1, bar.Value*barWidth/maxValue)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end" fill="currentColor">%s</text>`, labelWidth-6, y+15, svgText(bar.Label, 22))
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"><title>%s: %d</title></rect>`,
+			labelWidth, y+3, width, rowHeight-6, chartColors[i%len(chartColors)], html.EscapeString(bar.Label), bar.Value)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" fill="currentColor">%d</text>`, labelWidth+width+4, y+15, bar.Value)
+	}
+	b.WriteString("</svg>")
+	return template.HTML(b.String())
+}
+
+// histogram returns an SVG chart of bars as vertical columns with their
+// labels underneath, e.g. {{histogram .Charts.PortsPerHost}}.
+func histogram(bars []ChartBar) template.HTML {
+	const columnWidth, height, top, bottom = 44, 180, 18, 22
+	maxValue := maxBar(bars)
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="chart" role="img" width="%d" height="%d" viewBox="0 0 %[1]d %[2]d">`, len(bars)*columnWidth, top+height+bottom)
+	for i, bar := range bars {
+		x := i * columnWidth
+		columnHeight := bar.Value * height / maxValue
+		if bar.Value > 0 {
+			columnHeight = max(1, columnHeight)
+		}
+		y := top + height - columnHeight
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"><title>%s: %d</title></rect>`,
+			x+4, y, columnWidth-8, columnHeight, chartColors[0], html.EscapeString(bar.Label), bar.Value)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle" fill="currentColor">%d</text>`, x+columnWidth/2, y-4, bar.Value)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle" fill="currentColor">%s</text>`, x+columnWidth/2, top+height+16, html.EscapeString(bar.Label))
+	}
+	b.WriteString("</svg>")
+	return template.HTML(b.String())
+}
+
+// pieChart returns an SVG pie chart of bars with a legend, e.g.
+// {{pieChart .Charts.Versions}}.
+func pieChart(bars []ChartBar) template.HTML {
+	const radius, legendX, rowHeight = 90, 200, 20
+	total := 0
+	for _, bar := range bars {
+		total += bar.Value
+	}
+	height := max(2*radius+10, len(bars)*rowHeight)
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="chart" role="img" width="%d" height="%d" viewBox="0 0 %[1]d %[2]d">`, legendX+260, height)
+	angle := -math.Pi / 2
+	for i, bar := range bars {
+		color := chartColors[i%len(chartColors)]
+		title := fmt.Sprintf("<title>%s: %d</title>", html.EscapeString(bar.Label), bar.Value)
+		switch {
+		case total == 0 || bar.Value == 0:
+		case bar.Value == total:
+			fmt.Fprintf(&b, `<circle cx="%d" cy="%d" r="%d" fill="%s">%s</circle>`, radius+5, radius+5, radius, color, title)
+		default:
+			next := angle + 2*math.Pi*float64(bar.Value)/float64(total)
+			large := 0
+			if next-angle > math.Pi {
+				large = 1
+			}
+			fmt.Fprintf(&b, `<path d="M%d,%d L%.2f,%.2f A%d,%d 0 %d,1 %.2f,%.2f Z" fill="%s">%s</path>`,
+				radius+5, radius+5, radius+5+radius*math.Cos(angle), radius+5+radius*math.Sin(angle),
+				radius, radius, large, radius+5+radius*math.Cos(next), radius+5+radius*math.Sin(next), color, title)
+			angle = next
+		}
+		y := i * rowHeight
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="12" height="12" fill="%s"/>`, legendX, y+4, color)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" fill="currentColor">%s (%d)</text>`, legendX+18, y+15, svgText(bar.Label, 30), bar.Value)
+	}
+	b.WriteString("</svg>")
+	return template.HTML(b.String())
+}
+
+// maxBar returns the largest value of bars, at least 1.
+func maxBar(bars []ChartBar) int {
+	maxValue := 1
+	for _, bar := range bars {
+		maxValue = max(maxValue, bar.Value)
+	}
+	return maxValue
+}
+
+// svgText escapes label for an SVG text element, shortened to n characters.
+func svgText(label string, n int) string {
+	if runes := []rune(label); len(runes) > n {
+		label = string(runes[:n-1]) + "…"
+	}
+	return html.EscapeString(label)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runCLI runs nmapTables with args and returns what it wrote to standard
+// output.
+func runCLI(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	stdout, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+	saved := os.Stdout
+	os.Stdout = stdout
+	defer func() { os.Stdout = saved }()
+	cmd := newRootCmd()
+	cmd.SetArgs(normalizeArgs(cmd, args))
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	runErr := execute(cmd)
+	if _, err := stdout.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data), runErr
+}
+
+// writeFile writes data to name in dir, returning its path.
+func writeFile(t *testing.T, dir, name, data string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRootReportAlias(t *testing.T) {
+	dir := t.TempDir()
+	scan := writeFile(t, dir, "scan.xml", redactTestScan)
+	args := []string{scan, "--service", "http", "--output-format", "hostports", "--quiet"}
+	alias, err := runCLI(t, args...)
+	if err != nil {
+		t.Fatalf("nmapTables SCAN: %v", err)
+	}
+	report, err := runCLI(t, append([]string{"report"}, args...)...)
+	if err != nil {
+		t.Fatalf("nmapTables report SCAN: %v", err)
+	}
+	if alias != report {
+		t.Errorf("nmapTables SCAN wrote %q, nmapTables report SCAN %q", alias, report)
+	}
+	if want := "10.0.0.6:80\n"; !strings.Contains(report, want) {
+		t.Errorf("report wrote %q, want %q in it", report, want)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Column is a column of the report tables, chosen with --columns.
+type Column struct {
+	Name  string
+	Title string
+	// width is the preferred width in mm of the column in PDF reports; 0
+	// shares the remaining width.
+	width float64
+	// host returns the value of a column describing each host of a row, and
+	// row the value of a column describing the row as a whole.
+	host func(host *HostPort) string
+	row  func(row *ReportRow) string
+}
+
+// allColumns are every column that --columns can select.
+var allColumns = []Column{
+	{Name: "host", Title: "Host", width: 35, host: func(h *HostPort) string { return h.Addr }},
+	{Name: "hostport", Title: "Host", width: 45, host: func(h *HostPort) string { return h.String() }},
+	{Name: "hostname", Title: "Hostname", width: 45, host: func(h *HostPort) string { return h.Hostname }},
+	{Name: "port", Title: "Port", width: 15, host: func(h *HostPort) string { return h.Port }},
+	{Name: "protocol", Title: "Protocol", width: 20, row: func(r *ReportRow) string { return r.Protocol }},
+	{Name: "service", Title: "Service", width: 25, row: func(r *ReportRow) string { return r.Service }},
+	{Name: "count", Title: "Hosts", width: 15, row: func(r *ReportRow) string { return strconv.Itoa(r.HostCount()) }},
+	{Name: "product", Title: "Product", row: func(r *ReportRow) string { return r.Product }},
+	{Name: "version", Title: "Version", width: 25, row: func(r *ReportRow) string { return r.ProductVersion }},
+	{Name: "product-version", Title: "Version", row: func(r *ReportRow) string { return r.Version }},
+	{Name: "extrainfo", Title: "Extra Info", width: 40, host: func(h *HostPort) string { return h.ExtraInfo }},
+	{Name: "reason", Title: "Reason", width: 25, host: func(h *HostPort) string { return h.Reason }},
+	{Name: "reason-ttl", Title: "Reason TTL", width: 20, host: func(h *HostPort) string { return h.ReasonTTL }},
+	{Name: "ident", Title: "Ident Owner", width: 30, host: func(h *HostPort) string { return h.Ident }},
+	{Name: "cpe", Title: "CPE", width: 50, host: func(h *HostPort) string { return h.CPE }},
+	{Name: "verified", Title: "Verified", width: 20, row: func(r *ReportRow) string { return strconv.FormatBool(r.Verified) }},
+	{Name: "os", Title: "OS", width: 50, host: func(h *HostPort) string { return h.OS }},
+	{Name: "mac", Title: "MAC Address", width: 35, host: func(h *HostPort) string { return h.MAC }},
+	{Name: "vendor", Title: "Vendor", width: 35, host: func(h *HostPort) string { return h.Vendor }},
+	{Name: "source", Title: "Source File", host: func(h *HostPort) string { return h.Source }},
+	{Name: "seen", Title: "Seen", width: 35, host: func(h *HostPort) string { return formatSeen(h.Seen) }},
+	{Name: "country", Title: "Country", width: 20, host: func(h *HostPort) string { return h.Country }},
+	{Name: "asn", Title: "ASN", width: 50, host: func(h *HostPort) string { return h.ASN }},
+	{Name: "owner", Title: "Owner", host: func(h *HostPort) string { return h.Owner }},
+	{Name: "environment", Title: "Environment", width: 30, host: func(h *HostPort) string { return h.Environment }},
+	{Name: "criticality", Title: "Criticality", width: 25, host: func(h *HostPort) string { return h.Criticality }},
+	{Name: "asset", Title: "Asset", host: func(h *HostPort) string { return assetContext(h.Environment, h.Owner) }},
+	{Name: "risk", Title: "Risk", width: 25, row: func(r *ReportRow) string { return r.Risk }},
+	{Name: "eol", Title: "End of Life", width: 40, row: func(r *ReportRow) string {
+		if r.EOL == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s (%s)", r.EOLName, r.EOL)
+	}},
+	// The NSE script details of the canned service layouts.
+	{Name: "url", Title: "URL", width: 55, host: func(h *HostPort) string { return h.URL }},
+	{Name: "screenshot", Title: "Screenshot", width: 45, host: func(h *HostPort) string { return h.Screenshot }},
+	{Name: "title", Title: "Title", width: 45, host: hostDetail("title")},
+	{Name: "server", Title: "Server", width: 35, host: hostDetail("server")},
+	{Name: "redirect", Title: "Redirect", width: 45, host: hostDetail("redirect")},
+	{Name: "methods", Title: "Methods", width: 35, host: hostDetail("methods")},
+	{Name: "cert-cn", Title: "Certificate CN", width: 45, host: hostDetail("cert-cn")},
+	{Name: "cert-expiry", Title: "Certificate Expiry", width: 30, host: hostDetail("cert-expiry")},
+	{Name: "mssql-instance", Title: "Instance", width: 35, host: hostDetail("mssql-instance")},
+	{Name: "smb-os", Title: "SMB OS", width: 45, host: hostDetail("smb-os")},
+	{Name: "smb-signing", Title: "Message Signing", width: 35, host: hostDetail("smb-signing")},
+	{Name: "smb-v1", Title: "SMBv1", width: 20, host: hostDetail("smb-v1")},
+	{Name: "smb-guest", Title: "Guest Access", width: 20, host: hostDetail("smb-guest")},
+	{Name: "smb-issues", Title: "Issues", width: 50, host: hostDetail("smb-issues")},
+	{Name: "snmp-device", Title: "Device Type", width: 30, host: hostDetail("snmp-device")},
+	{Name: "snmp-sysdescr", Title: "sysDescr", host: hostDetail("snmp-sysdescr")},
+	{Name: "snmp-communities", Title: "Community Strings", width: 40, host: hostDetail("snmp-communities")},
+	{Name: "snmp-enterprise", Title: "Enterprise", width: 30, host: hostDetail("snmp-enterprise")},
+	{Name: "domain", Title: "Domain", width: 40, row: rowDetail("domain")},
+	// The columns of the ad table, which has a row per host.
+	{Name: "ad-host", Title: "Host", width: 50, row: adHost},
+	{Name: "ad-role", Title: "Role", width: 35, row: adRole},
+	{Name: "ad-ports", Title: "AD Services", row: adPortList},
+	{Name: "ad-os", Title: "OS", width: 45, row: rowDetail("smb-os")},
+}
+
+// columnNames returns the names of every column, for error messages.
+func columnNames() string {
+	names := make([]string, len(allColumns))
+	for i, column := range allColumns {
+		names[i] = column.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// parseColumns validates a comma separated list of column names.
+func parseColumns(value string) ([]string, error) {
+	names := splitList(value)
+	for _, name := range names {
+		if _, ok := lookupColumn(name); !ok {
+			return nil, fmt.Errorf("unknown column %q (available: %s)", name, columnNames())
+		}
+	}
+	return names, nil
+}
+
+func lookupColumn(name string) (Column, bool) {
+	for _, column := range allColumns {
+		if column.Name == name {
+			return column, true
+		}
+	}
+	return Column{}, false
+}
+
+// selectColumns returns the columns called names, in that order.
+func selectColumns(names []string) []Column {
+	selected := make([]Column, 0, len(names))
+	for _, name := range names {
+		if column, ok := lookupColumn(name); ok {
+			selected = append(selected, column)
+		}
+	}
+	return selected
+}
+
+// Values returns the value of c for row: one per host for per-host columns,
+// otherwise a single value.
+func (c Column) Values(row ReportRow) []string {
+	if c.host == nil {
+		return []string{c.row(&row)}
+	}
+	values := make([]string, len(row.Hosts))
+	for i := range row.Hosts {
+		values[i] = c.host(&row.Hosts[i])
+	}
+	return values
+}
+
+// Value returns the value of c for a single host of row.
+func (c Column) Value(row *ReportRow, host *HostPort) string {
+	if c.host == nil {
+		return c.row(row)
+	}
+	return c.host(host)
+}
+
+// text returns the values of c for row, one per line.
+func (c Column) text(row *ReportRow) string {
+	return strings.Join(c.Values(*row), "\n")
+}
+
+// TableColumns returns the columns of the report tables: the --columns
+// selection, or else the host, service and version columns with the host
+// count, followed by the service layout's detail columns and the optional
+// columns the report's flags turn on.
+func (r ReportData) TableColumns() []Column {
+	if len(r.Columns) > 0 {
+		return r.Columns
+	}
+	names := []string{"hostport"}
+	if r.ShowHostname {
+		names = append(names, "hostname")
+	}
+	names = append(names, "protocol", "service", "product-version")
+	if r.ShowExtraInfo {
+		names = append(names, "extrainfo")
+	}
+	if r.ShowIdent {
+		names = append(names, "ident")
+	}
+	if r.ShowReason {
+		names = append(names, "reason", "reason-ttl")
+	}
+	names = append(names, "count")
+	names = append(names, r.layoutDetails()...)
+	if r.ShowScreenshots {
+		names = append(names, "screenshot")
+	}
+	if r.ShowOS {
+		names = append(names, "os")
+	}
+	if r.ShowMAC {
+		names = append(names, "mac", "vendor")
+	}
+	if r.ShowSource {
+		names = append(names, "source")
+	}
+	if r.ShowSeen {
+		names = append(names, "seen")
+	}
+	if r.ShowGeo {
+		names = append(names, "country", "asn")
+	}
+	if r.ShowAsset {
+		names = append(names, "asset", "criticality")
+	}
+	if r.ShowRisk {
+		names = append(names, "risk")
+	}
+	if r.ShowEOL {
+		names = append(names, "eol")
+	}
+	return selectColumns(names)
+}
+
+// sizedColumns returns the table columns of tableData, with the columns without
+// a preferred width sharing what is left of width.
+func sizedColumns(tableData *ReportData, width float64) []Column {
+	columns := append([]Column(nil), tableData.TableColumns()...)
+	remaining := width
+	flexible := 0
+	for _, column := range columns {
+		remaining -= column.width
+		if column.width == 0 {
+			flexible++
+		}
+	}
+	for i := range columns {
+		if columns[i].width == 0 {
+			columns[i].width = max(remaining/float64(flexible), 30)
+		}
+	}
+	return columns
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigPath returns ~/.config/nmaptables/config.yaml, honoring
+// XDG_CONFIG_HOME when it is set.
+func defaultConfigPath() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "nmaptables", "config.yaml")
+	}
+	return filepath.Join("~", ".config", "nmaptables", "config.yaml")
+}
+
+// applyConfig sets the flags of flagSet that were not given on the command line
+// from the YAML config file at path. Config keys are flag names without the
+// leading dash; lists are joined with commas. When path is empty the default
+// config file is used if it exists.
+func applyConfig(flagSet *pflag.FlagSet, path string) error {
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	path, err := resolveAbsPath(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		f := flagSet.Lookup(key)
+		if f == nil {
+			// Keys for other subcommands are allowed in a shared config file.
+			continue
+		}
+		if f.Changed {
+			continue
+		}
+		if err := flagSet.Set(key, configValue(config[key])); err != nil {
+			return fmt.Errorf("%s: invalid value for %s: %w", path, key, err)
+		}
+	}
+	return nil
+}
+
+// configValue converts a YAML value into its flag string representation.
+func configValue(value interface{}) string {
+	switch v := value.(type) {
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, configValue(item))
+		}
+		return strings.Join(parts, ",")
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
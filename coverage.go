@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// The coverage of an in-scope target, from worst to best.
+const (
+	coverageMissing  = "missing"
+	coverageDown     = "down"
+	coveragePingOnly = "ping-only"
+	coveragePartial  = "partial"
+	coverageScanned  = "scanned"
+)
+
+// coverageRank orders the coverage statuses from worst to best.
+var coverageRank = map[string]int{coverageMissing: 0, coverageDown: 1, coveragePingOnly: 2, coveragePartial: 3, coverageScanned: 4}
+
+// maxCoverageHostBits is the size, in host bits, of the largest scope network
+// whose addresses are checked one by one; larger networks are checked as a
+// whole.
+const maxCoverageHostBits = 16
+
+// scopeEntry is a network or hostname of a scope file.
+type scopeEntry struct {
+	prefix   netip.Prefix
+	hostname string
+}
+
+// String returns the entry as written in the scope file.
+func (e scopeEntry) String() string {
+	if e.hostname != "" {
+		return e.hostname
+	}
+	if e.prefix.IsSingleIP() {
+		return e.prefix.Addr().String()
+	}
+	return e.prefix.String()
+}
+
+// CoverageEntry is the coverage of an in-scope address or hostname by the
+// scans.
+type CoverageEntry struct {
+	Target string `json:"target"`
+	// Scope is the scope entry the target belongs to, e.g. its network.
+	Scope string `json:"scope"`
+	// Status is missing (in no scan), down (only reported down), ping-only
+	// (up, but only in scans without a port scan, such as nmap -sn), partial
+	// (only port scanned on fewer ports than --expect-ports) or scanned.
+	Status string `json:"status"`
+	// Note explains the status of networks too large to be checked by
+	// address.
+	Note string `json:"note,omitempty"`
+}
+
+// hostCoverage is what the scans saw of an address or hostname.
+type hostCoverage struct {
+	seen, up, portScanned bool
+	// fullyScanned is set once a scan that met the --expect-ports profile
+	// found the target up.
+	fullyScanned bool
+}
+
+// observe records that the target was in a scan, up or not, that scanned
+// ports or not and met the port profile or not.
+func (c *hostCoverage) observe(up, portScanned, fullyScanned bool) {
+	c.seen = true
+	c.up = c.up || up
+	c.portScanned = c.portScanned || (up && portScanned)
+	c.fullyScanned = c.fullyScanned || (up && fullyScanned)
+}
+
+// status returns the coverage status of c, which may be nil for targets that
+// are in no scan.
+func (c *hostCoverage) status() string {
+	switch {
+	case c == nil || !c.seen:
+		return coverageMissing
+	case !c.up:
+		return coverageDown
+	case !c.portScanned:
+		return coveragePingOnly
+	case !c.fullyScanned:
+		return coveragePartial
+	}
+	return coverageScanned
+}
+
+// readScopeFile reads the scope file at path: CIDRs, addresses and
+// hostnames, separated by newlines, commas or spaces, with # comments.
+func readScopeFile(path string) ([]scopeEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	outputManifest.input(path)
+	var entries []scopeEntry
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		for _, value := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			entry, err := parseScopeEntry(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// parseScopeEntry parses a CIDR, an address or a hostname.
+func parseScopeEntry(value string) (scopeEntry, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return scopeEntry{}, err
+		}
+		return scopeEntry{prefix: prefix.Masked()}, nil
+	}
+	if addr, err := netip.ParseAddr(value); err == nil {
+		return scopeEntry{prefix: netip.PrefixFrom(addr, addr.BitLen())}, nil
+	}
+	for _, r := range value {
+		if !(r == '-' || r == '.' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return scopeEntry{}, fmt.Errorf("invalid address or hostname %q", value)
+		}
+	}
+	return scopeEntry{hostname: strings.ToLower(strings.TrimSuffix(value, "."))}, nil
+}
+
+// scopeNetworks returns networks as scope entries.
+func scopeNetworks(networks []*net.IPNet) []scopeEntry {
+	var entries []scopeEntry
+	for _, network := range networks {
+		if prefix, err := netip.ParsePrefix(network.String()); err == nil {
+			entries = append(entries, scopeEntry{prefix: prefix})
+		}
+	}
+	return entries
+}
+
+// prefixAddrs returns the addresses of prefix, leaving out the network and
+// broadcast addresses of IPv4 networks larger than /31.
+func prefixAddrs(prefix netip.Prefix) []netip.Addr {
+	var addrs []netip.Addr
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	for addr := prefix.Addr(); addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+		addrs = append(addrs, addr)
+	}
+	if prefix.Addr().Is4() && hostBits > 1 {
+		addrs = addrs[1 : len(addrs)-1]
+	}
+	return addrs
+}
+
+// ScanCoverage checks which targets of scope the scans of runs cover: the
+// addresses and hostnames that are in no scan, that were only reported down
+// and that were only ping scanned or, with a profile, only scanned on fewer
+// ports than it expects. The addresses of the --exclude networks of opts are
+// left out. Scope networks of more than 2^16 addresses are checked as a
+// whole.
+func ScanCoverage(runs []Nmaprun, scope []scopeEntry, opts TableOptions, profile portProfile) []CoverageEntry {
+	byAddr := make(map[netip.Addr]*hostCoverage)
+	byName := make(map[string]*hostCoverage)
+	for i := range runs {
+		run := &runs[i]
+		// Ping scans (nmap -sn) have no scan information, nor ports.
+		portScan := len(run.Scaninfo) > 0
+		fullScan := profile == nil || len(profile.shortfalls(run)) == 0
+		for j := range run.Host {
+			host := &run.Host[j]
+			up := !isDown(host)
+			portScanned := portScan || len(host.Ports.Port) > 0
+			fullyScanned := portScanned && fullScan
+			for _, address := range host.Address {
+				addr, err := netip.ParseAddr(address.Addr)
+				if err != nil || address.Addrtype == "mac" {
+					continue
+				}
+				addr = addr.Unmap()
+				if byAddr[addr] == nil {
+					byAddr[addr] = &hostCoverage{}
+				}
+				byAddr[addr].observe(up, portScanned, fullyScanned)
+			}
+			for _, hostname := range host.Hostnames.Hostname {
+				name := strings.ToLower(strings.TrimSuffix(hostname.Name, "."))
+				if byName[name] == nil {
+					byName[name] = &hostCoverage{}
+				}
+				byName[name].observe(up, portScanned, fullyScanned)
+			}
+		}
+	}
+
+	var entries []CoverageEntry
+	seen := make(map[string]bool)
+	for _, target := range scope {
+		if target.hostname != "" {
+			if !seen[target.hostname] {
+				seen[target.hostname] = true
+				entries = append(entries, CoverageEntry{Target: target.hostname, Scope: target.String(), Status: byName[target.hostname].status()})
+			}
+			continue
+		}
+		if target.prefix.Addr().BitLen()-target.prefix.Bits() > maxCoverageHostBits {
+			entries = append(entries, networkCoverage(target, byAddr, opts))
+			continue
+		}
+		for _, addr := range prefixAddrs(target.prefix) {
+			if seen[addr.String()] || inNetworks(addr.String(), opts.Exclude) {
+				continue
+			}
+			seen[addr.String()] = true
+			entries = append(entries, CoverageEntry{Target: addr.String(), Scope: target.String(), Status: byAddr[addr].status()})
+		}
+	}
+	return entries
+}
+
+// networkCoverage returns the coverage of a network too large to be checked by
+// address: the best status of the addresses the scans saw in it.
+func networkCoverage(target scopeEntry, byAddr map[netip.Addr]*hostCoverage, opts TableOptions) CoverageEntry {
+	entry := CoverageEntry{Target: target.String(), Scope: target.String(), Status: coverageMissing}
+	counts := make(map[string]int)
+	for addr, coverage := range byAddr {
+		if !target.prefix.Contains(addr) || inNetworks(addr.String(), opts.Exclude) {
+			continue
+		}
+		status := coverage.status()
+		counts[status]++
+		if coverageRank[status] > coverageRank[entry.Status] {
+			entry.Status = status
+		}
+	}
+	entry.Note = fmt.Sprintf("too large to check by address: %d scanned, %d partial, %d ping-only and %d down hosts seen",
+		counts[coverageScanned], counts[coveragePartial], counts[coveragePingOnly], counts[coverageDown])
+	return entry
+}
+
+// coverageGaps returns the entries that are not scanned.
+func coverageGaps(entries []CoverageEntry) []CoverageEntry {
+	var gaps []CoverageEntry
+	for _, entry := range entries {
+		if entry.Status != coverageScanned || entry.Note != "" {
+			gaps = append(gaps, entry)
+		}
+	}
+	return gaps
+}
+
+// writeCoverage writes entries to w as a text table followed by the number of
+// targets of each status in all, as CSV or as JSON.
+func writeCoverage(w io.Writer, entries, all []CoverageEntry, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if entries == nil {
+			entries = []CoverageEntry{}
+		}
+		return encoder.Encode(entries)
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"target", "scope", "status", "note"}); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := cw.Write([]string{entry.Target, entry.Scope, entry.Status, entry.Note}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "", "text":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "TARGET\tSTATUS\tSCOPE\tNOTE")
+		for _, entry := range entries {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", entry.Target, entry.Status, entry.Scope, entry.Note)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		counts := make(map[string]int)
+		for _, entry := range all {
+			counts[entry.Status]++
+		}
+		_, err := fmt.Fprintf(w, "\n%d targets in scope: %d scanned, %d partial, %d ping-only, %d down, %d missing\n", len(all),
+			counts[coverageScanned], counts[coveragePartial], counts[coveragePingOnly], counts[coverageDown], counts[coverageMissing])
+		return err
+	}
+	return fmt.Errorf("unsupported coverage format: %s (use text, csv or json)", format)
+}
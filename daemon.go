@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// archiveTimeFormat is the timestamp of the report versions archived by the
+// daemon command.
+const archiveTimeFormat = "20060102-150405"
+
+// daemonReportFile returns the file the reports of f are written to. The
+// daemon only writes document formats: there is no one reading stdout.
+func daemonReportFile(f *reportFlags) (string, error) {
+	renderer, err := lookupRenderer(f.outputFormat)
+	if err != nil {
+		return "", err
+	}
+	document, ok := renderer.(documentRenderer)
+	if !ok {
+		return "", fmt.Errorf("daemon writes report files; %s is written to stdout (use html, pdf or docx)", f.outputFormat)
+	}
+	services := []string{f.table.service}
+	if _, ok := renderer.(multiRenderer); ok {
+		services = splitList(f.table.service)
+	}
+	return f.output.filename(fmt.Sprintf("%s.%s", strings.Join(services, "_"), document.Extension()))
+}
+
+// archiveFile moves path, if it exists, into archiveDir as
+// <name>-<timestamp><ext>, stamped with the time it was last written so that
+// each archived version is named after the run that generated it.
+func archiveFile(path, archiveDir string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(archiveDir, 0o755); err != nil {
+		return err
+	}
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	archived := filepath.Join(archiveDir, strings.TrimSuffix(base, ext)+"-"+info.ModTime().Format(archiveTimeFormat)+ext)
+	if err := os.Rename(path, archived); err != nil {
+		return err
+	}
+	logger.Info("archived previous report", "file", archived)
+	return nil
+}
+
+// daemonRun is a run of the daemon command.
+type daemonRun struct {
+	f          *daemonFlags
+	args       []string
+	reportFile string
+	diffFile   string
+	archiveDir string
+	// previous are the scans of the last run, compared with the next one by
+	// --diff.
+	previous []Nmaprun
+}
+
+// run generates the report and, with --manifest, writes the manifest of the
+// files of this run, archiving that of the previous run with its report: the
+// daemon never returns for the manifest to be written when it finishes.
+func (d *daemonRun) run() error {
+	if outputManifest == nil {
+		return d.generate()
+	}
+	if err := archiveFile(outputManifest.path, d.archiveDir); err != nil {
+		return fmt.Errorf("Error archiving the previous manifest: %w", err)
+	}
+	var err error
+	if outputManifest, err = newManifest(outputManifest.path); err != nil {
+		return err
+	}
+	err = d.generate()
+	if writeErr := outputManifest.write(); err == nil {
+		err = writeErr
+	}
+	return err
+}
+
+// generate archives the files of the previous run and regenerates the report
+// from the scans found now, and with --diff writes how they differ from those
+// of the previous run.
+func (d *daemonRun) generate() error {
+	if err := archiveFile(d.reportFile, d.archiveDir); err != nil {
+		return fmt.Errorf("Error archiving the previous report: %w", err)
+	}
+	// The scans of the report are those compared: nil when it failed before
+	// parsing them.
+	var runs []Nmaprun
+	d.f.report.parsed = func(parsed []Nmaprun) { runs = parsed }
+	defer func() { d.f.report.parsed = nil }()
+	if err := runReport(&d.f.report, d.args); err != nil {
+		var exit *exitError
+		if !errors.As(err, &exit) || exit.code != exitNoMatches {
+			return err
+		}
+		logger.Warn("no hosts matched the filters", "file", d.reportFile)
+	}
+	if !d.f.diff || runs == nil {
+		return nil
+	}
+	opts, err := d.f.report.table.options()
+	if err != nil {
+		return err
+	}
+	previous := d.previous
+	d.previous = runs
+	if previous == nil {
+		return nil
+	}
+	if err := archiveFile(d.diffFile, d.archiveDir); err != nil {
+		return fmt.Errorf("Error archiving the previous diff: %w", err)
+	}
+	entries := DiffScans(previous, runs, opts)
+	file, err := os.Create(d.diffFile)
+	if err != nil {
+		return err
+	}
+	outputManifest.output(d.diffFile)
+	if err := writeDiff(file, entries); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	logger.Info("scans compared with the previous run", "file", d.diffFile, "changes", len(entries))
+	statusf("%d changes since the previous run written to %s\n", len(entries), d.diffFile)
+	return nil
+}
+
+// runDaemon implements the daemon subcommand, which regenerates the report of
+// f on f.schedule until it is stopped. Each run moves the report of the
+// previous one into the archive directory first. Runs that fail are logged
+// and the daemon carries on with the next.
+func runDaemon(f *daemonFlags, args []string) error {
+	if f.schedule == "" {
+		return errors.New("Please provide when to generate the report using the --schedule flag, e.g. --schedule \"0 6 * * *\"")
+	}
+	sched, err := parseSchedule(f.schedule)
+	if err != nil {
+		return err
+	}
+	if sched.next(time.Now()).IsZero() {
+		return fmt.Errorf("invalid schedule %q: it never runs", f.schedule)
+	}
+	if f.report.input.watch {
+		return errors.New("--watch cannot be used with daemon; its --schedule says when to regenerate")
+	}
+	if f.report.splitBy != "" {
+		return errors.New("--split-by cannot be used with daemon")
+	}
+	// A missing input directory is fine while the scans are yet to come, but
+	// flags that can never work are reported now rather than at the first run.
+	if _, err := f.report.table.options(); err != nil {
+		return err
+	}
+	reportFile, err := daemonReportFile(&f.report)
+	if err != nil {
+		return err
+	}
+	archiveDir := f.archiveDir
+	if archiveDir == "" {
+		archiveDir = filepath.Join(filepath.Dir(reportFile), "archive")
+	}
+	if archiveDir, err = resolveAbsPath(archiveDir); err != nil {
+		return err
+	}
+	ext := filepath.Ext(reportFile)
+	d := &daemonRun{
+		f:          f,
+		args:       args,
+		reportFile: reportFile,
+		diffFile:   strings.TrimSuffix(reportFile, ext) + "-diff.txt",
+		archiveDir: archiveDir,
+	}
+
+	if f.now {
+		if err := d.run(); err != nil {
+			logger.Error("could not generate report", "file", reportFile, "err", err)
+		}
+	}
+	for {
+		next := sched.next(time.Now())
+		logger.Info("next report", "file", reportFile, "at", next.Format(time.RFC3339))
+		statusf("Next report at %s\n", next.Format("2006-01-02 15:04"))
+		time.Sleep(time.Until(next))
+		if err := d.run(); err != nil {
+			logger.Error("could not generate report", "file", reportFile, "err", err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestDaemonRunManifest(t *testing.T) {
+	dir := t.TempDir()
+	scan := writeFile(t, dir, "scans/scan.xml", redactTestScan)
+	f := &daemonFlags{diff: true}
+	flags := pflag.NewFlagSet("daemon", pflag.ContinueOnError)
+	f.report.register(flags)
+	reportFile := filepath.Join(dir, "http.html")
+	if err := flags.Parse([]string{"--service", "http", "-o", reportFile, "--force"}); err != nil {
+		t.Fatal(err)
+	}
+	saved, savedQuiet := outputManifest, quiet
+	defer func() { outputManifest, quiet = saved, savedQuiet }()
+	quiet = true
+	manifestFile := filepath.Join(dir, "manifest.txt")
+	var err error
+	if outputManifest, err = newManifest(manifestFile); err != nil {
+		t.Fatal(err)
+	}
+	d := &daemonRun{
+		f:          f,
+		args:       []string{scan},
+		reportFile: reportFile,
+		diffFile:   filepath.Join(dir, "http-diff.txt"),
+		archiveDir: filepath.Join(dir, "archive"),
+	}
+	for run := 1; run <= 2; run++ {
+		if err := d.run(); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+		data, err := os.ReadFile(manifestFile)
+		if err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+		manifest := string(data)
+		for _, file := range []string{"scans/scan.xml", "http.html"} {
+			if !strings.Contains(manifest, "  "+file+"\n") {
+				t.Errorf("run %d manifest has no %s:\n%s", run, file, manifest)
+			}
+		}
+		// The first run has nothing to compare its scans with.
+		if hasDiff := strings.Contains(manifest, "http-diff.txt"); hasDiff != (run == 2) {
+			t.Errorf("run %d manifest lists the diff: %t, want %t:\n%s", run, hasDiff, run == 2, manifest)
+		}
+	}
+	if _, err := os.Stat(d.diffFile); err != nil {
+		t.Errorf("no diff written: %v", err)
+	}
+	archived, err := filepath.Glob(filepath.Join(d.archiveDir, "manifest-*.txt"))
+	if err != nil || len(archived) != 1 {
+		t.Errorf("archived manifests %q, want the first run's", archived)
+	}
+}
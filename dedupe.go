@@ -0,0 +1,35 @@
+package main
+
+import (
+	"crypto/sha256"
+)
+
+// parsedScan is a run along with the SHA-256 digest of the XML it was parsed
+// from, used to recognise copied or renamed duplicates of the same scan.
+type parsedScan struct {
+	run    Nmaprun
+	file   string
+	digest [sha256.Size]byte
+}
+
+// uniqueRuns returns the runs of scans, in order, leaving out every scan whose
+// content is identical to an earlier one, and logs how many were skipped (and,
+// with -v, which) so that duplicates do not inflate the counts of the report.
+func uniqueRuns(scans []parsedScan) []Nmaprun {
+	seen := make(map[[sha256.Size]byte]string)
+	runs := make([]Nmaprun, 0, len(scans))
+	skipped := 0
+	for _, scan := range scans {
+		if first, ok := seen[scan.digest]; ok {
+			logger.Info("skipping duplicate scan", "file", scan.file, "duplicate_of", first)
+			skipped++
+			continue
+		}
+		seen[scan.digest] = scan.file
+		runs = append(runs, scan.run)
+	}
+	if skipped > 0 {
+		logger.Warn("skipped duplicate scan files", "count", skipped)
+	}
+	return runs
+}
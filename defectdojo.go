@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// dojoFindings is a DefectDojo Generic Findings Import document.
+type dojoFindings struct {
+	Findings []dojoFinding `json:"findings"`
+}
+
+// dojoFinding is a finding of the DefectDojo generic JSON format: a service
+// version with the endpoints of every host running it.
+type dojoFinding struct {
+	Title            string         `json:"title"`
+	Description      string         `json:"description"`
+	Severity         string         `json:"severity"`
+	Date             string         `json:"date"`
+	UniqueIDFromTool string         `json:"unique_id_from_tool"`
+	ComponentName    string         `json:"component_name,omitempty"`
+	ComponentVersion string         `json:"component_version,omitempty"`
+	Mitigation       string         `json:"mitigation,omitempty"`
+	Active           bool           `json:"active"`
+	Verified         bool           `json:"verified"`
+	StaticFinding    bool           `json:"static_finding"`
+	DynamicFinding   bool           `json:"dynamic_finding"`
+	Endpoints        []dojoEndpoint `json:"endpoints"`
+}
+
+// dojoEndpoint is a host port of a DefectDojo finding; Protocol is the
+// service name, which DefectDojo shows as the URL scheme of the endpoint.
+type dojoEndpoint struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// dojoSeverities are the DefectDojo severities of risk labels.
+var dojoSeverities = map[string]string{
+	"critical":      "Critical",
+	"high":          "High",
+	"medium":        "Medium",
+	"low":           "Low",
+	"info":          "Info",
+	"informational": "Info",
+}
+
+// dojoSeverity returns the DefectDojo severity of row: that of its risk label,
+// else Medium for end-of-life versions and Info for everything else.
+func dojoSeverity(row *ReportRow) string {
+	if severity, ok := dojoSeverities[strings.ToLower(row.Risk)]; ok {
+		return severity
+	}
+	if row.EOL != "" {
+		return "Medium"
+	}
+	return "Info"
+}
+
+// writeDefectDojo writes tableData to w in DefectDojo's Generic Findings
+// Import JSON format, pre-aggregated with a finding per row, so each service
+// version is a single finding listing every host port as an endpoint.
+func writeDefectDojo(tableData ReportData, w io.Writer) error {
+	date := tableData.Summary.ScanEnd
+	if date.IsZero() {
+		date = time.Now()
+	}
+	doc := dojoFindings{Findings: []dojoFinding{}}
+	for _, rows := range [][]ReportRow{tableData.Rows, tableData.Unverified} {
+		for i := range rows {
+			doc.Findings = append(doc.Findings, dojoRowFinding(&rows[i], date))
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// dojoRowFinding returns the finding of row, dated date.
+func dojoRowFinding(row *ReportRow, date time.Time) dojoFinding {
+	version := strings.TrimSpace(row.Version)
+	label := version
+	if label == "" {
+		label = "unknown version"
+	}
+	finding := dojoFinding{
+		Title:            fmt.Sprintf("%s exposed: %s (%s)", row.Service, label, plural(row.HostCount(), "host")),
+		Severity:         dojoSeverity(row),
+		Date:             date.Format(time.DateOnly),
+		UniqueIDFromTool: strings.Join([]string{"nmaptables", row.Service, row.Protocol, row.Product, row.ProductVersion}, ":"),
+		ComponentName:    row.Product,
+		ComponentVersion: row.ProductVersion,
+		Active:           true,
+		Verified:         row.Verified,
+		DynamicFinding:   true,
+	}
+
+	var description strings.Builder
+	fmt.Fprintf(&description, "Nmap detected %s on %s (%s).\n", label, plural(len(row.Hosts), "host port"), row.Protocol)
+	if row.Risk != "" {
+		fmt.Fprintf(&description, "Risk: %s", row.Risk)
+		if row.RiskRule != "" {
+			fmt.Fprintf(&description, " (rule %s)", row.RiskRule)
+		}
+		description.WriteString("\n")
+	}
+	if row.EOL != "" {
+		fmt.Fprintf(&description, "End of life: %s\n", row.EOL)
+		finding.Mitigation = fmt.Sprintf("Upgrade %s to a supported version.", label)
+	}
+	if !row.Verified {
+		description.WriteString("Detection confidence is below the --min-conf threshold.\n")
+	}
+	description.WriteString("\nHosts:\n")
+	for _, host := range row.Hosts {
+		fmt.Fprintf(&description, "- %s", host)
+		if host.Hostname != "" {
+			fmt.Fprintf(&description, " (%s)", host.Hostname)
+		}
+		description.WriteString("\n")
+		finding.Endpoints = append(finding.Endpoints, dojoEndpoint{Host: host.Addr, Port: atoi(host.Port), Protocol: row.Service})
+	}
+	finding.Description = description.String()
+	return finding
+}
+
+// plural returns n with noun, adding an s unless n is 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
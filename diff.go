@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+)
+
+// portObservation is what was seen on a single host port.
+type portObservation struct {
+	Service string `json:"service"`
+	Version string `json:"version"`
+}
+
+// DiffEntry is a single difference between two sets of scans.
+type DiffEntry struct {
+	// Change is "+" for a new port, "-" for a port that disappeared and "~"
+	// for a port whose service or version changed.
+	Change   string          `json:"change"`
+	HostPort string          `json:"hostport"`
+	Old      portObservation `json:"old"`
+	New      portObservation `json:"new"`
+}
+
+// observePorts returns the reported ports of runs keyed by addr:port/protocol.
+// An empty opts.ServiceName matches every service.
+func observePorts(runs []Nmaprun, opts TableOptions) map[string]portObservation {
+	observed := make(map[string]portObservation)
+	hosts := MergeHosts(runs, opts.PreferIPv6)
+	normalizeHosts(hosts, opts.Normalize)
+	for i := range hosts {
+		host := &hosts[i]
+		addr := hostAddress(host, opts.PreferIPv6)
+		if opts.excluded(addr) {
+			continue
+		}
+		for _, port := range host.Ports.Port {
+			if !opts.States[port.State.State] {
+				continue
+			}
+			if opts.ServiceName != "" && port.Service.Name != opts.ServiceName {
+				continue
+			}
+			key := net.JoinHostPort(addr, port.Portid) + "/" + port.Protocol
+			observed[key] = portObservation{
+				Service: port.Service.Name,
+				Version: fmt.Sprintf("%s %s", port.Service.Product, port.Service.Version),
+			}
+		}
+	}
+	return observed
+}
+
+// DiffScans compares the ports matching opts in oldRuns and newRuns.
+func DiffScans(oldRuns, newRuns []Nmaprun, opts TableOptions) []DiffEntry {
+	oldPorts := observePorts(oldRuns, opts)
+	newPorts := observePorts(newRuns, opts)
+
+	var entries []DiffEntry
+	for key, newObs := range newPorts {
+		oldObs, ok := oldPorts[key]
+		switch {
+		case !ok:
+			entries = append(entries, DiffEntry{Change: "+", HostPort: key, New: newObs})
+		case oldObs != newObs:
+			entries = append(entries, DiffEntry{Change: "~", HostPort: key, Old: oldObs, New: newObs})
+		}
+	}
+	for key, oldObs := range oldPorts {
+		if _, ok := newPorts[key]; !ok {
+			entries = append(entries, DiffEntry{Change: "-", HostPort: key, Old: oldObs})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].HostPort != entries[j].HostPort {
+			return entries[i].HostPort < entries[j].HostPort
+		}
+		return entries[i].Change < entries[j].Change
+	})
+	return entries
+}
+
+// writeDiff writes entries to w in a diff-like text format.
+func writeDiff(w io.Writer, entries []DiffEntry) error {
+	for _, entry := range entries {
+		var err error
+		switch entry.Change {
+		case "+":
+			_, err = fmt.Fprintf(w, "+ %s %s %s\n", entry.HostPort, entry.New.Service, entry.New.Version)
+		case "-":
+			_, err = fmt.Fprintf(w, "- %s %s %s\n", entry.HostPort, entry.Old.Service, entry.Old.Version)
+		default:
+			_, err = fmt.Fprintf(w, "~ %s %s %s -> %s %s\n", entry.HostPort,
+				entry.Old.Service, entry.Old.Version, entry.New.Service, entry.New.Version)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The port discovery tools whose output is read along with nmap XML.
+const (
+	discoveryRustscan = "rustscan"
+	discoveryNaabu    = "naabu"
+	discoveryZmap     = "zmap"
+)
+
+// The lines of rustscan output: its greppable output (-g), e.g.
+// "10.0.0.1 -> [22,80]", and the Open lines of its default output, e.g.
+// "Open 10.0.0.1:22".
+var (
+	rustscanGreppable = regexp.MustCompile(`^(\S+)\s+->\s+\[([\d,\s]*)\]$`)
+	rustscanOpen      = regexp.MustCompile(`^Open\s+\[?([^\s\]]+?)\]?:(\d+)$`)
+)
+
+// wellKnownPorts are the services guessed for the ports found by discovery
+// tools, which only report that a port is open. As nmap does for the ports it
+// did not probe, the guess is made with the "table" method and confidence 3, so
+// that the detections of a version scan of the same port win when merged.
+var wellKnownPorts = map[string]string{
+	"tcp/21": "ftp", "tcp/22": "ssh", "tcp/23": "telnet", "tcp/25": "smtp", "tcp/53": "domain",
+	"tcp/80": "http", "tcp/88": "kerberos-sec", "tcp/110": "pop3", "tcp/111": "rpcbind", "tcp/135": "msrpc",
+	"tcp/139": "netbios-ssn", "tcp/143": "imap", "tcp/389": "ldap", "tcp/443": "https", "tcp/445": "microsoft-ds",
+	"tcp/465": "smtps", "tcp/502": "modbus", "tcp/587": "submission", "tcp/636": "ldapssl", "tcp/993": "imaps",
+	"tcp/995": "pop3s", "tcp/1433": "ms-sql-s", "tcp/1521": "oracle", "tcp/2049": "nfs", "tcp/3268": "globalcatLDAP",
+	"tcp/3306": "mysql", "tcp/3389": "ms-wbt-server", "tcp/5432": "postgresql", "tcp/5900": "vnc", "tcp/5985": "wsman",
+	"tcp/6379": "redis", "tcp/8080": "http-proxy", "tcp/8443": "https-alt", "tcp/27017": "mongod",
+	"udp/53": "domain", "udp/69": "tftp", "udp/123": "ntp", "udp/137": "netbios-ns", "udp/161": "snmp",
+	"udp/500": "isakmp", "udp/1900": "upnp", "udp/5353": "zeroconf",
+}
+
+// discoveryScan collects the open ports found by a discovery tool into the
+// hosts of an Nmaprun, in the order their address was first seen.
+type discoveryScan struct {
+	run   Nmaprun
+	index map[string]int
+	seen  map[string]bool
+}
+
+// newDiscoveryScan returns an empty scan of scanner, run with args.
+func newDiscoveryScan(scanner, args string) *discoveryScan {
+	s := &discoveryScan{index: make(map[string]int), seen: make(map[string]bool)}
+	s.run.Scanner = scanner
+	s.run.Args = args
+	return s
+}
+
+// add records that port/protocol of addr is open, as reported for hostname if
+// set. tls is set when the tool found the port speaking TLS. Addresses are
+// normalised, so that they match those of nmap, and repeated ports are
+// dropped.
+func (s *discoveryScan) add(addr, hostname, protocol, port string, tls bool) error {
+	ip, err := netip.ParseAddr(strings.Trim(addr, "[]"))
+	if err != nil {
+		return fmt.Errorf("invalid address %q", addr)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	ip = ip.Unmap()
+	addr = ip.String()
+	pos, ok := s.index[addr]
+	if !ok {
+		var host NmapHost
+		host.Status.State = "up"
+		host.Status.Reason = "syn-ack"
+		addrtype := "ipv4"
+		if ip.Is6() {
+			addrtype = "ipv6"
+		}
+		host.Address = []NmapAddress{{Addr: addr, Addrtype: addrtype}}
+		pos = len(s.run.Host)
+		s.index[addr] = pos
+		s.run.Host = append(s.run.Host, host)
+	}
+	host := &s.run.Host[pos]
+	if hostname != "" && hostname != addr && !slices.ContainsFunc(host.Hostnames.Hostname, func(h NmapHostname) bool { return h.Name == hostname }) {
+		host.Hostnames.Hostname = append(host.Hostnames.Hostname, NmapHostname{Name: hostname, Type: "user"})
+	}
+	key := addr + " " + protocol + "/" + port
+	if s.seen[key] {
+		return nil
+	}
+	s.seen[key] = true
+	var p NmapPort
+	p.Protocol = protocol
+	p.Portid = port
+	p.State.State = "open"
+	p.State.Reason = "syn-ack"
+	if name, ok := wellKnownPorts[protocol+"/"+port]; ok {
+		p.Service = NmapService{Name: name, Method: "table", Conf: "3"}
+	}
+	if tls && !tlsServices[p.Service.Name] {
+		p.Service.Tunnel = "ssl"
+	}
+	host.Ports.Port = append(host.Ports.Port, p)
+	return nil
+}
+
+// finish returns the scan, with the run statistics of its hosts. Its start
+// time is left unset: the discovery of a port must not replace the version
+// detection of an nmap scan with --merge latest.
+func (s *discoveryScan) finish() Nmaprun {
+	hosts := strconv.Itoa(len(s.run.Host))
+	s.run.Runstats.Hosts.Up = hosts
+	s.run.Runstats.Hosts.Down = "0"
+	s.run.Runstats.Hosts.Total = hosts
+	return s.run
+}
+
+// lineError returns the ParseError of line, which starts at offset, of a
+// discovery output. The hosts of the lines before it are recovered.
+func (s *discoveryScan) lineError(filePath string, offset int64, line int, err error) *ParseError {
+	return &ParseError{File: filePath, Offset: offset, Reason: fmt.Sprintf("%s output line %d: %v", s.run.Scanner, line, err), Recovered: len(s.run.Host)}
+}
+
+// discoveryFormat returns the port discovery tool that wrote fileData, or ""
+// when it is none of rustscan greppable or default output, naabu JSON lines
+// and zmap CSV.
+func discoveryFormat(fileData []byte) string {
+	trimmed := bytes.TrimSpace(fileData)
+	if len(trimmed) == 0 || trimmed[0] == '<' {
+		return ""
+	}
+	if trimmed[0] == '{' {
+		return discoveryNaabu
+	}
+	header, _, _ := bytes.Cut(trimmed, []byte("\n"))
+	if slices.Contains(strings.Split(strings.TrimSpace(string(header)), ","), "saddr") {
+		return discoveryZmap
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if rustscanGreppable.MatchString(line) || rustscanOpen.MatchString(line) {
+			return discoveryRustscan
+		}
+	}
+	return ""
+}
+
+// parseDiscoveryData converts the output of the port discovery tool format,
+// see discoveryFormat, into an Nmaprun of its open ports, so that it can be
+// merged with nmap scans.
+func parseDiscoveryData(filePath string, fileData []byte, format string) (Nmaprun, *ParseError) {
+	switch format {
+	case discoveryNaabu:
+		return parseNaabu(filePath, fileData)
+	case discoveryZmap:
+		return parseZmap(filePath, fileData)
+	}
+	return parseRustscan(filePath, fileData)
+}
+
+// parseRustscan reads rustscan greppable output and the Open lines of its
+// default output. Its other lines, such as the banner and the output of the
+// nmap scan rustscan runs, are skipped.
+func parseRustscan(filePath string, fileData []byte) (Nmaprun, *ParseError) {
+	s := newDiscoveryScan(discoveryRustscan, "rustscan")
+	var offset int64
+	for i, line := range strings.SplitAfter(string(fileData), "\n") {
+		lineOffset := offset
+		offset += int64(len(line))
+		line = strings.TrimSpace(line)
+		if m := rustscanGreppable.FindStringSubmatch(line); m != nil {
+			for _, port := range splitList(m[2]) {
+				if err := s.add(m[1], "", "tcp", port, false); err != nil {
+					return s.finish(), s.lineError(filePath, lineOffset, i+1, err)
+				}
+			}
+		} else if m := rustscanOpen.FindStringSubmatch(line); m != nil {
+			if err := s.add(m[1], "", "tcp", m[2], false); err != nil {
+				return s.finish(), s.lineError(filePath, lineOffset, i+1, err)
+			}
+		}
+	}
+	return s.finish(), nil
+}
+
+// naabuResult is a line of naabu JSON output (-json). Older versions of naabu
+// write the port as an object.
+type naabuResult struct {
+	Host     string          `json:"host"`
+	IP       string          `json:"ip"`
+	Port     json.RawMessage `json:"port"`
+	Protocol string          `json:"protocol"`
+	TLS      bool            `json:"tls"`
+}
+
+// port returns the port number and protocol of r, and whether it speaks TLS.
+func (r naabuResult) port() (string, string, bool, error) {
+	var number int
+	if err := json.Unmarshal(r.Port, &number); err == nil {
+		return strconv.Itoa(number), r.Protocol, r.TLS, nil
+	}
+	var port struct {
+		Port     int  `json:"Port"`
+		Protocol any  `json:"Protocol"`
+		TLS      bool `json:"TLS"`
+	}
+	if err := json.Unmarshal(r.Port, &port); err != nil {
+		return "", "", false, fmt.Errorf("invalid port %s", r.Port)
+	}
+	protocol := r.Protocol
+	if name, ok := port.Protocol.(string); ok {
+		protocol = name
+	}
+	return strconv.Itoa(port.Port), protocol, r.TLS || port.TLS, nil
+}
+
+// parseNaabu reads naabu JSON lines output. The hosts of the lines before a
+// broken one, such as the last line of an interrupted scan, are recovered.
+func parseNaabu(filePath string, fileData []byte) (Nmaprun, *ParseError) {
+	s := newDiscoveryScan(discoveryNaabu, "naabu -json")
+	var offset int64
+	for i, line := range strings.SplitAfter(string(fileData), "\n") {
+		lineOffset := offset
+		offset += int64(len(line))
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var result naabuResult
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			return s.finish(), s.lineError(filePath, lineOffset, i+1, err)
+		}
+		port, protocol, tls, err := result.port()
+		if err == nil {
+			addr := result.IP
+			if addr == "" {
+				addr = result.Host
+			}
+			protocol = strings.ToLower(protocol)
+			if protocol == "" {
+				protocol = "tcp"
+			}
+			err = s.add(addr, result.Host, protocol, port, tls)
+		}
+		if err != nil {
+			return s.finish(), s.lineError(filePath, lineOffset, i+1, err)
+		}
+	}
+	return s.finish(), nil
+}
+
+// parseZmap reads zmap CSV output (-O csv), which must include the saddr and
+// sport fields, e.g. -f saddr,sport,classification,success. When present, the
+// success and classification fields leave out the responses that are not open
+// ports, such as RSTs.
+func parseZmap(filePath string, fileData []byte) (Nmaprun, *ParseError) {
+	s := newDiscoveryScan(discoveryZmap, "zmap -O csv")
+	r := csv.NewReader(bytes.NewReader(bytes.TrimSpace(fileData)))
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return Nmaprun{}, &ParseError{File: filePath, Reason: err.Error()}
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns["sport"]; !ok {
+		return Nmaprun{}, &ParseError{File: filePath, Reason: "zmap output has no sport field, scan with -f saddr,sport,classification,success"}
+	}
+	field := func(record []string, name string) (string, bool) {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return "", false
+		}
+		return strings.TrimSpace(record[i]), true
+	}
+	for {
+		offset := r.InputOffset()
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// FieldPos only knows the records that were read.
+			var parseErr *csv.ParseError
+			line := 0
+			if errors.As(err, &parseErr) {
+				line = parseErr.Line
+			}
+			return s.finish(), s.lineError(filePath, offset, line, err)
+		}
+		line, _ := r.FieldPos(0)
+		if success, ok := field(record, "success"); ok && success != "1" && success != "true" {
+			continue
+		}
+		protocol := "tcp"
+		if classification, ok := field(record, "classification"); ok {
+			switch classification {
+			case "synack":
+			case "udp":
+				protocol = "udp"
+			default:
+				continue
+			}
+		}
+		addr, _ := field(record, "saddr")
+		port, _ := field(record, "sport")
+		if err := s.add(addr, "", protocol, port, false); err != nil {
+			return s.finish(), s.lineError(filePath, offset, line, err)
+		}
+	}
+	return s.finish(), nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// discoveredPorts returns the open ports of run as addr protocol/port[/ssl].
+func discoveredPorts(run Nmaprun) []string {
+	var ports []string
+	for _, host := range run.Host {
+		for _, port := range host.Ports.Port {
+			p := host.Address[0].Addr + " " + port.Protocol + "/" + port.Portid
+			if port.Service.Tunnel == "ssl" {
+				p += "/ssl"
+			}
+			ports = append(ports, p)
+		}
+	}
+	return ports
+}
+
+func TestParseDiscovery(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		parse  func(string, []byte) (Nmaprun, *ParseError)
+		data   string
+		want   []string
+		broken string
+	}{
+		{
+			name:  "rustscan greppable",
+			parse: parseRustscan,
+			data:  "10.0.0.5 -> [22,443]\n10.0.0.6 -> [80]\n",
+			want:  []string{"10.0.0.5 tcp/22", "10.0.0.5 tcp/443", "10.0.0.6 tcp/80"},
+		},
+		{
+			name:  "rustscan default output",
+			parse: parseRustscan,
+			data:  ".----. .-. .-.\nOpen 10.0.0.5:22\nOpen [2001:db8::1]:80\nOpen 10.0.0.5:22\n",
+			want:  []string{"10.0.0.5 tcp/22", "2001:db8::1 tcp/80"},
+		},
+		{
+			name:   "rustscan invalid port",
+			parse:  parseRustscan,
+			data:   "Open 10.0.0.5:22\nOpen 10.0.0.6:99999\nOpen 10.0.0.7:80\n",
+			want:   []string{"10.0.0.5 tcp/22"},
+			broken: "rustscan output line 2: invalid port",
+		},
+		{
+			name:  "naabu",
+			parse: parseNaabu,
+			data: `{"host":"web1.corp.local","ip":"10.0.0.5","port":5986,"protocol":"tcp","tls":true}
+{"ip":"10.0.0.6","port":{"Port":53,"Protocol":"udp","TLS":false}}
+`,
+			want: []string{"10.0.0.5 tcp/5986/ssl", "10.0.0.6 udp/53"},
+		},
+		{
+			name:   "naabu truncated line",
+			parse:  parseNaabu,
+			data:   "{\"ip\":\"10.0.0.5\",\"port\":22}\n{\"ip\":\"10.0.0.6\",\"po",
+			want:   []string{"10.0.0.5 tcp/22"},
+			broken: "naabu output line 2:",
+		},
+		{
+			name:  "zmap",
+			parse: parseZmap,
+			data:  "saddr,sport,classification,success\n10.0.0.5,22,synack,1\n10.0.0.6,80,rst,0\n10.0.0.7,161,udp,1\n",
+			want:  []string{"10.0.0.5 tcp/22", "10.0.0.7 udp/161"},
+		},
+		{
+			name:   "zmap unterminated quote",
+			parse:  parseZmap,
+			data:   "saddr,sport\n10.0.0.5,22\n\"10.0.0.6,80\n",
+			want:   []string{"10.0.0.5 tcp/22"},
+			broken: "zmap output line 3:",
+		},
+		{
+			name:   "zmap invalid address",
+			parse:  parseZmap,
+			data:   "saddr,sport\n10.0.0.5,22\nnot-an-ip,80\n",
+			want:   []string{"10.0.0.5 tcp/22"},
+			broken: "zmap output line 3: invalid address",
+		},
+		{
+			name:   "zmap without ports",
+			parse:  parseZmap,
+			data:   "saddr\n10.0.0.5\n",
+			broken: "no sport field",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			run, parseErr := test.parse("scan.out", []byte(test.data))
+			if got := discoveredPorts(run); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ports = %q, want %q", got, test.want)
+			}
+			switch {
+			case test.broken == "" && parseErr != nil:
+				t.Errorf("unexpected error: %s", parseErr.Reason)
+			case test.broken != "" && parseErr == nil:
+				t.Errorf("no error, want %q", test.broken)
+			case test.broken != "" && !strings.Contains(parseErr.Reason, test.broken):
+				t.Errorf("error %q, want %q", parseErr.Reason, test.broken)
+			case parseErr != nil && parseErr.Recovered != len(run.Host):
+				t.Errorf("%d hosts recovered, want %d", parseErr.Recovered, len(run.Host))
+			}
+		})
+	}
+}
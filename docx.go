@@ -0,0 +1,303 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// docxTableWidth is the width in twips of the text area of an A4 portrait page
+// with 2 cm margins, which tables are scaled to fill.
+const docxTableWidth = 9638
+
+// The fixed parts of a DOCX package. The styles use the built-in Word style IDs
+// so that tables and headings pick up the formatting of the document they are
+// pasted into.
+const (
+	docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>
+</Types>`
+	docxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+</Relationships>`
+	docxDocumentRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`
+	docxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:rPr><w:sz w:val="20"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/><w:basedOn w:val="Normal"/><w:rPr><w:b/><w:sz w:val="48"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="240" w:after="120"/></w:pPr><w:rPr><w:b/><w:sz w:val="32"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/><w:basedOn w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="200" w:after="100"/></w:pPr><w:rPr><w:b/><w:sz w:val="26"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Classification"><w:name w:val="Classification"/><w:basedOn w:val="Normal"/><w:pPr><w:shd w:val="clear" w:color="auto" w:fill="B71C1C"/><w:spacing w:after="0"/><w:jc w:val="center"/></w:pPr><w:rPr><w:b/><w:color w:val="FFFFFF"/></w:rPr></w:style>
+<w:style w:type="table" w:styleId="TableGrid"><w:name w:val="Table Grid"/><w:tblPr><w:tblBorders><w:top w:val="single" w:sz="4" w:color="DDDDDD"/><w:left w:val="single" w:sz="4" w:color="DDDDDD"/><w:bottom w:val="single" w:sz="4" w:color="DDDDDD"/><w:right w:val="single" w:sz="4" w:color="DDDDDD"/><w:insideH w:val="single" w:sz="4" w:color="DDDDDD"/><w:insideV w:val="single" w:sz="4" w:color="DDDDDD"/></w:tblBorders></w:tblPr></w:style>
+</w:styles>`
+	// docxBannerContentTypes and docxBannerRels add the page header and
+	// footer showing the classification of the document, see --classification.
+	docxBannerContentTypes = `<Override PartName="/word/header1.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.header+xml"/>
+<Override PartName="/word/footer1.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.footer+xml"/>
+`
+	docxBannerRels = `<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/header" Target="header1.xml"/>
+<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/footer" Target="footer1.xml"/>
+`
+)
+
+// docxWriter builds the body of word/document.xml.
+type docxWriter struct {
+	body bytes.Buffer
+	// messages translates the labels of the document, see --lang.
+	messages messages
+}
+
+// xmlEscape returns text escaped for use in XML character data.
+func xmlEscape(text string) string {
+	var buf strings.Builder
+	xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
+
+// paragraph writes a paragraph of text in style, or the normal style when
+// style is empty.
+func (d *docxWriter) paragraph(style, text string) {
+	d.body.WriteString("<w:p>")
+	if style != "" {
+		fmt.Fprintf(&d.body, `<w:pPr><w:pStyle w:val="%s"/></w:pPr>`, style)
+	}
+	fmt.Fprintf(&d.body, `<w:r><w:t xml:space="preserve">%s</w:t></w:r></w:p>`, xmlEscape(text))
+}
+
+// cell writes a table cell with a paragraph per line of text, rather than
+// line breaks, so every host is a paragraph of its own in Word.
+func (d *docxWriter) cell(width int, text string, bold bool, fill string) {
+	fmt.Fprintf(&d.body, `<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/>`, width)
+	if fill != "" {
+		fmt.Fprintf(&d.body, `<w:shd w:val="clear" w:color="auto" w:fill="%s"/>`, fill)
+	}
+	d.body.WriteString("</w:tcPr>")
+	for _, line := range strings.Split(text, "\n") {
+		d.body.WriteString("<w:p><w:pPr><w:spacing w:after=\"0\"/></w:pPr><w:r>")
+		if bold {
+			d.body.WriteString("<w:rPr><w:b/></w:rPr>")
+		}
+		fmt.Fprintf(&d.body, `<w:t xml:space="preserve">%s</w:t></w:r></w:p>`, xmlEscape(line))
+	}
+	d.body.WriteString("</w:tc>")
+}
+
+// table writes rows as a table whose header row, of the translated titles,
+// repeats on every page.
+// highlight returns the fill color of a row, or "" for none.
+func (d *docxWriter) table(titles []string, widths []int, rows [][]string, highlight func(i int) string) {
+	d.body.WriteString(`<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblW w:w="5000" w:type="pct"/></w:tblPr><w:tblGrid>`)
+	for _, width := range widths {
+		fmt.Fprintf(&d.body, `<w:gridCol w:w="%d"/>`, width)
+	}
+	d.body.WriteString(`</w:tblGrid><w:tr><w:trPr><w:tblHeader/></w:trPr>`)
+	for i, title := range titles {
+		d.cell(widths[i], d.messages.T(title), true, "F2F2F2")
+	}
+	d.body.WriteString("</w:tr>")
+	for i, row := range rows {
+		d.body.WriteString(`<w:tr><w:trPr><w:cantSplit/></w:trPr>`)
+		for j, text := range row {
+			d.cell(widths[j], text, false, highlight(i))
+		}
+		d.body.WriteString("</w:tr>")
+	}
+	d.body.WriteString("</w:tbl>")
+	// Word requires a paragraph between consecutive tables.
+	d.paragraph("", "")
+}
+
+// reportTable writes rows with the columns of the report.
+func (d *docxWriter) reportTable(columns []Column, rows []ReportRow) {
+	titles := make([]string, len(columns))
+	total := 0.0
+	for i, column := range columns {
+		titles[i] = column.Title
+		total += column.width
+	}
+	widths := make([]int, len(columns))
+	for i, column := range columns {
+		widths[i] = int(column.width / total * docxTableWidth)
+	}
+	cells := make([][]string, len(rows))
+	for i := range rows {
+		cells[i] = make([]string, len(columns))
+		for j, column := range columns {
+			cells[i][j] = column.text(&rows[i])
+		}
+	}
+	d.table(titles, widths, cells, func(i int) string { return rowFill(&rows[i]) })
+}
+
+// writeDOCX writes reports, one section per service, as a Word document to w.
+// The title is the --title of the Branding of the first report or else
+// engagement when not empty, and its classification is shown at the top and
+// bottom of every page.
+func writeDOCX(w io.Writer, engagement string, reports []ReportData) error {
+	d := &docxWriter{}
+	var branding Branding
+	if len(reports) > 0 {
+		d.messages = reports[0].messages
+		branding = reports[0].Branding
+	}
+	title := engagement
+	if branding.Title != "" {
+		title = branding.Title
+	} else {
+		engagement = ""
+	}
+	if title == "" {
+		title = d.messages.T("Nmap Service Report")
+	}
+	d.paragraph("Title", title)
+	if branding.Client != "" {
+		d.paragraph("", d.messages.T("Prepared for %s", branding.Client))
+	}
+	if engagement != "" {
+		d.paragraph("", engagement)
+	}
+	if len(reports) > 0 {
+		summary := reports[0].Summary
+		d.paragraph("", d.messages.T("Hosts scanned: %d, hosts up: %d, scan date range: %s",
+			summary.HostsScanned, summary.HostsUp, d.messages.T(summary.DateRange())))
+		if exposures := reports[0].OT; len(exposures) > 0 {
+			d.paragraph("Heading1", d.messages.T("Industrial (OT) protocols"))
+			d.paragraph("", d.messages.T(otWarning))
+			rows := make([][]string, len(exposures))
+			for i, exposure := range exposures {
+				rows[i] = []string{exposure.Host.String(), exposure.Protocol, exposure.Service, exposure.Label()}
+			}
+			d.table([]string{"Host", "Protocol", "Service", "Product"}, []int{2400, 1800, 1800, 3638}, rows, func(int) string { return otFill })
+		}
+		if wins := reports[0].QuickWins; len(wins) > 0 {
+			d.paragraph("Heading1", d.messages.T("Quick Wins"))
+			rows := make([][]string, len(wins))
+			for i, win := range wins {
+				rows[i] = []string{win.Host.String(), win.Service, win.Finding, win.Status()}
+			}
+			d.table([]string{"Host", "Service", "Finding", "Status"}, []int{2400, 1400, 4638, 1200}, rows, func(int) string { return "" })
+		}
+		if top := reports[0].Top; len(top) > 0 {
+			d.paragraph("Heading1", d.messages.T("Top %d service versions", len(top)))
+			rows := make([][]string, len(top))
+			for i, count := range top {
+				rows[i] = []string{fmt.Sprint(count.Rank), count.Service, count.Label(), fmt.Sprint(count.Hosts), fmt.Sprint(count.Ports)}
+			}
+			d.table([]string{"Rank", "Service", "Version", "Hosts", "Ports"}, []int{800, 2000, 4838, 1000, 1000}, rows, func(int) string { return "" })
+		}
+	}
+
+	for i := range reports {
+		tableData := &reports[i]
+		d.paragraph("Heading1", tableData.Service)
+		d.paragraph("", d.messages.T("%d matching ports, %d distinct versions",
+			tableData.Summary.MatchingPorts, tableData.Summary.DistinctVersions))
+		columns := sizedColumns(tableData, 170)
+		if len(tableData.Rows) == 0 {
+			d.paragraph("", d.messages.T("No matching ports."))
+		} else {
+			d.reportTable(columns, tableData.Rows)
+		}
+		if len(tableData.Unverified) > 0 {
+			d.paragraph("Heading2", d.messages.T("Unverified detections (confidence below %d)", tableData.MinConf))
+			d.reportTable(columns, tableData.Unverified)
+		}
+	}
+
+	if len(reports) > 0 && len(reports[0].DownHosts) > 0 {
+		d.paragraph("Heading1", d.messages.T("Down Hosts"))
+		rows := make([][]string, len(reports[0].DownHosts))
+		for i, host := range reports[0].DownHosts {
+			rows[i] = []string{host.Addr, host.Hostname, host.Reason}
+		}
+		d.table([]string{"Host", "Hostname", "Reason"}, []int{2500, 4638, 2500}, rows, func(int) string { return "" })
+	}
+	if len(reports) > 0 && len(reports[0].Scans) > 0 {
+		d.paragraph("Heading1", d.messages.T("Scans"))
+		rows := make([][]string, len(reports[0].Scans))
+		for i, scan := range reports[0].Scans {
+			rows[i] = []string{scan.File, scan.Args, formatDate(scan.Start, scanTimeLayout), formatDate(scan.End, scanTimeLayout), scan.Duration(),
+				fmt.Sprint(scan.HostsUp), fmt.Sprint(scan.HostsDown)}
+		}
+		d.table([]string{"File", "Command", "Start", "Finish", "Duration", "Up", "Down"}, []int{2000, 2838, 1400, 1400, 900, 550, 550}, rows, func(int) string { return "" })
+	}
+	if len(reports) > 0 && len(reports[0].Errors) > 0 {
+		d.paragraph("Heading1", d.messages.T("Files that could not be parsed"))
+		var rows [][]string
+		for _, parseErr := range reports[0].Errors {
+			reason := parseErr.Reason
+			if parseErr.Recovered > 0 {
+				reason += " (" + d.messages.T("%d complete host(s) recovered", parseErr.Recovered) + ")"
+			}
+			rows = append(rows, []string{parseErr.File, fmt.Sprint(parseErr.Offset), reason})
+		}
+		d.table([]string{"File", "Byte Offset", "Reason"}, []int{4300, 1000, 4338}, rows, func(int) string { return "" })
+	}
+
+	contentTypes, documentRels, references := docxContentTypes, docxDocumentRels, ""
+	var banners []struct{ name, content string }
+	if branding.Classification != "" {
+		contentTypes = strings.Replace(contentTypes, "</Types>", docxBannerContentTypes+"</Types>", 1)
+		documentRels = strings.Replace(documentRels, "</Relationships>", docxBannerRels+"</Relationships>", 1)
+		references = `<w:headerReference w:type="default" r:id="rId2"/><w:footerReference w:type="default" r:id="rId3"/>`
+		banner := &docxWriter{}
+		banner.paragraph("Classification", branding.Classification)
+		for _, part := range []struct{ name, element string }{{"word/header1.xml", "hdr"}, {"word/footer1.xml", "ftr"}} {
+			banners = append(banners, struct{ name, content string }{part.name, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:` + part.element + ` xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` + banner.body.String() + `</w:` + part.element + `>`})
+		}
+	}
+
+	zw := zip.NewWriter(w)
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", docxRels},
+		{"word/_rels/document.xml.rels", documentRels},
+		{"word/styles.xml", docxStyles},
+		{"word/document.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><w:body>` +
+			d.body.String() +
+			`<w:sectPr>` + references + `<w:pgSz w:w="11906" w:h="16838"/><w:pgMar w:top="1134" w:right="1134" w:bottom="1134" w:left="1134" w:header="708" w:footer="708" w:gutter="0"/></w:sectPr></w:body></w:document>`},
+	}
+	parts = append(parts, banners...)
+	for _, part := range parts {
+		pw, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(pw, part.content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// docxRenderer writes Word documents whose tables paste cleanly into report
+// templates.
+type docxRenderer struct{}
+
+func (docxRenderer) Render(data ReportData, w io.Writer) error {
+	return writeDOCX(w, data.Engagement, []ReportData{data})
+}
+
+func (docxRenderer) Extension() string { return "docx" }
+
+// RenderAll writes reports, one section per service, as a Word document to w.
+func (docxRenderer) RenderAll(reports []ReportData, w io.Writer) error {
+	var engagement string
+	if len(reports) > 0 {
+		engagement = reports[0].Engagement
+	}
+	return writeDOCX(w, engagement, reports)
+}
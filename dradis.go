@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// dradisTemplate is a Dradis project template (version 3), importable with
+// the Upload Manager's "Dradis::Plugins::Projects::Upload::Template".
+type dradisTemplate struct {
+	XMLName xml.Name      `xml:"dradis-template"`
+	Version int           `xml:"version,attr"`
+	Nodes   []dradisNode  `xml:"nodes>node"`
+	Issues  []dradisIssue `xml:"issues>issue"`
+}
+
+// dradisNode is a host of a Dradis project, with the evidence of every issue
+// found on it.
+type dradisNode struct {
+	ID         int              `xml:"id"`
+	Label      string           `xml:"label"`
+	ParentID   string           `xml:"parent-id"`
+	Position   int              `xml:"position"`
+	Properties dradisText       `xml:"properties"`
+	TypeID     int              `xml:"type-id"`
+	Notes      []struct{}       `xml:"notes>note"`
+	Evidence   []dradisEvidence `xml:"evidence>evidence"`
+}
+
+// dradisIssue is a service version of the report.
+type dradisIssue struct {
+	ID     int        `xml:"id"`
+	Author string     `xml:"author"`
+	Text   dradisText `xml:"text"`
+}
+
+// dradisEvidence links an issue to a host port of a node.
+type dradisEvidence struct {
+	ID      int        `xml:"id"`
+	Author  string     `xml:"author"`
+	IssueID int        `xml:"issue-id"`
+	Content dradisText `xml:"content"`
+}
+
+// dradisText is a text element written as CDATA, keeping the line breaks of
+// the #[Field]# blocks readable.
+type dradisText struct {
+	Text string `xml:",cdata"`
+}
+
+// dradisHostType is the Dradis node type of hosts.
+const dradisHostType = 1
+
+// dradisService is a port in the properties of a Dradis host node.
+type dradisService struct {
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	State    string `json:"state"`
+	Name     string `json:"name"`
+	Product  string `json:"product"`
+	Version  string `json:"version"`
+}
+
+// writeDradis writes tableData to w as a Dradis project template: an issue
+// per row, so each service version is reported once, and a host node per
+// address with the evidence of every port running it.
+func writeDradis(tableData ReportData, w io.Writer) error {
+	doc := dradisTemplate{Version: 3}
+	type nodeHost struct {
+		node       dradisNode
+		hostname   string
+		services   []dradisService
+		properties map[string]any
+	}
+	index := make(map[string]int)
+	var hosts []*nodeHost
+	evidenceID := 0
+	for _, rows := range [][]ReportRow{tableData.Rows, tableData.Unverified} {
+		for i := range rows {
+			row := &rows[i]
+			issue := dradisIssue{ID: len(doc.Issues) + 1, Author: "nmapTables", Text: dradisText{dradisIssueText(row)}}
+			doc.Issues = append(doc.Issues, issue)
+			for _, hostPort := range row.Hosts {
+				pos, ok := index[hostPort.Addr]
+				if !ok {
+					pos = len(hosts)
+					index[hostPort.Addr] = pos
+					hosts = append(hosts, &nodeHost{node: dradisNode{Label: hostPort.Addr, TypeID: dradisHostType}, hostname: hostPort.Hostname})
+				}
+				host := hosts[pos]
+				host.services = append(host.services, dradisService{
+					Port: atoi(hostPort.Port), Protocol: row.Protocol, State: "open",
+					Name: row.Service, Product: row.Product, Version: row.ProductVersion,
+				})
+				evidenceID++
+				host.node.Evidence = append(host.node.Evidence, dradisEvidence{
+					ID:      evidenceID,
+					Author:  "nmapTables",
+					IssueID: issue.ID,
+					Content: dradisText{fmt.Sprintf("#[Port]#\n%s/%s\n\n#[Host]#\n%s\n", hostPort.Port, row.Protocol, hostPort)},
+				})
+			}
+		}
+	}
+	sort.SliceStable(hosts, func(i, j int) bool { return lessAddr(hosts[i].node.Label, hosts[j].node.Label) })
+	for i, host := range hosts {
+		properties := map[string]any{"ip": host.node.Label, "services": host.services}
+		if host.hostname != "" {
+			properties["hostnames"] = []string{host.hostname}
+		}
+		data, err := json.Marshal(properties)
+		if err != nil {
+			return err
+		}
+		host.node.ID = i + 1
+		host.node.Position = i
+		host.node.Properties = dradisText{string(data)}
+		doc.Nodes = append(doc.Nodes, host.node)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// dradisIssueText returns the Dradis fields (#[Field]# blocks) of the issue of
+// row.
+func dradisIssueText(row *ReportRow) string {
+	label := strings.TrimSpace(row.Version)
+	if label == "" {
+		label = "unknown version"
+	}
+	fields := [][2]string{
+		{"Title", fmt.Sprintf("%s exposed: %s", row.Service, label)},
+		{"Service", row.Service},
+		{"Product", row.Product},
+		{"Version", row.ProductVersion},
+		{"Protocol", row.Protocol},
+		{"Hosts", fmt.Sprint(row.HostCount())},
+		{"Description", fmt.Sprintf("Nmap detected %s on %s. %s", label, plural(len(row.Hosts), "host port"), rowAnnotations(row))},
+	}
+	if row.Risk != "" {
+		fields = append(fields, [2]string{"Risk", row.Risk})
+	}
+	var text strings.Builder
+	for _, field := range fields {
+		fmt.Fprintf(&text, "#[%s]#\n%s\n\n", field[0], strings.TrimSpace(field[1]))
+	}
+	return strings.TrimRight(text.String(), "\n") + "\n"
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed eol.yaml
+var embeddedEOLData []byte
+
+// EOLEntry marks the product versions matched by its RowMatcher as end-of-life
+// from the EOL date onwards.
+type EOLEntry struct {
+	Name       string `yaml:"name"`
+	RowMatcher `yaml:",inline"`
+	EOL        string `yaml:"eol"`
+
+	date time.Time
+}
+
+// eolFile is the layout of a YAML end-of-life dataset.
+type eolFile struct {
+	Products []EOLEntry `yaml:"products"`
+}
+
+// LoadEOLData returns the end-of-life dataset: the entries of the file at path,
+// if any, followed by the embedded dataset, so user entries take precedence.
+func LoadEOLData(path string) ([]EOLEntry, error) {
+	var entries []EOLEntry
+	if path != "" {
+		var file eolFile
+		if err := readYAMLFile(path, &file); err != nil {
+			return nil, err
+		}
+		if err := compileEOLEntries(file.Products); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		entries = append(entries, file.Products...)
+	}
+
+	var embedded eolFile
+	if err := yaml.Unmarshal(embeddedEOLData, &embedded); err != nil {
+		return nil, fmt.Errorf("parsing embedded EOL data: %w", err)
+	}
+	if err := compileEOLEntries(embedded.Products); err != nil {
+		return nil, fmt.Errorf("embedded EOL data: %w", err)
+	}
+	return append(entries, embedded.Products...), nil
+}
+
+// compileEOLEntries prepares entries for matching.
+func compileEOLEntries(entries []EOLEntry) error {
+	for i := range entries {
+		entry := &entries[i]
+		date, err := time.Parse(time.DateOnly, entry.EOL)
+		if err != nil {
+			return fmt.Errorf("entry %d (%s): invalid eol date %q", i+1, entry.Name, entry.EOL)
+		}
+		entry.date = date
+		if err := entry.compile(); err != nil {
+			return fmt.Errorf("entry %d (%s): %w", i+1, entry.Name, err)
+		}
+	}
+	return nil
+}
+
+// applyEOL marks every row matched by an entry whose EOL date is not after now.
+// The first matching entry wins, even if its date is still in the future. Rows
+// running several versions are marked by their oldest end-of-life version.
+func applyEOL(rows []ReportRow, entries []EOLEntry, now time.Time) {
+	for i := range rows {
+	variants:
+		for _, variant := range rows[i].variants() {
+			for j := range entries {
+				if !entries[j].Matches(&variant) {
+					continue
+				}
+				if !entries[j].date.After(now) {
+					rows[i].EOL = entries[j].EOL
+					rows[i].EOLName = entries[j].Name
+					break variants
+				}
+				break
+			}
+		}
+	}
+}
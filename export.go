@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// runExport implements the export command, writing the table for a service in
+// any output format to stdout or to the -o file.
+func runExport(f *exportFlags, args []string) error {
+	renderer, err := lookupRenderer(f.format)
+	if err != nil {
+		return err
+	}
+	var hec *splunkClient
+	if f.format == "splunk" {
+		renderer = splunkRenderer{index: f.hecIndex, sourcetype: f.hecSourcetype}
+		if f.hecURL != "" {
+			token := f.hecToken
+			if token == "" {
+				token = os.Getenv("SPLUNK_HEC_TOKEN")
+			}
+			if hec, err = newSplunkClient(f.hecURL, token, f.hecInsecure); err != nil {
+				return fmt.Errorf("Splunk HEC: %w", err)
+			}
+		}
+	} else if f.hecURL != "" {
+		return errors.New("--hec-url requires --format splunk")
+	}
+	nmapFiles, err := f.input.nmapFiles(args)
+	if err != nil {
+		return err
+	}
+	opts, err := f.table.options()
+	if err != nil {
+		return err
+	}
+	if (f.format == "nuclei-targets" || f.format == "urls") && f.allServices {
+		opts.ServiceName = "web"
+		opts.Match = isWebPort
+	}
+	tableData := GenerateTableData(nmapFiles, opts)
+	if err := checkParseErrors(tableData.Errors); err != nil {
+		return err
+	}
+	tableData = opts.Redact.report(tableData)
+
+	if hec != nil {
+		events := renderer.(splunkRenderer).events(tableData)
+		if err := hec.send(events); err != nil {
+			return err
+		}
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "%d events sent to %s\n", len(events), hec.endpoint)
+		}
+		return checkMatches(tableData)
+	}
+	if f.output.path == "" && f.output.dir == "" {
+		if err := renderer.Render(tableData, os.Stdout); err != nil {
+			return err
+		}
+		return checkMatches(tableData)
+	}
+	defaultName := fmt.Sprintf("%s.txt", opts.ServiceName)
+	if document, ok := renderer.(documentRenderer); ok {
+		defaultName = fmt.Sprintf("%s.%s", opts.ServiceName, document.Extension())
+	}
+	outputFilename, err := f.output.filename(defaultName)
+	if err != nil {
+		return fmt.Errorf("invalid output path: %w", err)
+	}
+	outputFile, err := createOutputFile(outputFilename, f.output.force)
+	if err != nil {
+		return err
+	}
+	defer outputFile.Close()
+
+	if err := renderer.Render(tableData, outputFile); err != nil {
+		return err
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "%s export written to %s\n", f.format, outputFilename)
+	}
+	return checkMatches(tableData)
+}
+
+// writeHostPorts writes every distinct host:port of tableData to w, one per line,
+// so results can be piped into other tools.
+func writeHostPorts(tableData ReportData, w io.Writer) error {
+	seen := make(map[string]struct{})
+	var lines []string
+	for _, row := range tableData.Rows {
+		for _, host := range row.Hosts {
+			line := host.String()
+			if _, ok := seen[line]; ok {
+				continue
+			}
+			seen[line] = struct{}{}
+			lines = append(lines, line)
+		}
+	}
+	sort.Strings(lines)
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCSV writes tableData to w as CSV with one record per host port. Rows
+// below the confidence threshold are included with verified set to false, and
+// the script details of the service layout follow the other fields.
+// When --columns were chosen the records have exactly those columns.
+func writeCSV(tableData ReportData, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if len(tableData.Columns) > 0 {
+		header := make([]string, len(tableData.Columns))
+		for i, column := range tableData.Columns {
+			header[i] = column.Name
+		}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		for _, record := range columnRecords(tableData) {
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	header := []string{"host", "port", "protocol", "service", "product", "version", "verified"}
+	if tableData.ShowHostname {
+		header = append(header, "hostname")
+	}
+	if tableData.ShowExtraInfo {
+		header = append(header, "extrainfo")
+	}
+	if tableData.ShowIdent {
+		header = append(header, "ident")
+	}
+	if tableData.ShowReason {
+		header = append(header, "reason", "reason_ttl")
+	}
+	if tableData.ShowOS {
+		header = append(header, "os")
+	}
+	if tableData.ShowMAC {
+		header = append(header, "mac", "vendor")
+	}
+	if tableData.ShowSource {
+		header = append(header, "source")
+	}
+	if tableData.ShowSeen {
+		header = append(header, "seen")
+	}
+	if tableData.ShowGeo {
+		header = append(header, "country", "asn")
+	}
+	if tableData.ShowAsset {
+		header = append(header, "owner", "environment", "criticality")
+	}
+	if tableData.ShowRisk {
+		header = append(header, "risk")
+	}
+	if tableData.ShowEOL {
+		header = append(header, "eol")
+	}
+	details := tableData.layoutDetails()
+	header = append(header, details...)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	writeRows := func(rows []ReportRow, verified bool) error {
+		for _, row := range rows {
+			for _, host := range row.Hosts {
+				record := []string{host.Addr, host.Port, row.Protocol, row.Service, row.Product, row.ProductVersion, strconv.FormatBool(verified)}
+				if tableData.ShowHostname {
+					record = append(record, host.Hostname)
+				}
+				if tableData.ShowExtraInfo {
+					record = append(record, host.ExtraInfo)
+				}
+				if tableData.ShowIdent {
+					record = append(record, host.Ident)
+				}
+				if tableData.ShowReason {
+					record = append(record, host.Reason, host.ReasonTTL)
+				}
+				if tableData.ShowOS {
+					record = append(record, host.OS)
+				}
+				if tableData.ShowMAC {
+					record = append(record, host.MAC, host.Vendor)
+				}
+				if tableData.ShowSource {
+					record = append(record, host.Source)
+				}
+				if tableData.ShowSeen {
+					record = append(record, formatSeen(host.Seen))
+				}
+				if tableData.ShowGeo {
+					record = append(record, host.Country, host.ASN)
+				}
+				if tableData.ShowAsset {
+					record = append(record, host.Owner, host.Environment, host.Criticality)
+				}
+				if tableData.ShowRisk {
+					record = append(record, row.Risk)
+				}
+				if tableData.ShowEOL {
+					record = append(record, row.EOL)
+				}
+				for _, name := range details {
+					record = append(record, host.Details[name])
+				}
+				if err := cw.Write(record); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := writeRows(tableData.Rows, true); err != nil {
+		return err
+	}
+	if err := writeRows(tableData.Unverified, false); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeJSON writes tableData to w as indented JSON. When --columns were chosen
+// it is instead an array with an object of those columns per host port.
+func writeJSON(tableData ReportData, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if len(tableData.Columns) > 0 {
+		objects := []map[string]string{}
+		for _, record := range columnRecords(tableData) {
+			object := make(map[string]string, len(record))
+			for i, column := range tableData.Columns {
+				object[column.Name] = record[i]
+			}
+			objects = append(objects, object)
+		}
+		return encoder.Encode(objects)
+	}
+	return encoder.Encode(tableData)
+}
+
+// columnRecords returns the values of the --columns of tableData for every
+// host of its verified and unverified rows, or once per row for RowRecords
+// tables whose columns all describe the row as a whole.
+func columnRecords(tableData ReportData) [][]string {
+	perRow := tableData.RowRecords
+	for _, column := range tableData.Columns {
+		perRow = perRow && column.host == nil
+	}
+	var records [][]string
+	for _, rows := range [][]ReportRow{tableData.Rows, tableData.Unverified} {
+		for i := range rows {
+			if perRow {
+				record := make([]string, len(tableData.Columns))
+				for k, column := range tableData.Columns {
+					record[k] = column.row(&rows[i])
+				}
+				records = append(records, record)
+				continue
+			}
+			for j := range rows[i].Hosts {
+				record := make([]string, len(tableData.Columns))
+				for k, column := range tableData.Columns {
+					record[k] = column.Value(&rows[i], &rows[i].Hosts[j])
+				}
+				records = append(records, record)
+			}
+		}
+	}
+	return records
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// faradayReport is a Faraday bulk create document: the hosts of a report with
+// their services and the command that produced it.
+type faradayReport struct {
+	Hosts   []faradayHost  `json:"hosts"`
+	Command faradayCommand `json:"command"`
+}
+
+// faradayHost is a host of a Faraday bulk create document.
+type faradayHost struct {
+	IP              string           `json:"ip"`
+	Hostnames       []string         `json:"hostnames"`
+	OS              string           `json:"os,omitempty"`
+	MAC             string           `json:"mac,omitempty"`
+	Description     string           `json:"description"`
+	Services        []faradayService `json:"services"`
+	Vulnerabilities []any            `json:"vulnerabilities"`
+}
+
+// faradayService is a host port of a Faraday host.
+type faradayService struct {
+	Name            string `json:"name"`
+	Port            int    `json:"port"`
+	Protocol        string `json:"protocol"`
+	Status          string `json:"status"`
+	Version         string `json:"version"`
+	Description     string `json:"description"`
+	Vulnerabilities []any  `json:"vulnerabilities"`
+}
+
+// faradayCommand describes the tool run that created the hosts.
+type faradayCommand struct {
+	Tool      string `json:"tool"`
+	Command   string `json:"command"`
+	Params    string `json:"params"`
+	User      string `json:"user"`
+	Hostname  string `json:"hostname"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date,omitempty"`
+	// ImportSource is "report" for imported tool output.
+	ImportSource string `json:"import_source"`
+}
+
+// writeFaraday writes the hosts of tableData to w as a Faraday bulk create
+// JSON document (POST /_api/v3/ws/<workspace>/bulk_create), one host per
+// address with a service per port, annotated with the risk and end-of-life
+// findings of its row.
+func writeFaraday(tableData ReportData, w io.Writer) error {
+	index := make(map[string]int)
+	var hosts []faradayHost
+	for _, rows := range [][]ReportRow{tableData.Rows, tableData.Unverified} {
+		for _, row := range rows {
+			for _, hostPort := range row.Hosts {
+				pos, ok := index[hostPort.Addr]
+				if !ok {
+					pos = len(hosts)
+					index[hostPort.Addr] = pos
+					host := faradayHost{IP: hostPort.Addr, Hostnames: []string{}, OS: hostPort.OS, MAC: hostPort.MAC, Vulnerabilities: []any{}}
+					if hostPort.Hostname != "" {
+						host.Hostnames = append(host.Hostnames, hostPort.Hostname)
+					}
+					hosts = append(hosts, host)
+				}
+				hosts[pos].Services = append(hosts[pos].Services, faradayService{
+					Name:            row.Service,
+					Port:            atoi(hostPort.Port),
+					Protocol:        row.Protocol,
+					Status:          "open",
+					Version:         strings.TrimSpace(row.Version),
+					Description:     rowAnnotations(&row),
+					Vulnerabilities: []any{},
+				})
+			}
+		}
+	}
+	sort.Slice(hosts, func(i, j int) bool { return lessAddr(hosts[i].IP, hosts[j].IP) })
+	if hosts == nil {
+		hosts = []faradayHost{}
+	}
+
+	start := tableData.Summary.ScanStart
+	if start.IsZero() {
+		start = time.Now()
+	}
+	report := faradayReport{
+		Hosts: hosts,
+		Command: faradayCommand{
+			Tool:         "nmapTables",
+			Command:      "nmapTables",
+			Params:       "--service " + tableData.Service,
+			StartDate:    start.UTC().Format(time.RFC3339),
+			ImportSource: "report",
+		},
+	}
+	if end := tableData.Summary.ScanEnd; !end.IsZero() {
+		report.Command.EndDate = end.UTC().Format(time.RFC3339)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// rowAnnotations describes the risk, end-of-life and confidence findings of
+// row in a sentence each, for the descriptions of platform exports.
+func rowAnnotations(row *ReportRow) string {
+	var notes []string
+	if row.Risk != "" {
+		notes = append(notes, "Risk: "+row.Risk+".")
+	}
+	if row.EOL != "" {
+		notes = append(notes, "End of life: "+row.EOL+".")
+	}
+	if !row.Verified {
+		notes = append(notes, "Unverified detection.")
+	}
+	return strings.Join(notes, " ")
+}
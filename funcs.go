@@ -0,0 +1,106 @@
+package main
+
+import (
+	"html/template"
+	"net/netip"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/mr-pmillz/nmapTables/nmap"
+)
+
+// templateFuncs are the helper functions available to every template,
+// including custom --template files.
+var templateFuncs = template.FuncMap{
+	"safe": func(s string) template.HTML {
+		return template.HTML(s)
+	},
+	"riskClass":      riskClass,
+	"script":         script,
+	"stylesheet":     stylesheet,
+	"joinHosts":      joinHosts,
+	"count":          count,
+	"severityColor":  severityColor,
+	"compareVersion": nmap.CompareVersions,
+	"versionAtLeast": versionAtLeast,
+	"inCIDR":         inCIDR,
+	"formatDate":     formatDate,
+	"anchor":         anchor,
+	"barChart":       barChart,
+	"pieChart":       pieChart,
+	"histogram":      histogram,
+}
+
+// joinHosts joins the addr:port of hosts with sep, e.g.
+// {{joinHosts .Hosts ", "}}.
+func joinHosts(hosts []HostPort, sep string) string {
+	parts := make([]string, len(hosts))
+	for i, host := range hosts {
+		parts[i] = host.String()
+	}
+	return strings.Join(parts, sep)
+}
+
+// count returns the number of elements of a slice, map or string, or 0 for
+// anything else, so {{count .Rows}} also works on nil values.
+func count(v any) int {
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.String:
+		return value.Len()
+	}
+	return 0
+}
+
+// anchor returns the id of the section of a multi-service report about
+// service, e.g. {{anchor .Service}} is service-ms-sql-s for ms-sql-s.
+func anchor(service string) string {
+	return "service-" + screenshotKey(service)
+}
+
+// severityColors are the background colors of the risk labels, matching the
+// risk-* classes of the built-in templates.
+var severityColors = map[string]string{
+	"critical": "#7b1fa2",
+	"high":     "#d32f2f",
+	"medium":   "#f57c00",
+	"low":      "#fbc02d",
+	"info":     "#1976d2",
+}
+
+// severityColor returns the color of a risk label such as "High" for inline
+// styles, or "" for a label without one.
+func severityColor(risk string) template.CSS {
+	return template.CSS(severityColors[strings.ToLower(strings.TrimSpace(risk))])
+}
+
+// versionAtLeast reports whether version is min or later, compared
+// numerically.
+func versionAtLeast(version, min string) bool {
+	return nmap.CompareVersions(version, min) >= 0
+}
+
+// inCIDR reports whether addr is in the network cidr, e.g.
+// {{if inCIDR .Addr "10.0.0.0/8"}}.
+func inCIDR(addr, cidr string) (bool, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return false, err
+	}
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false, nil
+	}
+	return prefix.Contains(ip.Unmap()), nil
+}
+
+// formatDate formats t with a Go time layout, e.g.
+// {{formatDate .Summary.ScanStart "2006-01-02"}}, or returns "unknown" for
+// the zero time.
+func formatDate(t time.Time, layout string) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.Format(layout)
+}
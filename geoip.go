@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"sync"
+)
+
+// geoDB annotates hosts with the country and autonomous system found for
+// them in MaxMind DB files, such as GeoLite2 Country or City and GeoLite2 ASN.
+type geoDB struct {
+	readers []*mmdbReader
+
+	mu    sync.Mutex
+	cache map[string]geoInfo
+}
+
+// geoInfo is the country code and autonomous system of an address.
+type geoInfo struct {
+	country string
+	asn     string
+}
+
+// openGeoDB opens the MaxMind DB files at paths, or returns nil when there
+// are none.
+func openGeoDB(paths []string) (*geoDB, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	g := &geoDB{cache: make(map[string]geoInfo)}
+	for _, path := range paths {
+		path, err := resolveAbsPath(path)
+		if err != nil {
+			return nil, err
+		}
+		reader, err := openMMDB(path)
+		if err != nil {
+			return nil, err
+		}
+		g.readers = append(g.readers, reader)
+	}
+	return g, nil
+}
+
+// lookup returns the country and autonomous system of addr, e.g. "DE" and
+// "AS3320 Deutsche Telekom AG", from the first database that has each.
+func (g *geoDB) lookup(addr string) geoInfo {
+	if g == nil {
+		return geoInfo{}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if info, ok := g.cache[addr]; ok {
+		return info
+	}
+	var info geoInfo
+	ip, err := netip.ParseAddr(addr)
+	if err == nil {
+		for _, reader := range g.readers {
+			record, err := reader.lookup(ip)
+			if err != nil {
+				logger.Debug("GeoIP lookup failed", "addr", addr, "err", err)
+				continue
+			}
+			if info.country == "" {
+				info.country = recordCountry(record)
+			}
+			if number, ok := record["autonomous_system_number"].(uint64); ok && info.asn == "" {
+				info.asn = fmt.Sprintf("AS%d", number)
+				if org, ok := record["autonomous_system_organization"].(string); ok && org != "" {
+					info.asn += " " + org
+				}
+			}
+		}
+	}
+	g.cache[addr] = info
+	return info
+}
+
+// recordCountry returns the ISO code of the country of a GeoIP record, or of
+// the country it is registered in for records without one.
+func recordCountry(record map[string]any) string {
+	for _, key := range []string{"country", "registered_country"} {
+		if country, ok := record[key].(map[string]any); ok {
+			if code, ok := country["iso_code"].(string); ok {
+				return code
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// exposureGraph is the network exposure of the scans as a graph: hosts in
+// subnets exposing services.
+type exposureGraph struct {
+	Hosts    []graphHost
+	Subnets  []string
+	Services []graphService
+}
+
+// graphHost is a Host node, with its EXPOSES relationships.
+type graphHost struct {
+	Addr     string
+	Hostname string
+	OS       string
+	Subnet   string
+	// DC marks likely domain controllers, see likelyDomainController.
+	DC    bool
+	Ports []graphPort
+	// Hops are the routers of the traceroute to the host, nearest first;
+	// Traced is set when the host had a traceroute.
+	Hops   []graphHop
+	Traced bool
+}
+
+// graphHop is a router of a traceroute.
+type graphHop struct {
+	Addr string
+	Name string
+}
+
+// graphPort is an EXPOSES relationship from a host to a service.
+type graphPort struct {
+	Port     int
+	Protocol string
+	Service  string
+}
+
+// graphService is a Service node: a distinct service version.
+type graphService struct {
+	Key     string
+	Name    string
+	Product string
+	Version string
+}
+
+// BuildGraph returns the exposure graph of the ports of runs matching opts, an
+// empty opts.ServiceName matching every service. Hosts are grouped into /bits4
+// IPv4 and /bits6 IPv6 subnets.
+func BuildGraph(runs []Nmaprun, opts TableOptions, bits4, bits6 int) exposureGraph {
+	var g exposureGraph
+	subnets := make(map[string]struct{})
+	services := make(map[string]struct{})
+	hosts := opts.mergeHosts(runs)
+	for i := range hosts {
+		host := &hosts[i]
+		addr := hostAddress(host, opts.PreferIPv6)
+		if opts.excluded(addr) {
+			continue
+		}
+		node := graphHost{Addr: addr, Subnet: hostSubnet(addr, bits4, bits6)}
+		if len(host.Hostnames.Hostname) > 0 {
+			node.Hostname = host.Hostnames.Hostname[0].Name
+		}
+		if best, _ := bestOSMatch(host); best != nil {
+			node.OS = best.Name
+		}
+		open := make(map[string]bool)
+		for _, port := range host.Ports.Port {
+			if !opts.States[port.State.State] {
+				continue
+			}
+			open[port.Portid] = true
+			if opts.ServiceName != "" && port.Service.Name != opts.ServiceName {
+				continue
+			}
+			service := graphService{Name: port.Service.Name, Product: port.Service.Product, Version: port.Service.Version}
+			service.Key = strings.Join([]string{service.Name, service.Product, service.Version}, "|")
+			if _, ok := services[service.Key]; !ok {
+				services[service.Key] = struct{}{}
+				g.Services = append(g.Services, service)
+			}
+			node.Ports = append(node.Ports, graphPort{Port: atoi(port.Portid), Protocol: port.Protocol, Service: service.Key})
+		}
+		if len(node.Ports) == 0 {
+			continue
+		}
+		node.DC = likelyDomainController(open)
+		node.Hops, node.Traced = traceHops(host)
+		if node.Subnet != "" {
+			if _, ok := subnets[node.Subnet]; !ok {
+				subnets[node.Subnet] = struct{}{}
+				g.Subnets = append(g.Subnets, node.Subnet)
+			}
+		}
+		g.Hosts = append(g.Hosts, node)
+	}
+	sort.Slice(g.Hosts, func(i, j int) bool { return lessAddr(g.Hosts[i].Addr, g.Hosts[j].Addr) })
+	sort.Strings(g.Subnets)
+	sort.Slice(g.Services, func(i, j int) bool { return g.Services[i].Key < g.Services[j].Key })
+	return g
+}
+
+// hostSubnet returns the subnet of addr, or "" for addresses that are not IP
+// addresses.
+func hostSubnet(addr string, bits4, bits6 int) string {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return ""
+	}
+	bits := bits6
+	if ip.Is4() {
+		bits = bits4
+	}
+	prefix, err := ip.Prefix(bits)
+	if err != nil {
+		return ""
+	}
+	return prefix.String()
+}
+
+// labels returns the Neo4j labels of h.
+func (h graphHost) labels() string {
+	if h.DC {
+		return "Host:DomainController"
+	}
+	return "Host"
+}
+
+// cypherString quotes s as a Cypher string literal.
+func cypherString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// writeCypher writes g to w as a Cypher script for cypher-shell, merging the
+// nodes and relationships so it can be run again as the scans grow.
+func writeCypher(w io.Writer, g exposureGraph) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "CREATE CONSTRAINT host_addr IF NOT EXISTS FOR (h:Host) REQUIRE h.addr IS UNIQUE;")
+	fmt.Fprintln(bw, "CREATE CONSTRAINT subnet_cidr IF NOT EXISTS FOR (s:Subnet) REQUIRE s.cidr IS UNIQUE;")
+	fmt.Fprintln(bw, "CREATE CONSTRAINT service_key IF NOT EXISTS FOR (v:Service) REQUIRE v.key IS UNIQUE;")
+	for _, subnet := range g.Subnets {
+		fmt.Fprintf(bw, "MERGE (:Subnet {cidr: %s});\n", cypherString(subnet))
+	}
+	for _, service := range g.Services {
+		fmt.Fprintf(bw, "MERGE (v:Service {key: %s}) SET v.name = %s, v.product = %s, v.version = %s;\n",
+			cypherString(service.Key), cypherString(service.Name), cypherString(service.Product), cypherString(service.Version))
+	}
+	for _, host := range g.Hosts {
+		fmt.Fprintf(bw, "MERGE (h:Host {addr: %s}) SET h:%s, h.hostname = %s, h.os = %s",
+			cypherString(host.Addr), host.labels(), cypherString(host.Hostname), cypherString(host.OS))
+		if host.Subnet != "" {
+			fmt.Fprintf(bw, " WITH h MATCH (s:Subnet {cidr: %s}) MERGE (h)-[:IN_SUBNET]->(s)", cypherString(host.Subnet))
+		}
+		fmt.Fprintln(bw, ";")
+		for _, port := range host.Ports {
+			fmt.Fprintf(bw, "MATCH (h:Host {addr: %s}), (v:Service {key: %s}) MERGE (h)-[:EXPOSES {port: %d, protocol: %s}]->(v);\n",
+				cypherString(host.Addr), cypherString(port.Service), port.Port, cypherString(port.Protocol))
+		}
+	}
+	return bw.Flush()
+}
+
+// writeGraph writes g in the --format of f: a Cypher script or DOT graph to
+// stdout or the -o file, or the CSV files to the --output-dir.
+func writeGraph(g exposureGraph, f *graphFlags) error {
+	if f.format == "csv" {
+		create := func(name string) (io.WriteCloser, error) {
+			path, err := outputPath(name, f.output.dir, name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid output path: %w", err)
+			}
+			return createOutputFile(path, f.output.force)
+		}
+		if err := writeGraphCSV(g, create); err != nil {
+			return err
+		}
+		if !quiet {
+			dir := f.output.dir
+			if dir == "" {
+				dir = "."
+			}
+			fmt.Fprintf(os.Stderr, "graph CSV files written to %s: %s\n", dir, strings.Join(graphCSVFiles, ", "))
+		}
+		return nil
+	}
+	write, defaultName, kind := writeCypher, "graph.cypher", "Cypher script"
+	if f.format == "dot" {
+		write, defaultName, kind = writeDOT, "topology.dot", "DOT graph"
+	}
+	if f.output.path == "" && f.output.dir == "" {
+		return write(os.Stdout, g)
+	}
+	outputFilename, err := f.output.filename(defaultName)
+	if err != nil {
+		return fmt.Errorf("invalid output path: %w", err)
+	}
+	outputFile, err := createOutputFile(outputFilename, f.output.force)
+	if err != nil {
+		return err
+	}
+	defer outputFile.Close()
+	if err := write(outputFile, g); err != nil {
+		return err
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "%s written to %s\n", kind, outputFilename)
+	}
+	return nil
+}
+
+// graphCSVFiles are the files of writeGraphCSV, in the order of the
+// neo4j-admin import arguments that load them.
+var graphCSVFiles = []string{"hosts.csv", "subnets.csv", "services.csv", "in_subnet.csv", "exposes.csv"}
+
+// writeGraphCSV writes g as the node and relationship files of neo4j-admin
+// database import, created with create.
+func writeGraphCSV(g exposureGraph, create func(name string) (io.WriteCloser, error)) error {
+	records := map[string][][]string{
+		"hosts.csv":     {{"addr:ID(Host)", "hostname", "os", ":LABEL"}},
+		"subnets.csv":   {{"cidr:ID(Subnet)", ":LABEL"}},
+		"services.csv":  {{"key:ID(Service)", "name", "product", "version", ":LABEL"}},
+		"in_subnet.csv": {{":START_ID(Host)", ":END_ID(Subnet)", ":TYPE"}},
+		"exposes.csv":   {{":START_ID(Host)", ":END_ID(Service)", "port:int", "protocol", ":TYPE"}},
+	}
+	for _, subnet := range g.Subnets {
+		records["subnets.csv"] = append(records["subnets.csv"], []string{subnet, "Subnet"})
+	}
+	for _, service := range g.Services {
+		records["services.csv"] = append(records["services.csv"], []string{service.Key, service.Name, service.Product, service.Version, "Service"})
+	}
+	for _, host := range g.Hosts {
+		records["hosts.csv"] = append(records["hosts.csv"], []string{host.Addr, host.Hostname, host.OS, strings.ReplaceAll(host.labels(), ":", ";")})
+		if host.Subnet != "" {
+			records["in_subnet.csv"] = append(records["in_subnet.csv"], []string{host.Addr, host.Subnet, "IN_SUBNET"})
+		}
+		for _, port := range host.Ports {
+			records["exposes.csv"] = append(records["exposes.csv"], []string{host.Addr, port.Service, fmt.Sprint(port.Port), port.Protocol, "EXPOSES"})
+		}
+	}
+	for _, name := range graphCSVFiles {
+		file, err := create(name)
+		if err != nil {
+			return err
+		}
+		cw := csv.NewWriter(file)
+		cw.WriteAll(records[name])
+		if err := cw.Error(); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
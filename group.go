@@ -0,0 +1,303 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/mr-pmillz/nmapTables/nmap"
+)
+
+// groupKey identifies a row of the table. Only the fields used by the
+// --group-by strategy are set; by default ports are grouped by service version
+// and protocol so that e.g. tcp/1433 and udp/1433 never share a row. The host,
+// cpe and asn strategies group every port of a host, CPE or ASN in a row
+// whatever its protocol: their rows may mix protocols, whose host ports are
+// then told apart as addr:port/protocol.
+type groupKey struct {
+	Product   string
+	Version   string
+	ExtraInfo string
+	Protocol  string
+	// Tunnel keeps the ports of a service in an SSL tunnel apart from the
+	// cleartext ones.
+	Tunnel string
+	// Service is set for the unidentified ports of --include-unknown.
+	Service string
+	Addr    string
+	Port    string
+	CPE     string
+	ASN     string
+}
+
+// groupedPort is a host port along with the service detected on it.
+type groupedPort struct {
+	hostPort HostPort
+	protocol string
+	product  string
+	version  string
+	// unknown is "unknown" or "tcpwrapped" for the unidentified ports of
+	// --include-unknown.
+	unknown string
+}
+
+// productVersion is a distinct product and version of the ports of a row.
+type productVersion struct {
+	product string
+	version string
+}
+
+// grouping is an aggregation strategy for --group-by.
+type grouping struct {
+	// key returns the key of the row port belongs to; ports with equal keys
+	// share a row.
+	key func(port *NmapPort, hostPort *HostPort) groupKey
+	// less orders the rows; nil orders them by product and version.
+	less func(a, b *ReportRow) bool
+}
+
+// groupings are the strategies of --group-by by name.
+var groupings = map[string]grouping{
+	"version": {key: func(port *NmapPort, _ *HostPort) groupKey {
+		return groupKey{Product: port.Service.Product, Version: port.Service.Version, Protocol: port.Protocol, Tunnel: port.Service.Tunnel}
+	}},
+	"banner": {key: func(port *NmapPort, _ *HostPort) groupKey {
+		return groupKey{Product: port.Service.Product, Version: port.Service.Version, ExtraInfo: port.Service.Extrainfo, Protocol: port.Protocol, Tunnel: port.Service.Tunnel}
+	}},
+	"product": {key: func(port *NmapPort, _ *HostPort) groupKey {
+		return groupKey{Product: port.Service.Product, Protocol: port.Protocol, Tunnel: port.Service.Tunnel}
+	}},
+	"host": {
+		key: func(_ *NmapPort, hostPort *HostPort) groupKey {
+			return groupKey{Addr: hostPort.Addr}
+		},
+		less: func(a, b *ReportRow) bool {
+			return lessAddr(a.Hosts[0].Addr, b.Hosts[0].Addr)
+		},
+	},
+	"port": {
+		key: func(port *NmapPort, _ *HostPort) groupKey {
+			return groupKey{Port: port.Portid, Protocol: port.Protocol, Tunnel: port.Service.Tunnel}
+		},
+		less: func(a, b *ReportRow) bool {
+			if pa, pb := atoi(a.Hosts[0].Port), atoi(b.Hosts[0].Port); pa != pb {
+				return pa < pb
+			}
+			if a.Protocol != b.Protocol {
+				return a.Protocol < b.Protocol
+			}
+			return a.Service < b.Service
+		},
+	},
+	"cpe": {key: func(port *NmapPort, _ *HostPort) groupKey {
+		return groupKey{CPE: port.Service.Cpe}
+	}},
+	"asn": {
+		key: func(_ *NmapPort, hostPort *HostPort) groupKey {
+			return groupKey{ASN: hostPort.ASN}
+		},
+		less: func(a, b *ReportRow) bool {
+			return a.Hosts[0].ASN < b.Hosts[0].ASN
+		},
+	},
+	"none": {
+		key: func(port *NmapPort, hostPort *HostPort) groupKey {
+			return groupKey{Addr: hostPort.Addr, Port: port.Portid, Protocol: port.Protocol}
+		},
+		less: func(a, b *ReportRow) bool {
+			if a.Hosts[0].Addr != b.Hosts[0].Addr {
+				return lessAddr(a.Hosts[0].Addr, b.Hosts[0].Addr)
+			}
+			return atoi(a.Hosts[0].Port) < atoi(b.Hosts[0].Port)
+		},
+	},
+}
+
+// groupingNames returns the sorted names of the --group-by strategies.
+func groupingNames() []string {
+	names := make([]string, 0, len(groupings))
+	for name := range groupings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lessAddr orders IP addresses numerically, and anything else as text.
+func lessAddr(a, b string) bool {
+	addrA, errA := netip.ParseAddr(a)
+	addrB, errB := netip.ParseAddr(b)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	return addrA.Less(addrB)
+}
+
+// buildRows turns grouped host ports into report rows ordered by strategy.
+// Fields that differ between the ports of a row list every distinct value.
+func buildRows(groups map[groupKey][]groupedPort, strategy grouping, opts TableOptions) []ReportRow {
+	var data []ReportRow
+	for _, ports := range groups {
+		sort.Slice(ports, func(i, j int) bool {
+			a, b := ports[i].hostPort.String(), ports[j].hostPort.String()
+			if a != b {
+				return a < b
+			}
+			return ports[i].hostPort.Seen.Before(ports[j].hostPort.Seen)
+		})
+		hosts := make([]HostPort, len(ports))
+		protocols := make(map[string]struct{})
+		services := make(map[string]struct{})
+		products := make(map[string]struct{})
+		seen := make(map[productVersion]struct{})
+		var versions []productVersion
+		for _, port := range ports {
+			protocols[port.protocol] = struct{}{}
+		}
+		for i, port := range ports {
+			hosts[i] = port.hostPort
+			hosts[i].showProtocol = len(protocols) > 1
+			services[tunnelService(opts.ServiceName, port.hostPort.Tunnel)] = struct{}{}
+			products[port.product] = struct{}{}
+			pv := productVersion{product: port.product, version: port.version}
+			if _, ok := seen[pv]; !ok {
+				seen[pv] = struct{}{}
+				versions = append(versions, pv)
+			}
+		}
+		sort.Slice(versions, func(i, j int) bool {
+			if versions[i].product != versions[j].product {
+				return versions[i].product < versions[j].product
+			}
+			return nmap.CompareVersions(versions[i].version, versions[j].version) < 0
+		})
+
+		row := ReportRow{
+			Hosts:    hosts,
+			Protocol: joinKeys(protocols),
+			Service:  opts.ServiceName,
+			Product:  joinKeys(products),
+			versions: versions,
+		}
+		if opts.Match == nil {
+			// Ports in SSL tunnels show as ssl/<service>, as in nmap's output.
+			row.Service = joinKeys(services)
+		}
+		if unknown := ports[0].unknown; unknown != "" {
+			row.Service = unknown
+			row.Version = fmt.Sprintf("%s (port %s/%s)", unknown, hosts[0].Port, row.Protocol)
+		} else if len(versions) == 1 {
+			row.Version = fmt.Sprintf("%s %s", versions[0].product, versions[0].version)
+			row.ProductVersion = versions[0].version
+		} else {
+			numbers := make([]string, len(versions))
+			labels := make([]string, len(versions))
+			for i, pv := range versions {
+				numbers[i] = pv.version
+				labels[i] = strings.TrimSpace(pv.product + " " + pv.version)
+			}
+			row.ProductVersion = strings.Join(numbers, ", ")
+			row.Version = strings.Join(labels, ", ")
+			if len(products) == 1 {
+				row.Version = fmt.Sprintf("%s %s", row.Product, row.ProductVersion)
+			}
+		}
+		data = append(data, row)
+	}
+
+	if strategy.less != nil {
+		sort.Slice(data, func(i, j int) bool {
+			return strategy.less(&data[i], &data[j])
+		})
+	} else {
+		sortRows(data, opts.NewestFirst)
+	}
+	if opts.SortByCount {
+		sort.SliceStable(data, func(i, j int) bool {
+			return data[i].HostCount() > data[j].HostCount()
+		})
+	}
+	if opts.IncludeUnknown {
+		// The unidentified ports follow the service's rows, by port.
+		sort.SliceStable(data, func(i, j int) bool {
+			a, b := data[i].Service != opts.ServiceName, data[j].Service != opts.ServiceName
+			if a != b || !a {
+				return b
+			}
+			if data[i].Service != data[j].Service {
+				return data[i].Service < data[j].Service
+			}
+			return atoi(data[i].Hosts[0].Port) < atoi(data[j].Hosts[0].Port)
+		})
+	}
+	return data
+}
+
+// unknownService returns "unknown" for ports without a service name and
+// "tcpwrapped" for tcpwrapped ones, or "" for identified services.
+func unknownService(port *NmapPort) string {
+	switch port.Service.Name {
+	case "", "unknown":
+		return "unknown"
+	case "tcpwrapped":
+		return "tcpwrapped"
+	}
+	return ""
+}
+
+// filterRowHosts returns the rows of data with at least opts.MinHosts hosts,
+// or only those of a single host with opts.OnlySingletons.
+func filterRowHosts(data []ReportRow, opts TableOptions) []ReportRow {
+	if opts.MinHosts <= 1 && !opts.OnlySingletons {
+		return data
+	}
+	filtered := data[:0]
+	for i := range data {
+		hosts := data[i].HostCount()
+		if hosts < opts.MinHosts || opts.OnlySingletons && hosts != 1 {
+			continue
+		}
+		filtered = append(filtered, data[i])
+	}
+	return filtered
+}
+
+// HostCount returns the number of distinct hosts of the row; a host listening
+// on several ports of the row counts once.
+func (r *ReportRow) HostCount() int {
+	addrs := make(map[string]struct{}, len(r.Hosts))
+	for _, host := range r.Hosts {
+		addrs[host.Addr] = struct{}{}
+	}
+	return len(addrs)
+}
+
+// joinKeys returns the sorted keys of set separated by commas, leaving out the
+// empty ones, such as the product of unidentified ports.
+func joinKeys(set map[string]struct{}) string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
+
+// variants returns the rows risk rules and end-of-life data are matched
+// against: the row itself, or a row per distinct product version, oldest
+// first, when its ports run several.
+func (r *ReportRow) variants() []ReportRow {
+	if len(r.versions) <= 1 {
+		return []ReportRow{*r}
+	}
+	variants := make([]ReportRow, len(r.versions))
+	for i, pv := range r.versions {
+		variants[i] = *r
+		variants[i].Product = pv.product
+		variants[i].ProductVersion = pv.version
+	}
+	return variants
+}
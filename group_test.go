@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestBuildRowsMixedProtocols(t *testing.T) {
+	groups := map[groupKey][]groupedPort{
+		{Addr: "10.0.0.5"}: {
+			{hostPort: HostPort{Addr: "10.0.0.5", Port: "1433", Protocol: "tcp"}, protocol: "tcp", product: "Microsoft SQL Server 2019", version: "15.00.2000"},
+			{hostPort: HostPort{Addr: "10.0.0.5", Port: "1434", Protocol: "udp"}, protocol: "udp", product: "Microsoft SQL Server 2019", version: "15.00.2000"},
+		},
+		{Addr: "10.0.0.6"}: {
+			{hostPort: HostPort{Addr: "10.0.0.6", Port: "1433", Protocol: "tcp"}, protocol: "tcp", product: "Microsoft SQL Server 2019", version: "15.00.2000"},
+		},
+	}
+	rows := buildRows(groups, groupings["host"], TableOptions{ServiceName: "ms-sql-s"})
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	if got, want := rows[0].Protocol, "tcp, udp"; got != want {
+		t.Errorf("Protocol = %q, want %q", got, want)
+	}
+	if got, want := joinHosts(rows[0].Hosts, ", "), "10.0.0.5:1433/tcp, 10.0.0.5:1434/udp"; got != want {
+		t.Errorf("mixed row hosts = %q, want %q", got, want)
+	}
+	if got, want := joinHosts(rows[1].Hosts, ", "), "10.0.0.6:1433"; got != want {
+		t.Errorf("single protocol row hosts = %q, want %q", got, want)
+	}
+}
+
+func TestBuildRowsUnidentifiedProduct(t *testing.T) {
+	groups := map[groupKey][]groupedPort{
+		{Addr: "10.0.0.5"}: {
+			{hostPort: HostPort{Addr: "10.0.0.5", Port: "1433", Protocol: "tcp"}, protocol: "tcp", product: "Microsoft SQL Server 2019", version: "15.00.2000"},
+			{hostPort: HostPort{Addr: "10.0.0.5", Port: "1435", Protocol: "tcp"}, protocol: "tcp"},
+		},
+	}
+	rows := buildRows(groups, groupings["host"], TableOptions{ServiceName: "ms-sql-s"})
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	if got, want := rows[0].Product, "Microsoft SQL Server 2019"; got != want {
+		t.Errorf("Product = %q, want %q", got, want)
+	}
+}
+
+func TestJoinKeys(t *testing.T) {
+	for _, test := range []struct {
+		keys []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{""}, ""},
+		{[]string{"udp", "tcp"}, "tcp, udp"},
+		{[]string{"", "Microsoft SQL Server 2019"}, "Microsoft SQL Server 2019"},
+	} {
+		set := make(map[string]struct{})
+		for _, key := range test.keys {
+			set[key] = struct{}{}
+		}
+		if got := joinKeys(set); got != test.want {
+			t.Errorf("joinKeys(%q) = %q, want %q", test.keys, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+)
+
+// heatLevels is the number of shades of the heatmap cells with matches.
+const heatLevels = 4
+
+// Heatmap is the heatmap view: a grid of 256 cells per IPv4 subnet with
+// matching hosts.
+type Heatmap struct {
+	// Prefix is 24, for a cell per address, or 16, for a cell per /24.
+	Prefix int           `json:"prefix"`
+	Max    int           `json:"max"`
+	Grids  []HeatmapGrid `json:"grids"`
+	// Skipped counts the matching hosts without an IPv4 address.
+	Skipped int `json:"skipped,omitempty"`
+}
+
+// HeatmapGrid is a subnet of the heatmap as 16 rows of 16 cells.
+type HeatmapGrid struct {
+	Network string          `json:"network"`
+	Hosts   int             `json:"hosts"`
+	Ports   int             `json:"ports"`
+	Rows    [][]HeatmapCell `json:"-"`
+}
+
+// HeatmapCell is an address of a /24 grid, or a /24 of a /16 grid.
+type HeatmapCell struct {
+	Label string
+	// Octet is the last octet of the address, or the third of the /24.
+	Octet int
+	// Value is the number of matching ports of the address, or of matching
+	// hosts in the /24.
+	Value int
+	// Level is the shade of the cell, 0 for no matches up to heatLevels.
+	Level int
+}
+
+// BuildHeatmap returns the heatmap of the host ports of data, grouped into
+// /prefix subnets; prefix is 24 or 16.
+func BuildHeatmap(data ReportData, prefix int) *Heatmap {
+	heatmap := &Heatmap{Prefix: prefix}
+	// values holds the cell values of every subnet, hosts the matching
+	// hosts of the subnet and ports its matching ports.
+	values := make(map[netip.Prefix]*[256]int)
+	hosts := make(map[netip.Prefix]map[netip.Addr]bool)
+	ports := make(map[netip.Prefix]int)
+	skipped := make(map[string]bool)
+	for _, rows := range [][]ReportRow{data.Rows, data.Unverified} {
+		for _, row := range rows {
+			for _, host := range row.Hosts {
+				addr, err := netip.ParseAddr(host.Addr)
+				if err != nil || !addr.Unmap().Is4() {
+					skipped[host.Addr] = true
+					continue
+				}
+				addr = addr.Unmap()
+				network, _ := addr.Prefix(prefix)
+				if values[network] == nil {
+					values[network] = new([256]int)
+					hosts[network] = make(map[netip.Addr]bool)
+				}
+				octets := addr.As4()
+				ports[network]++
+				if prefix == 24 {
+					values[network][octets[3]]++
+				} else if !hosts[network][addr] {
+					values[network][octets[2]]++
+				}
+				hosts[network][addr] = true
+			}
+		}
+	}
+	heatmap.Skipped = len(skipped)
+
+	networks := make([]netip.Prefix, 0, len(values))
+	for network, cells := range values {
+		networks = append(networks, network)
+		for _, value := range cells {
+			heatmap.Max = max(heatmap.Max, value)
+		}
+	}
+	sort.Slice(networks, func(i, j int) bool { return networks[i].Addr().Less(networks[j].Addr()) })
+	for _, network := range networks {
+		grid := HeatmapGrid{Network: network.String(), Hosts: len(hosts[network]), Ports: ports[network]}
+		base := network.Addr().As4()
+		for row := 0; row < 16; row++ {
+			cells := make([]HeatmapCell, 16)
+			for column := range cells {
+				octet := row*16 + column
+				cell := HeatmapCell{Octet: octet, Value: values[network][octet]}
+				if prefix == 24 {
+					cell.Label = netip.AddrFrom4([4]byte{base[0], base[1], base[2], byte(octet)}).String()
+				} else {
+					cell.Label = fmt.Sprintf("%d.%d.%d.0/24", base[0], base[1], octet)
+				}
+				if cell.Value > 0 {
+					// Shades split 1..Max evenly, so the busiest cells are darkest.
+					cell.Level = (cell.Value*heatLevels + heatmap.Max - 1) / heatmap.Max
+				}
+				cells[column] = cell
+			}
+			grid.Rows = append(grid.Rows, cells)
+		}
+		heatmap.Grids = append(heatmap.Grids, grid)
+	}
+	return heatmap
+}
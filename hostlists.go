@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// hostPorts returns every host port of tableData, verified or not.
+func hostPorts(tableData ReportData) []*HostPort {
+	var hosts []*HostPort
+	for _, rows := range [][]ReportRow{tableData.Rows, tableData.Unverified} {
+		for i := range rows {
+			for j := range rows[i].Hosts {
+				hosts = append(hosts, &rows[i].Hosts[j])
+			}
+		}
+	}
+	return hosts
+}
+
+// hostAddrs returns the distinct addresses of the host ports of tableData, in
+// address order.
+func hostAddrs(tableData ReportData) []string {
+	seen := make(map[string]bool)
+	var addrs []string
+	for _, host := range hostPorts(tableData) {
+		if !seen[host.Addr] {
+			seen[host.Addr] = true
+			addrs = append(addrs, host.Addr)
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool { return lessAddr(addrs[i], addrs[j]) })
+	return addrs
+}
+
+// hostnamesOf returns every hostname of the host of hostPort.
+func hostnamesOf(hostPort *HostPort) []string {
+	if hostPort.host == nil {
+		if hostPort.Hostname == "" {
+			return nil
+		}
+		return []string{hostPort.Hostname}
+	}
+	var names []string
+	for _, hostname := range hostPort.host.Hostnames.Hostname {
+		names = append(names, hostname.Name)
+	}
+	return names
+}
+
+// writeLines writes lines to w, one per line.
+func writeLines(w io.Writer, lines []string) error {
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeIPs writes the distinct addresses of the hosts of tableData to w, one
+// per line, for tools that take a list of targets such as masscan (-iL).
+func writeIPs(tableData ReportData, w io.Writer) error {
+	return writeLines(w, hostAddrs(tableData))
+}
+
+// aggregatePrefixes returns the smallest list of networks covering exactly
+// addrs, which must be sorted and distinct: adjacent networks of the same size
+// are merged into their parent until no two can be.
+func aggregatePrefixes(addrs []netip.Addr) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, addr := range addrs {
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		for n := len(prefixes); n >= 2; n = len(prefixes) {
+			a, b := prefixes[n-2], prefixes[n-1]
+			if a.Bits() != b.Bits() || a.Bits() == 0 {
+				break
+			}
+			parent := netip.PrefixFrom(a.Addr(), a.Bits()-1).Masked()
+			if parent != netip.PrefixFrom(b.Addr(), b.Bits()-1).Masked() {
+				break
+			}
+			prefixes = append(prefixes[:n-2], parent)
+		}
+	}
+	return prefixes
+}
+
+// writeCIDRs writes the addresses of the hosts of tableData to w aggregated
+// into CIDR networks, one per line, covering those hosts and no others.
+// Addresses that are not IPs, such as hostnames, are written as they are.
+func writeCIDRs(tableData ReportData, w io.Writer) error {
+	var addrs []netip.Addr
+	var others []string
+	for _, value := range hostAddrs(tableData) {
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			others = append(others, value)
+			continue
+		}
+		addrs = append(addrs, addr.Unmap())
+	}
+	var lines []string
+	for _, prefix := range aggregatePrefixes(addrs) {
+		lines = append(lines, prefix.String())
+	}
+	return writeLines(w, append(lines, others...))
+}
+
+// writeEtcHosts writes the hosts of tableData with a hostname to w in the
+// format of /etc/hosts, an address followed by its hostnames per line, so
+// that virtual hosts resolve on the tester's machine.
+func writeEtcHosts(tableData ReportData, w io.Writer) error {
+	names := make(map[string][]string)
+	for _, host := range hostPorts(tableData) {
+		for _, name := range hostnamesOf(host) {
+			if !strings.EqualFold(name, host.Addr) && !slices.ContainsFunc(names[host.Addr], func(v string) bool { return strings.EqualFold(v, name) }) {
+				names[host.Addr] = append(names[host.Addr], name)
+			}
+		}
+	}
+	var lines []string
+	for _, addr := range hostAddrs(tableData) {
+		if len(names[addr]) > 0 {
+			lines = append(lines, addr+"\t"+strings.Join(names[addr], " "))
+		}
+	}
+	return writeLines(w, lines)
+}
+
+// burpScopeRule is an include rule of a Burp Suite advanced target scope.
+type burpScopeRule struct {
+	Enabled  bool   `json:"enabled"`
+	File     string `json:"file"`
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Protocol string `json:"protocol"`
+}
+
+// burpScope is a Burp Suite project options file holding only a target scope,
+// loaded with Project options > Load project options.
+type burpScope struct {
+	Target struct {
+		Scope struct {
+			AdvancedMode bool            `json:"advanced_mode"`
+			Exclude      []burpScopeRule `json:"exclude"`
+			Include      []burpScopeRule `json:"include"`
+		} `json:"scope"`
+	} `json:"target"`
+}
+
+// writeBurpScope writes the host ports of tableData to w as a Burp Suite
+// target scope, with a rule for the address and every hostname of each host
+// port. The rules of web servers are limited to their scheme, see webURL.
+func writeBurpScope(tableData ReportData, w io.Writer) error {
+	var scope burpScope
+	scope.Target.Scope.AdvancedMode = true
+	scope.Target.Scope.Exclude = []burpScopeRule{}
+	scope.Target.Scope.Include = []burpScopeRule{}
+	seen := make(map[burpScopeRule]bool)
+	hosts := hostPorts(tableData)
+	sort.SliceStable(hosts, func(i, j int) bool {
+		if hosts[i].Addr != hosts[j].Addr {
+			return lessAddr(hosts[i].Addr, hosts[j].Addr)
+		}
+		return atoi(hosts[i].Port) < atoi(hosts[j].Port)
+	})
+	for _, host := range hosts {
+		protocol := "any"
+		if scheme, _, ok := strings.Cut(host.URL, "://"); ok {
+			protocol = scheme
+		}
+		for _, name := range append([]string{host.Addr}, hostnamesOf(host)...) {
+			rule := burpScopeRule{
+				Enabled:  true,
+				File:     "^/.*",
+				Host:     "^" + regexp.QuoteMeta(name) + "$",
+				Port:     "^" + host.Port + "$",
+				Protocol: protocol,
+			}
+			if !seen[rule] {
+				seen[rule] = true
+				scope.Target.Scope.Include = append(scope.Target.Scope.Include, rule)
+			}
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(scope)
+}
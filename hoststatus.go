@@ -0,0 +1,36 @@
+package main
+
+// DownHost is a host the scans reported down, listed in the appendix of
+// reports with --down-hosts.
+type DownHost struct {
+	Addr     string `json:"addr"`
+	Hostname string `json:"hostname,omitempty"`
+	// Reason is why nmap considers the host down, e.g. no-response.
+	Reason string `json:"reason,omitempty"`
+}
+
+// isDown reports whether host was not up in any of the scans it appears in.
+// Hosts without a status, as written by some tools, count as up.
+func isDown(host *NmapHost) bool {
+	return host.Status.State != "" && host.Status.State != "up"
+}
+
+// downHosts returns the hosts of hosts that are down and not excluded by opts.
+// nmap only lists down hosts in its XML with -v or --reason; the others are
+// only counted in the run statistics.
+func downHosts(hosts []NmapHost, opts TableOptions) []DownHost {
+	var down []DownHost
+	for i := range hosts {
+		host := &hosts[i]
+		addr := hostAddress(host, opts.PreferIPv6)
+		if !isDown(host) || opts.excluded(addr) {
+			continue
+		}
+		downHost := DownHost{Addr: addr, Reason: host.Status.Reason}
+		if len(host.Hostnames.Hostname) > 0 {
+			downHost.Hostname = host.Hostnames.Hostname[0].Name
+		}
+		down = append(down, downHost)
+	}
+	return down
+}
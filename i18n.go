@@ -0,0 +1,75 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultLang is the language of the report labels written in the code and
+// templates, the keys of the message catalogs.
+const defaultLang = "en"
+
+//go:embed messages/*.yaml
+var messageFS embed.FS
+
+// messages is the message catalog of a language: the translation of each
+// English label, or format string, of the reports. Labels missing from it are
+// written in English.
+type messages map[string]string
+
+// loadMessages returns the message catalog of lang, such as de, from the
+// catalogs built into the binary. English has none.
+func loadMessages(lang string) (messages, error) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "" || lang == defaultLang {
+		return nil, nil
+	}
+	data, err := messageFS.ReadFile(path.Join("messages", lang+".yaml"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("unsupported language: %s (available: %s)", lang, strings.Join(languages(), ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	var catalog messages
+	if err := yaml.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("invalid %s message catalog: %w", lang, err)
+	}
+	return catalog, nil
+}
+
+// languages returns the sorted languages of --lang.
+func languages() []string {
+	langs := []string{defaultLang}
+	entries, _ := messageFS.ReadDir("messages")
+	for _, entry := range entries {
+		langs = append(langs, strings.TrimSuffix(entry.Name(), ".yaml"))
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// T returns the translation of msg, formatted with args as by fmt.Sprintf
+// when there are any, e.g. {{$.T "Matching %s Ports" .Service}}.
+func (m messages) T(msg string, args ...any) string {
+	if translated, ok := m[msg]; ok && translated != "" {
+		msg = translated
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// T returns the translation of the report label msg in the language of the
+// report, see messages.T.
+func (r ReportData) T(msg string, args ...any) string {
+	return r.messages.T(msg, args...)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Asset is the business context of a host from the --inventory file.
+type Asset struct {
+	Owner       string
+	Environment string
+	Criticality string
+}
+
+// assetContext returns the environment and owner of a host for display, e.g.
+// "prod / payments team".
+func assetContext(environment, owner string) string {
+	var parts []string
+	for _, part := range []string{environment, owner} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " / ")
+}
+
+// inventoryNetwork is an inventory entry for a whole network.
+type inventoryNetwork struct {
+	prefix netip.Prefix
+	asset  Asset
+}
+
+// inventory maps hosts to their assets, by address or by network.
+type inventory struct {
+	hosts map[netip.Addr]Asset
+	// networks are ordered most specific first.
+	networks []inventoryNetwork
+}
+
+// inventoryColumns are the accepted names of the columns of an inventory file,
+// by field.
+var inventoryColumns = map[string][]string{
+	"ip":          {"ip", "address", "host", "cidr"},
+	"owner":       {"owner", "team"},
+	"environment": {"environment", "env"},
+	"criticality": {"criticality"},
+}
+
+// loadInventory reads the CSV asset inventory at path. Its header names the
+// ip (an address or CIDR), owner, environment and criticality columns, in any
+// order; other columns are ignored.
+func loadInventory(path string) (*inventory, error) {
+	if path == "" {
+		return nil, nil
+	}
+	path, err := resolveAbsPath(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	outputManifest.input(path)
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	index := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		for field, names := range inventoryColumns {
+			if _, ok := index[field]; !ok && slices.Contains(names, name) {
+				index[field] = i
+			}
+		}
+	}
+	if _, ok := index["ip"]; !ok {
+		return nil, errors.New("no ip column in header")
+	}
+	value := func(record []string, field string) string {
+		i, ok := index[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	inv := &inventory{hosts: make(map[netip.Addr]Asset)}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		ip := value(record, "ip")
+		if ip == "" {
+			continue
+		}
+		asset := Asset{Owner: value(record, "owner"), Environment: value(record, "environment"), Criticality: value(record, "criticality")}
+		if strings.Contains(ip, "/") {
+			prefix, err := netip.ParsePrefix(ip)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line(reader), err)
+			}
+			inv.networks = append(inv.networks, inventoryNetwork{prefix: prefix.Masked(), asset: asset})
+			continue
+		}
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line(reader), err)
+		}
+		inv.hosts[addr.Unmap()] = asset
+	}
+	sort.SliceStable(inv.networks, func(i, j int) bool {
+		return inv.networks[i].prefix.Bits() > inv.networks[j].prefix.Bits()
+	})
+	return inv, nil
+}
+
+// line returns the line of the record reader last read.
+func line(reader *csv.Reader) int {
+	line, _ := reader.FieldPos(0)
+	return line
+}
+
+// lookup returns the asset of the host addr: its own entry, or that of the
+// most specific network containing it.
+func (inv *inventory) lookup(addr string) (Asset, bool) {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return Asset{}, false
+	}
+	ip = ip.Unmap()
+	if asset, ok := inv.hosts[ip]; ok {
+		return asset, true
+	}
+	for _, network := range inv.networks {
+		if network.prefix.Contains(ip) {
+			return network.asset, true
+		}
+	}
+	return Asset{}, false
+}
@@ -0,0 +1,164 @@
+package main
+
+import "strings"
+
+// serviceLayouts are the canned table layouts of services whose NSE script
+// output is worth reporting: the detail columns added to the default columns
+// of a report on the service when no --columns were chosen. Columns no host has
+// a value for are left out, e.g. for scans run without -sC.
+var serviceLayouts = map[string][]string{
+	"http":          {"title", "server", "redirect"},
+	"http-alt":      {"title", "server", "redirect"},
+	"http-proxy":    {"title", "server", "redirect"},
+	"https":         {"title", "server", "redirect", "cert-cn", "cert-expiry"},
+	"https-alt":     {"title", "server", "redirect", "cert-cn", "cert-expiry"},
+	"ssl":           {"cert-cn", "cert-expiry"},
+	"imaps":         {"cert-cn", "cert-expiry"},
+	"pop3s":         {"cert-cn", "cert-expiry"},
+	"smtps":         {"cert-cn", "cert-expiry"},
+	"ldaps":         {"cert-cn", "cert-expiry"},
+	"ms-wbt-server": {"cert-cn", "cert-expiry"},
+	"ms-sql-s":      {"mssql-instance"},
+	"microsoft-ds":  {"smb-os", "smb-signing", "smb-issues"},
+	"netbios-ssn":   {"smb-os", "smb-signing", "smb-issues"},
+	"snmp":          {"snmp-device", "snmp-sysdescr", "snmp-communities"},
+}
+
+// hostDetail returns the value of a column for the script detail key.
+func hostDetail(key string) func(h *HostPort) string {
+	return func(h *HostPort) string { return h.Details[key] }
+}
+
+// layoutDetails returns the script detail columns of the layout of the report
+// service that any host has a value for.
+func (r ReportData) layoutDetails() []string {
+	var names []string
+	for _, name := range serviceLayouts[r.Service] {
+		if r.hasDetail(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// hasDetail reports whether any host of the report has the script detail key.
+func (r ReportData) hasDetail(key string) bool {
+	for _, rows := range [][]ReportRow{r.Rows, r.Unverified} {
+		for _, row := range rows {
+			for _, host := range row.Hosts {
+				if host.Details[key] != "" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// scriptDetails extracts the NSE script output that the canned layouts report
+// for port of host: the HTTP title, redirect, methods and server header, the
+// subject CN and expiry of the TLS certificate, the SQL Server instance name
+// the SMB OS, message signing mode and posture, see smbDetails, the SNMP
+// findings, see snmpDetails, and the AD domain. It returns nil when none was
+// found.
+func scriptDetails(host *NmapHost, port *NmapPort) map[string]string {
+	details := make(map[string]string)
+	set := func(key, value string) {
+		if value = strings.TrimSpace(value); value != "" && details[key] == "" {
+			details[key] = value
+		}
+	}
+	set("domain", serviceDomain(port.Service.Extrainfo))
+	for i := range port.Script {
+		script := &port.Script[i]
+		switch script.ID {
+		case "http-title":
+			set("title", elemValue(script.Elem, "title"))
+			set("redirect", elemValue(script.Elem, "redirect_url"))
+		case "http-methods":
+			set("methods", strings.Join(tableTexts(script.Table, "Supported Methods"), " "))
+		case "http-server-header":
+			set("server", elemValue(script.Elem, ""))
+			set("server", script.Output)
+		case "ssl-cert":
+			set("cert-cn", elemValue(tableElems(script.Table, "subject"), "commonName"))
+			set("cert-expiry", elemValue(tableElems(script.Table, "validity"), "notAfter"))
+		case "ms-sql-info":
+			set("mssql-instance", findElem(script.Table, "Instance name"))
+		}
+	}
+	for i := range host.Hostscript.Script {
+		script := &host.Hostscript.Script[i]
+		switch script.ID {
+		case "smb-os-discovery":
+			set("smb-os", elemValue(script.Elem, "os"))
+			set("domain", elemValue(script.Elem, "domain_dns"))
+		case "smb-security-mode":
+			set("smb-signing", elemValue(script.Elem, "message_signing"))
+		case "smb2-security-mode":
+			set("smb-signing", findElem(script.Table, ""))
+		case "ms-sql-info":
+			// Before nmap 7.70 ms-sql-info was a host script with a table
+			// per instance, keyed by address and port.
+			for _, table := range script.Table {
+				if len(script.Table) == 1 || strings.HasSuffix(strings.Trim(table.Key, "[]"), ":"+port.Portid) {
+					set("mssql-instance", elemValue(table.Elem, "Instance name"))
+				}
+			}
+		}
+	}
+	smbDetails(host.Hostscript.Script, set)
+	if isSNMPPort(port) {
+		snmpDetails(port, set)
+	}
+	if len(details) == 0 {
+		return nil
+	}
+	return details
+}
+
+// elemValue returns the text of the element keyed key among elems.
+func elemValue(elems []NmapElem, key string) string {
+	for _, elem := range elems {
+		if elem.Key == key {
+			return elem.Text
+		}
+	}
+	return ""
+}
+
+// tableTexts returns the text of the unkeyed elements of the table keyed key
+// among tables, such as the list of methods of http-methods.
+func tableTexts(tables []NmapTable, key string) []string {
+	var texts []string
+	for _, elem := range tableElems(tables, key) {
+		if elem.Key == "" {
+			texts = append(texts, elem.Text)
+		}
+	}
+	return texts
+}
+
+// tableElems returns the elements of the table keyed key among tables.
+func tableElems(tables []NmapTable, key string) []NmapElem {
+	for _, table := range tables {
+		if table.Key == key {
+			return table.Elem
+		}
+	}
+	return nil
+}
+
+// findElem returns the text of the first element keyed key in tables or their
+// nested tables.
+func findElem(tables []NmapTable, key string) string {
+	for _, table := range tables {
+		if value := elemValue(table.Elem, key); value != "" {
+			return value
+		}
+		if value := findElem(table.Table, key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
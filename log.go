@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// logger logs what nmapTables is doing to stderr: only warnings by default,
+// more with -v and -vv, and only errors with --quiet.
+var logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+// jsonLogs is set by --log-format json; the final error of a command is then
+// logged as a JSON record too.
+var jsonLogs bool
+
+// setupLogging configures logger for the --verbose count and the --quiet,
+// --log-level and --log-format flags. An explicit --log-level wins over the
+// others.
+func setupLogging(verbosity int, quiet bool, levelName, format string) error {
+	level := slog.LevelWarn
+	switch {
+	case quiet:
+		level = slog.LevelError
+	case verbosity == 1:
+		level = slog.LevelInfo
+	case verbosity > 1:
+		level = slog.LevelDebug
+	}
+	if levelName != "" {
+		if err := level.UnmarshalText([]byte(levelName)); err != nil {
+			return fmt.Errorf("invalid --log-level: %s (use debug, info, warn or error)", levelName)
+		}
+	}
+	options := &slog.HandlerOptions{Level: level}
+	switch format {
+	case "", "text":
+		jsonLogs = false
+		logger = slog.New(slog.NewTextHandler(os.Stderr, options))
+	case "json":
+		jsonLogs = true
+		logger = slog.New(slog.NewJSONHandler(os.Stderr, options))
+	default:
+		return fmt.Errorf("invalid --log-format: %s (use text or json)", format)
+	}
+	return nil
+}
+
+// logCommandError reports the error a command failed with: as "Error: ..."
+// text, or as a log record with --log-format json.
+func logCommandError(err error) {
+	if jsonLogs {
+		logger.Error("command failed", "err", err)
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Error:", err)
+}
+
+// logParseError logs an input file that could not be parsed.
+func logParseError(parseErr *ParseError) {
+	attrs := []any{"file", parseErr.File, "offset", parseErr.Offset, "reason", parseErr.Reason}
+	if parseErr.Recovered > 0 {
+		attrs = append(attrs, "recovered_hosts", parseErr.Recovered)
+	}
+	logger.Warn("could not parse input file", attrs...)
+}
+
+// statusf prints a message about the output files that were written, unless
+// --quiet is set.
+func statusf(format string, args ...any) {
+	if !quiet {
+		fmt.Printf(format, args...)
+	}
+}
+
+// isTerminal reports whether f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// progressWidth is the number of characters of the progress bar.
+const progressWidth = 30
+
+// progress draws a progress bar of the files parsed so far on stderr. It is
+// only shown on a terminal, without --quiet or verbose logging that would
+// interleave with it.
+type progress struct {
+	w     io.Writer
+	total int
+	done  int
+}
+
+// newProgress returns a progress bar for total files, or nil when it should
+// not be shown. The methods of a nil progress do nothing.
+func newProgress(total int) *progress {
+	if quiet || verbosity > 0 || total < 2 || !isTerminal(os.Stderr) {
+		return nil
+	}
+	return &progress{w: os.Stderr, total: total}
+}
+
+// step records that another file was parsed and redraws the bar.
+func (p *progress) step() {
+	if p == nil {
+		return
+	}
+	p.done++
+	filled := p.done * progressWidth / p.total
+	fmt.Fprintf(p.w, "\rParsing [%s%s] %d/%d files", strings.Repeat("=", filled), strings.Repeat(" ", progressWidth-filled), p.done, p.total)
+}
+
+// clear erases the bar, before other messages are written to stderr and once
+// parsing is done. The next step draws it again.
+func (p *progress) clear() {
+	if p == nil {
+		return
+	}
+	fmt.Fprintf(p.w, "\r%s\r", strings.Repeat(" ", progressWidth+40))
+}
@@ -1,87 +1,771 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"embed"
 	"encoding/xml"
-	"flag"
+	"errors"
 	"fmt"
 	"html/template"
-	"log"
+	"io"
+	"io/fs"
+	"net"
 	"os"
 	"os/user"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/mr-pmillz/nmapTables/nmap"
 )
 
-func GenerateTableData(nmapFiles []string, serviceName string) [][]string {
-	versionMap := make(map[string][]string)
+// ScanSummary holds aggregate statistics about the scans that were parsed,
+// collected while building the table data.
+type ScanSummary struct {
+	HostsScanned     int       `json:"hosts_scanned"`
+	HostsUp          int       `json:"hosts_up"`
+	HostsDown        int       `json:"hosts_down"`
+	MatchingPorts    int       `json:"matching_ports"`
+	DistinctVersions int       `json:"distinct_versions"`
+	ScanStart        time.Time `json:"scan_start"`
+	ScanEnd          time.Time `json:"scan_end"`
+}
 
-	for _, filePath := range nmapFiles {
-		fileData, err := os.ReadFile(filePath)
-		if err != nil {
-			fmt.Printf("Error reading file %s: %v\n", filePath, err)
-			continue
+// DateRange returns the scan date range formatted for display.
+func (s ScanSummary) DateRange() string {
+	if s.ScanStart.IsZero() {
+		return "unknown"
+	}
+	const layout = "2006-01-02 15:04 MST"
+	if s.ScanEnd.IsZero() || s.ScanEnd.Equal(s.ScanStart) {
+		return s.ScanStart.Format(layout)
+	}
+	return fmt.Sprintf("%s - %s", s.ScanStart.Format(layout), s.ScanEnd.Format(layout))
+}
+
+// HostPort is a single host and port on which a service was found.
+type HostPort struct {
+	Addr string `json:"addr"`
+	Port string `json:"port"`
+	// Protocol is the transport protocol of the port, tcp or udp, and
+	// showProtocol adds it to String in rows mixing protocols, such as those
+	// of --group-by host.
+	Protocol     string `json:"protocol"`
+	showProtocol bool
+	// OS is the best OS match of the host, only set when requested.
+	OS string `json:"os,omitempty"`
+	// MAC and Vendor are the hardware address of the host and its vendor, only
+	// set when requested.
+	MAC    string `json:"mac,omitempty"`
+	Vendor string `json:"vendor,omitempty"`
+	// Hostname is the first hostname of the host.
+	Hostname string `json:"hostname,omitempty"`
+	// CPE is the CPE of the service detected on the port.
+	CPE string `json:"cpe,omitempty"`
+	// ExtraInfo is the extra information of the service banner, e.g.
+	// "protocol 2.0" or "Ubuntu Linux; protocol 2.0".
+	ExtraInfo string `json:"extrainfo,omitempty"`
+	// Tunnel is ssl when nmap detected the service through an SSL tunnel.
+	Tunnel string `json:"tunnel,omitempty"`
+	// Ident is the user running the service, see portOwner.
+	Ident string `json:"ident,omitempty"`
+	// Reason is why nmap considers the port in its state, e.g. syn-ack, and
+	// ReasonTTL the TTL of the response it got.
+	Reason    string `json:"reason,omitempty"`
+	ReasonTTL string `json:"reason_ttl,omitempty"`
+	// Country and ASN are the country code and autonomous system of the host,
+	// only set with --geoip.
+	Country string `json:"country,omitempty"`
+	ASN     string `json:"asn,omitempty"`
+	// Owner, Environment and Criticality are the business context of the
+	// host, only set with --inventory.
+	Owner       string `json:"owner,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	Criticality string `json:"criticality,omitempty"`
+	// Source is the input file the port was read from, only set with
+	// --show-source.
+	Source string `json:"source,omitempty"`
+	// Seen is the start time of the scan the port was observed in, only set
+	// with --merge all.
+	Seen time.Time `json:"seen,omitzero"`
+	// URL is the address of web servers, see webURL.
+	URL string `json:"url,omitempty"`
+	// Screenshot is the path of the screenshot of the web server, only set
+	// with --screenshots.
+	Screenshot string `json:"screenshot,omitempty"`
+	// Details are the NSE script details shown by the canned layout of the
+	// service, keyed by column name; see scriptDetails.
+	Details map[string]string `json:"details,omitempty"`
+	// host and port are the merged host and port the host port was built
+	// from, which the nmap-xml export writes in full.
+	host *NmapHost
+	port *NmapPort
+}
+
+// String returns addr:port, with IPv6 addresses in brackets ([addr]:port),
+// followed by /protocol in rows mixing protocols.
+func (h HostPort) String() string {
+	if h.showProtocol && h.Protocol != "" {
+		return net.JoinHostPort(h.Addr, h.Port) + "/" + h.Protocol
+	}
+	return net.JoinHostPort(h.Addr, h.Port)
+}
+
+// ReportRow is a single row of the report table: by default every host running
+// the same version of a service over the same protocol, see --group-by.
+type ReportRow struct {
+	Hosts    []HostPort `json:"hosts"`
+	Protocol string     `json:"protocol"`
+	Service  string     `json:"service"`
+	// Version is the product and version, as displayed in the report.
+	Version        string `json:"-"`
+	Product        string `json:"product"`
+	ProductVersion string `json:"version"`
+	// Risk is the label of the first matching risk rule, if any.
+	Risk     string `json:"risk,omitempty"`
+	RiskRule string `json:"risk_rule,omitempty"`
+	// EOL is the end-of-life date of the version, if it is no longer supported.
+	EOL     string `json:"eol,omitempty"`
+	EOLName string `json:"eol_name,omitempty"`
+	// OT is the industrial protocol of the ports of the row, if any, which
+	// demand different handling.
+	OT string `json:"ot,omitempty"`
+	// Verified is false for detections below the --min-conf threshold.
+	Verified bool `json:"verified"`
+	// versions are the distinct product versions of the row, oldest first.
+	versions []productVersion
+}
+
+// Classes returns the CSS classes of the row in HTML reports: eol for
+// end-of-life versions and ot for industrial protocols.
+func (r ReportRow) Classes() string {
+	var classes []string
+	if r.EOL != "" {
+		classes = append(classes, "eol")
+	}
+	if r.OT != "" {
+		classes = append(classes, "ot")
+	}
+	return strings.Join(classes, " ")
+}
+
+// ReportData is the context passed to the HTML template.
+type ReportData struct {
+	Service string `json:"service"`
+	MinConf int    `json:"min_conf"`
+	ShowOS  bool   `json:"-"`
+	ShowMAC bool   `json:"-"`
+	// ShowHostname adds the hostname to the default columns, once --resolve
+	// or --hosts-file filled in the missing ones.
+	ShowHostname bool `json:"-"`
+	// ShowGeo adds the country and autonomous system columns, with --geoip.
+	ShowGeo bool `json:"-"`
+	// ShowSource adds the input file of each host port to the default columns.
+	ShowSource bool `json:"-"`
+	// ShowExtraInfo adds the extra information of the service banners, with
+	// --extrainfo or --group-by banner.
+	ShowExtraInfo bool `json:"-"`
+	// ShowIdent adds the user running each service, with --ident.
+	ShowIdent bool `json:"-"`
+	// ShowReason adds the reason and response TTL of the port states, with
+	// --reason.
+	ShowReason bool `json:"-"`
+	// ShowSeen adds the time each host port was observed, with --merge all.
+	ShowSeen bool `json:"-"`
+	// ShowAsset adds the asset context and criticality columns, with
+	// --inventory.
+	ShowAsset bool `json:"-"`
+	// ShowScreenshots adds the screenshots of the web servers, with
+	// --screenshots.
+	ShowScreenshots bool        `json:"-"`
+	ShowRisk        bool        `json:"-"`
+	ShowEOL         bool        `json:"-"`
+	Summary         ScanSummary `json:"summary"`
+	Rows            []ReportRow `json:"rows"`
+	// Unverified holds the rows whose detection confidence is below MinConf.
+	Unverified []ReportRow `json:"unverified,omitempty"`
+	// DownHosts are the hosts the scans reported down, with --down-hosts.
+	DownHosts []DownHost `json:"down_hosts,omitempty"`
+	// DownExcluded is set when the down hosts are left out of the host
+	// counts, with --exclude-down.
+	DownExcluded bool `json:"-"`
+	// Paths holds the matching hosts grouped by last-hop router.
+	Paths []PathRow `json:"paths,omitempty"`
+	// Scans are the provenance of the input scans: file, command and times.
+	Scans []ScanSource `json:"scans,omitempty"`
+	// Errors lists the input files that could not be parsed.
+	Errors []ParseError `json:"errors,omitempty"`
+	// Theme is the initial theme of HTML reports: light, dark or print.
+	Theme string `json:"-"`
+	// ProtocolLayout is how HTML tables show the protocols of their rows, see
+	// --protocol-layout.
+	ProtocolLayout string `json:"-"`
+	// Services are the reports of every service of a multi-service HTML
+	// report, each a section of the report, set on the report the template is
+	// executed with; a single-service report has none.
+	Services []ReportData `json:"-"`
+	// Engagement is the title of pdf and docx reports, see --engagement.
+	Engagement string `json:"-"`
+	// Severity colors the rows of HTML reports by severity, see --color-rows;
+	// rows are not colored when it is nil.
+	Severity *rowSeverity `json:"-"`
+	// screenshots are the screenshots of --screenshots, embedded in HTML
+	// reports by ScreenshotData.
+	screenshots *screenshotIndex
+	// Lang is the language of the report labels, see --lang, and messages
+	// their translations.
+	Lang     string `json:"-"`
+	messages messages
+	// Branding is the client branding of the reports, see --title.
+	Branding Branding `json:"-"`
+	// Top ranks the most common service versions of the whole dataset, see
+	// --top.
+	Top []VersionCount `json:"top,omitempty"`
+	// OT are the ports of the whole dataset speaking an industrial protocol.
+	OT []OTExposure `json:"ot,omitempty"`
+	// QuickWins are the services of the whole dataset commonly exposed with
+	// default or no credentials, see --quick-wins.
+	QuickWins []QuickWin `json:"quick_wins,omitempty"`
+	// Charts are the data of the charts of HTML reports.
+	Charts *ReportCharts `json:"charts,omitempty"`
+	// Heatmap is the matching hosts per subnet of the heatmap view.
+	Heatmap *Heatmap `json:"heatmap,omitempty"`
+	// Columns are the --columns chosen for the report tables; see TableColumns.
+	Columns []Column `json:"-"`
+	// RowRecords makes column exports have a record per row rather than per
+	// host port, for tables with a row per host such as ad.
+	RowRecords bool `json:"-"`
+}
+
+// parseUnixTime converts an nmap unix timestamp attribute into a time.Time.
+// A zero time is returned when the attribute is empty or invalid.
+func parseUnixTime(value string) time.Time {
+	sec, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || sec <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// atoi converts an nmap numeric attribute into an int, treating invalid values as 0.
+func atoi(value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// updateSummary adds the run statistics and scan times of nmapRun to summary.
+func updateSummary(summary *ScanSummary, nmapRun *Nmaprun) {
+	summary.HostsScanned += atoi(nmapRun.Runstats.Hosts.Total)
+	summary.HostsUp += atoi(nmapRun.Runstats.Hosts.Up)
+	summary.HostsDown += atoi(nmapRun.Runstats.Hosts.Down)
+
+	start := parseUnixTime(nmapRun.Start)
+	end := parseUnixTime(nmapRun.Runstats.Finished.Time)
+	if end.IsZero() {
+		end = start
+	}
+	if !start.IsZero() && (summary.ScanStart.IsZero() || start.Before(summary.ScanStart)) {
+		summary.ScanStart = start
+	}
+	if !end.IsZero() && end.After(summary.ScanEnd) {
+		summary.ScanEnd = end
+	}
+}
+
+// TableOptions controls which ports are included when generating table data.
+type TableOptions struct {
+	ServiceName string
+	// States is the set of port states (open, closed, filtered, open|filtered, ...) to report.
+	States map[string]bool
+	// MinConf is the minimum nmap service detection confidence (0-10) for a
+	// detection to be reported as verified.
+	MinConf int
+	// IncludeOS adds the best OS fingerprint match of each host to the report.
+	IncludeOS bool
+	// IncludeMAC adds the MAC address and vendor of each host to the report.
+	IncludeMAC bool
+	// IncludeSource adds the input file each host port was read from.
+	IncludeSource bool
+	// IncludeExtraInfo adds the extra information of the service banners.
+	IncludeExtraInfo bool
+	// IncludeIdent adds the user running each service, see portOwner.
+	IncludeIdent bool
+	// IncludeReason adds the reason and response TTL of the port states.
+	IncludeReason bool
+	// Reasons keeps only the ports whose state reason is one of them, e.g.
+	// syn-ack; empty keeps every port.
+	Reasons map[string]bool
+	// ExcludeDown leaves the hosts the scans reported down out of the report
+	// and its host counts, see isDown.
+	ExcludeDown bool
+	// ListDown lists the down hosts in an appendix of the report.
+	ListDown bool
+	// IncludeUnknown adds rows for the ports with no or an unknown service
+	// and the tcpwrapped ones, by port number.
+	IncludeUnknown bool
+	// Merge is the --merge strategy of the ports seen in several scans:
+	// confidence (the default), latest or all.
+	Merge string
+	// NetworkPath groups the matching hosts by their last-hop router.
+	NetworkPath bool
+	// RiskRules tag rows with a risk label.
+	RiskRules []RiskRule
+	// Exclude lists networks whose hosts are left out of the report.
+	Exclude []*net.IPNet
+	// Scope, when set, leaves out the hosts outside these networks.
+	Scope []*net.IPNet
+	// NewestFirst sorts the versions of each product newest first.
+	NewestFirst bool
+	// EOLData flags rows whose version has reached end-of-life.
+	EOLData []EOLEntry
+	// Normalize are the rules rewriting banner noise out of the products and
+	// versions, see --normalize.
+	Normalize []NormalizeRule
+	// ExcludeProducts leaves out the ports of these products, compared
+	// case-insensitively, and ProductPattern keeps only the ports whose
+	// product matches it.
+	ExcludeProducts []string
+	ProductPattern  *regexp.Regexp
+	// TLSOnly keeps only the ports speaking TLS and PlaintextOnly only the
+	// cleartext ones, see isTLSPort.
+	TLSOnly       bool
+	PlaintextOnly bool
+	// MinHosts hides the rows of fewer hosts, and OnlySingletons the rows of
+	// more than one host.
+	MinHosts       int
+	OnlySingletons bool
+	// PreferIPv6 identifies dual-stack hosts by their IPv6 address.
+	PreferIPv6 bool
+	// Columns are the names of the columns to output, in order. Empty uses the
+	// default columns of each output format.
+	Columns []string
+	// GroupBy is the --group-by strategy aggregating ports into rows; empty
+	// groups them by version.
+	GroupBy string
+	// SortByCount orders rows by their number of hosts, most first, keeping the
+	// grouping order between rows of the same count.
+	SortByCount bool
+	// Resolver looks up the hostnames the scans have none for, see --resolve
+	// and --hosts-file; nil leaves them empty.
+	Resolver *hostResolver
+	// GeoIP annotates hosts with their country and autonomous system, see
+	// --geoip; nil leaves them empty.
+	GeoIP *geoDB
+	// Inventory tags hosts with their owner, environment and criticality, see
+	// --inventory; nil leaves them empty.
+	Inventory *inventory
+	// Screenshots links the web servers to their screenshots, see
+	// --screenshots; nil links none.
+	Screenshots *screenshotIndex
+	// Redact sanitizes the reports to be shared, see --redact; nil leaves
+	// them as they are.
+	Redact *redactor
+	// Match selects the ports to report instead of ServiceName when set, for
+	// reports spanning several services such as web.
+	Match func(port *NmapPort) bool
+}
+
+// matchesProduct reports whether the product of port passes the
+// --exclude-product and --product-regex filters of opts.
+func (opts TableOptions) matchesProduct(port *NmapPort) bool {
+	for _, product := range opts.ExcludeProducts {
+		if strings.EqualFold(port.Service.Product, product) {
+			return false
 		}
+	}
+	return opts.ProductPattern == nil || opts.ProductPattern.MatchString(port.Service.Product)
+}
 
-		var nmapRun Nmaprun
-		err = xml.Unmarshal(fileData, &nmapRun)
-		if err != nil {
-			fmt.Printf("Failed to unmarshal xml data in %s\nError: %v\n", filePath, err)
+// matchesTransport reports whether port passes the --tls-only and
+// --plaintext-only filters of opts.
+func (opts TableOptions) matchesTransport(port *NmapPort) bool {
+	switch {
+	case opts.TLSOnly:
+		return isTLSPort(port)
+	case opts.PlaintextOnly:
+		return !isTLSPort(port)
+	}
+	return true
+}
+
+// matches reports whether port is one of the ports selected by opts.
+func (opts TableOptions) matches(port *NmapPort) bool {
+	if opts.Match != nil {
+		return opts.Match(port)
+	}
+	return matchesService(port, opts.ServiceName)
+}
+
+// parseStates splits a comma separated list of port states into a set.
+func parseStates(value string) map[string]bool {
+	states := make(map[string]bool)
+	for _, state := range strings.Split(value, ",") {
+		state = strings.TrimSpace(state)
+		if state != "" {
+			states[state] = true
+		}
+	}
+	return states
+}
+
+// ParseError describes an input file that could not be read or parsed.
+type ParseError struct {
+	File string `json:"file"`
+	// Offset is the byte offset in File at which parsing stopped.
+	Offset int64  `json:"offset"`
+	Reason string `json:"reason"`
+	// Recovered is the number of complete hosts salvaged from a truncated file.
+	Recovered int `json:"recovered,omitempty"`
+}
+
+func (e *ParseError) Error() string {
+	msg := fmt.Sprintf("Failed to parse %s at byte %d\nError: %s", e.File, e.Offset, e.Reason)
+	if e.Recovered > 0 {
+		msg += fmt.Sprintf("\nRecovered %d complete host(s)", e.Recovered)
+	}
+	return msg
+}
+
+// parseInput reads and unmarshals the scans of a single input file, or of
+// standard input when filePath is stdinPath: an nmap XML file or the output
+// of rustscan, naabu or zmap, optionally gzipped, or a zip or tar archive of
+// nmap XML files. The complete hosts of truncated
+// scans are returned along with their error.
+func parseInput(filePath string) ([]parsedScan, []ParseError) {
+	documents, err := readInput(filePath)
+	if err != nil {
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			parseErr = &ParseError{File: filePath, Reason: err.Error()}
+		}
+		return nil, []ParseError{*parseErr}
+	}
+	var scans []parsedScan
+	var parseErrors []ParseError
+	for _, document := range documents {
+		if document.err != nil {
+			parseErrors = append(parseErrors, ParseError{File: document.name, Reason: document.err.Error()})
 			continue
 		}
+		nmapRun, err := parseNmapData(document.name, document.data)
+		if err != nil {
+			parseErrors = append(parseErrors, *err)
+			if err.Recovered == 0 {
+				continue
+			}
+		}
+		setSource(&nmapRun, document.name)
+		scans = append(scans, parsedScan{run: nmapRun, file: document.name, digest: sha256.Sum256(document.data)})
+	}
+	return scans, parseErrors
+}
+
+// parseNmapData unmarshals the nmap XML document fileData read from filePath,
+// or converts the output of a port discovery tool, see discoveryFormat.
+func parseNmapData(filePath string, fileData []byte) (Nmaprun, *ParseError) {
+	if format := discoveryFormat(fileData); format != "" {
+		return parseDiscoveryData(filePath, fileData, format)
+	}
+	var nmapRun Nmaprun
+	decoder := xml.NewDecoder(bytes.NewReader(fileData))
+	if err := decoder.Decode(&nmapRun); err != nil {
+		parseErr := &ParseError{File: filePath, Offset: decoder.InputOffset(), Reason: err.Error()}
+		if errors.Is(err, io.EOF) {
+			parseErr.Reason = "no XML data"
+		} else if isTruncated(err) {
+			if recovered, ok := recoverHosts(fileData); ok {
+				parseErr.Recovered = len(recovered.Host)
+				return recovered, parseErr
+			}
+		}
+		return Nmaprun{}, parseErr
+	}
+	return nmapRun, nil
+}
+
+// ParseNmapFiles reads and unmarshals every nmap XML file in nmapFiles.
+// Files that cannot be read or parsed are reported, skipped and returned in
+// the ParseError slice; the recovered hosts of truncated files are kept.
+// Exact duplicates of an earlier file are skipped.
+func ParseNmapFiles(nmapFiles []string) ([]Nmaprun, []ParseError) {
+	var scans []parsedScan
+	var parseErrors []ParseError
+	start := time.Now()
+	bar := newProgress(len(nmapFiles))
+	cachedFiles := 0
+	for _, filePath := range nmapFiles {
+		outputManifest.input(filePath)
+		var fileScans []parsedScan
+		var fileErrors []ParseError
+		if inputCache != nil {
+			var cached bool
+			fileScans, fileErrors, cached = inputCache.parseInput(filePath)
+			if cached {
+				cachedFiles++
+			}
+		} else {
+			fileScans, fileErrors = parseInput(filePath)
+		}
+		if len(fileErrors) > 0 {
+			bar.clear()
+		}
+		for i := range fileErrors {
+			logParseError(&fileErrors[i])
+		}
+		for _, scan := range fileScans {
+			logger.Debug("parsed scan", "file", scan.file, "hosts", len(scan.run.Host))
+		}
+		scans = append(scans, fileScans...)
+		parseErrors = append(parseErrors, fileErrors...)
+		bar.step()
+	}
+	bar.clear()
+	runs := uniqueRuns(scans)
+	if inputCache != nil {
+		logger.Info("read cached scans", "files", cachedFiles, "parsed", len(nmapFiles)-cachedFiles)
+	}
+	logger.Info("parsed input files", "files", len(nmapFiles), "scans", len(runs), "errors", len(parseErrors), "duration", time.Since(start).Round(time.Millisecond))
+	return runs, parseErrors
+}
+
+// hostAddress returns the address used to identify host in the report: its
+// IPv4 address, or its IPv6 address when preferIPv6 is set or it has no IPv4
+// address. A MAC address is only used when the host has no IP address.
+func hostAddress(host *NmapHost, preferIPv6 bool) string {
+	addrTypes := []string{"ipv4", "ipv6"}
+	if preferIPv6 {
+		addrTypes = []string{"ipv6", "ipv4"}
+	}
+	for _, addrType := range addrTypes {
+		for _, address := range host.Address {
+			if address.Addrtype == addrType {
+				return address.Addr
+			}
+		}
+	}
+	if len(host.Address) > 0 {
+		return host.Address[0].Addr
+	}
+	return ""
+}
 
-		for _, port := range nmapRun.Host.Ports.Port {
-			if port.State.State == "filtered" {
+// hostMAC returns the MAC address of host and the vendor nmap looked up for it.
+// Both are empty for hosts that were not on the local network of the scanner.
+func hostMAC(host *NmapHost) (string, string) {
+	for _, address := range host.Address {
+		if address.Addrtype == "mac" {
+			return address.Addr, address.Vendor
+		}
+	}
+	return "", ""
+}
+
+// portOwner returns the user running the service of port: the owner found by
+// nmap's ident scan or, on newer versions of nmap, by the auth-owners script
+// querying the host's identd. Both need an ident server, mostly found on
+// legacy Unix hosts.
+func portOwner(port *NmapPort) string {
+	if port.Owner.Name != "" {
+		return port.Owner.Name
+	}
+	for _, script := range port.Script {
+		if script.ID == "auth-owners" {
+			return strings.TrimSpace(script.Output)
+		}
+	}
+	return ""
+}
+
+// GenerateTableData parses nmapFiles and builds the report for the service in
+// opts, listing the files that failed to parse in its Errors.
+func GenerateTableData(nmapFiles []string, opts TableOptions) ReportData {
+	runs, parseErrors := ParseNmapFiles(nmapFiles)
+	tableData := BuildTableData(runs, opts)
+	tableData.Errors = parseErrors
+	return tableData
+}
+
+// BuildTableData aggregates the ports of runs matching opts into report rows.
+// Detections below opts.MinConf are kept apart in the Unverified rows.
+func BuildTableData(runs []Nmaprun, opts TableOptions) ReportData {
+	serviceName := opts.ServiceName
+	strategy, ok := groupings[opts.GroupBy]
+	if !ok {
+		strategy = groupings["version"]
+	}
+	versionMap := make(map[groupKey][]groupedPort)
+	unverifiedMap := make(map[groupKey][]groupedPort)
+	pathMap := make(map[routerKey][]HostPort)
+	summary := ScanSummary{}
+
+	for i := range runs {
+		updateSummary(&summary, &runs[i])
+	}
+	if opts.ExcludeDown {
+		summary.HostsScanned -= summary.HostsDown
+		summary.HostsDown = 0
+	}
+
+	hosts := opts.mergeHosts(runs)
+	for i := range hosts {
+		host := &hosts[i]
+		if opts.excluded(hostAddress(host, opts.PreferIPv6)) || (opts.ExcludeDown && isDown(host)) {
+			continue
+		}
+		for j, port := range host.Ports.Port {
+			if !opts.States[port.State.State] || (len(opts.Reasons) > 0 && !opts.Reasons[port.State.Reason]) {
 				continue
 			}
-			if port.Service.Name == serviceName {
-				var hostIP string
-				if len(nmapRun.Host.Address) > 0 {
-					hostIP = nmapRun.Host.Address[0].Addr
+			matched, unknown := opts.matches(&port), ""
+			if !matched && opts.IncludeUnknown && opts.ServiceName != "" {
+				unknown = unknownService(&port)
+				matched = unknown != ""
+			}
+			if matched && opts.matchesProduct(&port) && opts.matchesTransport(&port) {
+				hostPort := HostPort{Addr: hostAddress(host, opts.PreferIPv6), Port: port.Portid, Protocol: port.Protocol, CPE: port.Service.Cpe, ExtraInfo: port.Service.Extrainfo, Tunnel: port.Service.Tunnel, Ident: portOwner(&port),
+					Reason: port.State.Reason, ReasonTTL: port.State.ReasonTtl, host: host, port: &host.Ports.Port[j]}
+				if len(host.Hostnames.Hostname) > 0 {
+					hostPort.Hostname = host.Hostnames.Hostname[0].Name
 				}
-				portID := port.Portid
-				serviceVersion := fmt.Sprintf("%s %s", port.Service.Product, port.Service.Version)
-
-				hostPort := hostIP + ":" + portID
-				versionMap[serviceVersion] = append(versionMap[serviceVersion], hostPort)
+				hostPort.Details = scriptDetails(host, &port)
+				if opts.GeoIP != nil {
+					geo := opts.GeoIP.lookup(hostPort.Addr)
+					hostPort.Country, hostPort.ASN = geo.country, geo.asn
+				}
+				if opts.Inventory != nil {
+					if asset, ok := opts.Inventory.lookup(hostPort.Addr); ok {
+						hostPort.Owner, hostPort.Environment, hostPort.Criticality = asset.Owner, asset.Environment, asset.Criticality
+					}
+				}
+				if isWebPort(&port) {
+					hostPort.URL = webURL(&hostPort, &port)
+					if opts.Screenshots != nil {
+						hostPort.Screenshot = opts.Screenshots.lookup(&hostPort)
+					}
+				}
+				if opts.IncludeOS {
+					hostPort.OS = hostOS(host)
+				}
+				if opts.IncludeMAC {
+					hostPort.MAC, hostPort.Vendor = hostMAC(host)
+				}
+				if opts.IncludeSource {
+					hostPort.Source = port.Source
+				}
+				if opts.Merge == mergeAll {
+					hostPort.Seen = port.Seen
+				}
+				logger.Debug("matched port", "host", hostPort.Addr, "port", port.Portid, "protocol", port.Protocol,
+					"product", port.Service.Product, "version", port.Service.Version, "conf", port.Service.Conf)
+				key := strategy.key(&port, &hostPort)
+				if unknown != "" {
+					key = groupKey{Service: unknown, Port: port.Portid, Protocol: port.Protocol}
+				}
+				grouped := groupedPort{hostPort: hostPort, protocol: port.Protocol, product: port.Service.Product, version: port.Service.Version, unknown: unknown}
+				if atoi(port.Service.Conf) < opts.MinConf {
+					unverifiedMap[key] = append(unverifiedMap[key], grouped)
+				} else {
+					versionMap[key] = append(versionMap[key], grouped)
+				}
+				if opts.NetworkPath {
+					router := lastHopRouter(host)
+					pathMap[router] = append(pathMap[router], hostPort)
+				}
+				summary.MatchingPorts++
 			}
 		}
 	}
 
-	var data [][]string
-	for version, hosts := range versionMap {
-		sort.Strings(hosts)
-		hostsJoined := strings.Join(hosts, "<br>")
-		data = append(data, []string{hostsJoined, serviceName, version})
+	data := buildRows(versionMap, strategy, opts)
+	unverified := buildRows(unverifiedMap, strategy, opts)
+	data = filterRowHosts(data, opts)
+	unverified = filterRowHosts(unverified, opts)
+	for i := range data {
+		data[i].Verified = true
+	}
+	applyRiskRules(data, opts.RiskRules)
+	applyRiskRules(unverified, opts.RiskRules)
+	now := time.Now()
+	applyEOL(data, opts.EOLData, now)
+	applyEOL(unverified, opts.EOLData, now)
+	applyOT(data)
+	applyOT(unverified)
+	versions := make(map[productVersion]struct{})
+	for _, row := range data {
+		for _, pv := range row.versions {
+			versions[pv] = struct{}{}
+		}
+	}
+	summary.DistinctVersions = len(versions)
+	var down []DownHost
+	if opts.ListDown {
+		down = downHosts(hosts, opts)
+	}
+
+	return ReportData{
+		Service:         serviceName,
+		MinConf:         opts.MinConf,
+		ShowOS:          opts.IncludeOS,
+		ShowMAC:         opts.IncludeMAC,
+		ShowHostname:    opts.Resolver != nil,
+		ShowGeo:         opts.GeoIP != nil,
+		ShowAsset:       opts.Inventory != nil,
+		ShowScreenshots: opts.Screenshots != nil,
+		screenshots:     opts.Screenshots,
+		ShowSource:      opts.IncludeSource,
+		ShowExtraInfo:   opts.IncludeExtraInfo || opts.GroupBy == "banner",
+		ShowIdent:       opts.IncludeIdent,
+		ShowReason:      opts.IncludeReason,
+		ShowSeen:        opts.Merge == mergeAll,
+		Summary:         summary,
+		Rows:            data,
+		ShowRisk:        len(opts.RiskRules) > 0,
+		ShowEOL:         len(opts.EOLData) > 0,
+		Unverified:      unverified,
+		DownHosts:       down,
+		DownExcluded:    opts.ExcludeDown,
+		Paths:           buildPathRows(pathMap),
+		Scans:           scanSources(runs),
+		Columns:         selectColumns(opts.Columns),
 	}
+}
 
-	// Sort the data slice by version
+// sortRows orders rows by product and then numerically by version, oldest
+// first unless newestFirst is set. Rows of the same version are ordered by
+// protocol.
+func sortRows(data []ReportRow, newestFirst bool) {
 	sort.Slice(data, func(i, j int) bool {
-		return data[i][2] < data[j][2]
+		if data[i].Product != data[j].Product {
+			return data[i].Product < data[j].Product
+		}
+		if cmp := nmap.CompareVersions(data[i].ProductVersion, data[j].ProductVersion); cmp != 0 {
+			if newestFirst {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		if data[i].Protocol != data[j].Protocol {
+			return data[i].Protocol < data[j].Protocol
+		}
+		if data[i].Service != data[j].Service {
+			return data[i].Service < data[j].Service
+		}
+		// Rows of --group-by banner differ in their extra information only.
+		return data[i].Hosts[0].ExtraInfo < data[j].Hosts[0].ExtraInfo
 	})
-
-	return data
 }
 
 // FilePathWalkDir walks through the directory specified by dirPath and returns a slice of file paths
 // that match the given file extension.
 func FilePathWalkDir(dirPath, extension string) ([]string, error) {
-	var files []string
-	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err // Return the error to stop the walk.
-		}
-		if !info.IsDir() && strings.HasSuffix(info.Name(), extension) {
-			absPath, err := filepath.Abs(path)
-			if err != nil {
-				return err // Return the error to stop the walk.
-			}
-			files = append(files, absPath)
-		}
-		return nil
-	})
-	return files, err
+	return walkDir(dirPath, WalkOptions{Extensions: []string{extension}})
 }
 
 // resolveAbsPath ...
@@ -106,222 +790,345 @@ func resolveAbsPath(path string) (string, error) {
 	return path, nil
 }
 
-//go:embed template.html
+//go:embed template.html path.html heatmap.html serve.html host.html tables.js theme.js themes.css branding.css
 var templateFS embed.FS
 
+// script returns the embedded JavaScript file name for inclusion in a
+// <script> element, so reports work offline without any CDN.
+func script(name string) (template.JS, error) {
+	data, err := templateFS.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(data), nil
+}
+
+// stylesheet returns the embedded CSS file name for inclusion in a <style>
+// element.
+func stylesheet(name string) (template.CSS, error) {
+	data, err := templateFS.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	return template.CSS(data), nil
+}
+
 func main() {
-	// Define command-line flags
-	serviceName := flag.String("service", "ms-sql-s", "The service name to filter by")
-	nmapDir := flag.String("nmap-dir", "", "The directory containing Nmap XML files")
-	flag.Parse()
+	if err := execute(newRootCmd()); err != nil {
+		logCommandError(err)
+		os.Exit(exitCode(err))
+	}
+}
 
-	// Check if nmap-dir is provided
-	if *nmapDir == "" {
-		log.Fatal("Please provide the Nmap directory using the -nmap-dir flag")
+// runReport implements the report command: it renders the table for a single
+// service as HTML, or in one of the plain output formats.
+func runReport(f *reportFlags, args []string) error {
+	nmapFiles, err := f.input.nmapFiles(args)
+	if err != nil {
+		return err
+	}
+	opts, err := f.table.options()
+	if err != nil {
+		return err
+	}
+	opts.NetworkPath = f.view == "network-path"
+
+	templateName := "template.html"
+	switch f.view {
+	case "table":
+	case "network-path":
+		templateName = "path.html"
+	case "heatmap":
+		templateName = "heatmap.html"
+		if f.heatmapPrefix != 24 && f.heatmapPrefix != 16 {
+			return fmt.Errorf("unsupported --heatmap-prefix: %d (use 24 or 16)", f.heatmapPrefix)
+		}
+		// The grid is laid out by the octets --redact masks.
+		if opts.Redact != nil {
+			return errors.New("--redact cannot be used with the heatmap view")
+		}
+	default:
+		return fmt.Errorf("unsupported view: %s", f.view)
+	}
+	renderer, err := lookupRenderer(f.outputFormat)
+	if err != nil {
+		return err
+	}
+	services := []string{opts.ServiceName}
+	// allServices is set by --service all, a section for every service found.
+	allServices := false
+	if _, ok := renderer.(multiRenderer); ok {
+		// Documents have a section for each of a comma separated list of services.
+		services = splitList(opts.ServiceName)
+		allServices = len(services) == 1 && services[0] == "all"
+		if f.view != "table" && (f.outputFormat != "html" || len(services) > 1 || allServices) {
+			return fmt.Errorf("the %s view cannot be written as a multi-service %s report", f.view, f.outputFormat)
+		}
+	}
+	// Documents are written to files, other formats to stdout.
+	document, isDocument := renderer.(documentRenderer)
+	defaultName := fmt.Sprintf("%s.txt", opts.ServiceName)
+	if isDocument {
+		defaultName = fmt.Sprintf("%s.%s", strings.Join(services, "_"), document.Extension())
+	}
+	switch f.theme {
+	case "light", "dark", "print":
+	default:
+		return fmt.Errorf("unsupported theme: %s", f.theme)
+	}
+	if err := checkProtocolLayout(f.protocolLayout); err != nil {
+		return err
+	}
+	if f.splitBy != "" {
+		if _, ok := splitKeys[f.splitBy]; !ok {
+			return fmt.Errorf("unsupported --split-by: %s (available: product, version)", f.splitBy)
+		}
+		if f.view != "table" || !isDocument {
+			return errors.New("--split-by requires the table view and a document output format such as html, pdf or docx")
+		}
+	}
+	if f.outputFormat == "html" {
+		tmpl, err := loadTemplate(templateName, f.template)
+		if err != nil {
+			return fmt.Errorf("Error parsing template: %w", err)
+		}
+		renderer = htmlRenderer{tmpl: tmpl}
 	}
 
-	absNmapDir, err := resolveAbsPath(*nmapDir)
+	var notify *notifier
+	if f.notify != "" {
+		if notify, err = newNotifier(f.notify); err != nil {
+			return fmt.Errorf("invalid --notify URL: %w", err)
+		}
+	}
+	profile, err := parsePortProfile(f.expectPorts)
+	if err != nil {
+		return fmt.Errorf("invalid --expect-ports: %w", err)
+	}
+	lang := strings.ToLower(strings.TrimSpace(f.lang))
+	if lang == "" {
+		lang = defaultLang
+	}
+	catalog, err := loadMessages(lang)
+	if err != nil {
+		return fmt.Errorf("invalid --lang: %w", err)
+	}
+	branding, err := loadBranding(f.title, f.client, f.logo, f.classification)
 	if err != nil {
-		log.Fatalf("invalid path: %s", err.Error())
+		return fmt.Errorf("invalid --logo: %w", err)
+	}
+	var severity *rowSeverity
+	if f.colorRows || f.severityHosts != "" {
+		if severity, err = parseRowSeverity(f.severityHosts); err != nil {
+			return fmt.Errorf("invalid --severity-hosts: %w", err)
+		}
 	}
 
-	nmapFiles, err := FilePathWalkDir(absNmapDir, ".xml")
+	outputFilename, err := f.output.filename(defaultName)
 	if err != nil {
-		log.Fatalf("Error getting files\nError: %+v\n", err)
+		return fmt.Errorf("invalid output path: %w", err)
+	}
+	if isDocument {
+		opts.Screenshots.linkFrom(outputFilename)
+	}
+	// Watch mode may regenerate the files that were written by this run.
+	written := make(map[string]bool)
+	writeFile := func(filename string, reports []ReportData) error {
+		overwrite := f.output.force || written[filename]
+		err := writeRendered(filename, overwrite, f.outputFormat, renderer, reports)
+		if err == nil {
+			written[filename] = true
+		}
+		return err
 	}
-	// ms-sql-s
-	tableData := GenerateTableData(nmapFiles, *serviceName)
+	// reports are the reports last written, checked for matches at exit.
+	var reports []ReportData
+	// notified are the runs of the last notification, to find the new hosts
+	// and ports of the next.
+	var notified []Nmaprun
+	sendNotification := func(runs []Nmaprun) error {
+		var newHosts, newPorts []string
+		if notified != nil {
+			for _, service := range services {
+				serviceOpts := opts
+				serviceOpts.ServiceName = service
+				hosts, ports := scanNews(notified, DiffScans(notified, runs, serviceOpts), serviceOpts)
+				newHosts = append(newHosts, hosts...)
+				newPorts = append(newPorts, ports...)
+			}
+			if len(newPorts) == 0 {
+				return nil
+			}
+		}
+		report := ""
+		if isDocument {
+			report = outputFilename
+		}
+		if err := notify.send(reportNotification(services, reports, report, newHosts, newPorts)); err != nil {
+			return err
+		}
+		notified = runs
+		return nil
+	}
+	writeReports := func(runs []Nmaprun) error {
+		if !isDocument {
+			return render(renderer, reports, os.Stdout)
+		}
+		if f.splitBy == "" {
+			return writeFile(outputFilename, reports)
+		}
+		parts := splitReports(reports, f.splitBy)
+		for _, part := range parts {
+			filename := splitPath(outputFilename, part.key)
+			if err := writeFile(filename, part.reports); err != nil {
+				return err
+			}
+		}
+		if len(parts) == 0 {
+			statusf("No %s hosts found, no files written\n", strings.Join(services, ", "))
+		}
+		return nil
+	}
+	writeOutput := func(runs []Nmaprun, parseErrors []ParseError) error {
+		if profile != nil {
+			profile.warn(runs, opts)
+		}
+		if allServices {
+			if services = reportServices(runs, opts); len(services) == 0 {
+				return errors.New("no services found for --service all")
+			}
+		}
+		reports = make([]ReportData, len(services))
+		for i, service := range services {
+			serviceOpts := opts
+			serviceOpts.ServiceName = service
+			reports[i] = BuildTableData(runs, serviceOpts)
+			reports[i].Errors = parseErrors
+			reports[i].Theme = f.theme
+			reports[i].ProtocolLayout = f.protocolLayout
+			if f.view == "heatmap" {
+				reports[i].Heatmap = BuildHeatmap(reports[i], f.heatmapPrefix)
+			}
+			reports[i].Engagement = f.engagement
+			reports[i].Severity = severity
+			reports[i].Lang, reports[i].messages = lang, catalog
+			reports[i].Branding = branding
+		}
+		if f.top > 0 {
+			top := CountVersions(runs, opts, f.top)
+			for i := range reports {
+				reports[i].Top = top
+			}
+		}
+		ot := FindOTExposures(runs, opts)
+		for i := range reports {
+			reports[i].OT = ot
+			if f.outputFormat == "html" && f.view == "table" {
+				charts := BuildCharts(runs, opts, reports[i])
+				reports[i].Charts = &charts
+			}
+		}
+		if f.quickWins {
+			wins := FindQuickWins(runs, opts)
+			for i := range reports {
+				reports[i].QuickWins = wins
+			}
+		}
+		for i := range reports {
+			reports[i] = opts.Redact.report(reports[i])
+		}
+		if err := writeReports(runs); err != nil {
+			return err
+		}
+		if notify != nil {
+			return sendNotification(runs)
+		}
+		return nil
+	}
+
+	if f.input.watch {
+		watchDir, err := f.input.watchDir(args)
+		if err != nil {
+			return err
+		}
+		return watchNmapDir(watchDir, f.input.walkOptions(), nmapFiles, func(runs []Nmaprun, parseErrors []ParseError) {
+			if err := writeOutput(runs, parseErrors); err != nil {
+				logger.Error("could not write report", "file", outputFilename, "err", err)
+			}
+		})
+	}
+	runs, parseErrors := ParseNmapFiles(nmapFiles)
+	if err := checkParseErrors(parseErrors); err != nil {
+		return err
+	}
+	if f.parsed != nil {
+		f.parsed(runs)
+	}
+	if err := writeOutput(runs, parseErrors); err != nil {
+		return err
+	}
+	return checkMatches(reports...)
+}
 
-	tmpl, err := template.New("template.html").Funcs(template.FuncMap{
-		"safe": func(s string) template.HTML {
-			return template.HTML(s)
-		},
-	}).ParseFS(templateFS, "template.html")
+// reportServices returns the services of --service all: the sorted, distinct
+// services of the ports of runs that are not excluded.
+func reportServices(runs []Nmaprun, opts TableOptions) []string {
+	var hosts []NmapHost
+	for _, host := range opts.mergeHosts(runs) {
+		if !opts.excluded(hostAddress(&host, opts.PreferIPv6)) {
+			hosts = append(hosts, host)
+		}
+	}
+	return ServiceNames(hosts, opts.States)
+}
+
+// loadTemplate parses the custom template at path, or the embedded template
+// name when path is empty.
+func loadTemplate(name, path string) (*template.Template, error) {
+	if path == "" {
+		return template.New(name).Funcs(templateFuncs).ParseFS(templateFS, name)
+	}
+	path, err := resolveAbsPath(path)
 	if err != nil {
-		log.Fatalf("Error parsing template: %v", err)
+		return nil, err
 	}
+	outputManifest.input(path)
+	return template.New(filepath.Base(path)).Funcs(templateFuncs).ParseFiles(path)
+}
 
-	outputFilename := fmt.Sprintf("%s.html", *serviceName)
-	outputFile, err := os.Create(outputFilename)
+// outputPath resolves the output file path from the -o and -output-dir flags,
+// falling back to defaultName, and creates the containing directory.
+func outputPath(output, outputDir, defaultName string) (string, error) {
+	if output == "" {
+		output = defaultName
+	}
+	if outputDir != "" && !filepath.IsAbs(output) && !strings.HasPrefix(output, "~") {
+		output = filepath.Join(outputDir, output)
+	}
+	output, err := resolveAbsPath(output)
 	if err != nil {
-		fmt.Println("Error creating output file:", err)
-		return
+		return output, err
 	}
-	defer outputFile.Close()
+	if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+		return output, err
+	}
+	return output, nil
+}
 
-	err = tmpl.Execute(outputFile, tableData)
+// createOutputFile creates outputFilename, refusing to replace an existing file
+// unless overwrite is set.
+func createOutputFile(outputFilename string, overwrite bool) (*os.File, error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+	outputFile, err := os.OpenFile(outputFilename, flags, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return nil, fmt.Errorf("Error creating output file: %s already exists (use --force to overwrite)", outputFilename)
+	}
 	if err != nil {
-		fmt.Println("Error executing template:", err)
-		return
-	}
-
-	fmt.Printf("HTML table written to %s\n", outputFilename)
-}
-
-type Nmaprun struct {
-	XMLName          xml.Name `xml:"nmaprun"`
-	Text             string   `xml:",chardata"`
-	Scanner          string   `xml:"scanner,attr"`
-	Args             string   `xml:"args,attr"`
-	Start            string   `xml:"start,attr"`
-	Startstr         string   `xml:"startstr,attr"`
-	Version          string   `xml:"version,attr"`
-	Xmloutputversion string   `xml:"xmloutputversion,attr"`
-	Scaninfo         struct {
-		Text        string `xml:",chardata"`
-		Type        string `xml:"type,attr"`
-		Protocol    string `xml:"protocol,attr"`
-		Numservices string `xml:"numservices,attr"`
-		Services    string `xml:"services,attr"`
-	} `xml:"scaninfo"`
-	Verbose struct {
-		Text  string `xml:",chardata"`
-		Level string `xml:"level,attr"`
-	} `xml:"verbose"`
-	Debugging struct {
-		Text  string `xml:",chardata"`
-		Level string `xml:"level,attr"`
-	} `xml:"debugging"`
-	Taskbegin []struct {
-		Text string `xml:",chardata"`
-		Task string `xml:"task,attr"`
-		Time string `xml:"time,attr"`
-	} `xml:"taskbegin"`
-	Taskend []struct {
-		Text      string `xml:",chardata"`
-		Task      string `xml:"task,attr"`
-		Time      string `xml:"time,attr"`
-		Extrainfo string `xml:"extrainfo,attr"`
-	} `xml:"taskend"`
-	Hosthint struct {
-		Text   string `xml:",chardata"`
-		Status struct {
-			Text      string `xml:",chardata"`
-			State     string `xml:"state,attr"`
-			Reason    string `xml:"reason,attr"`
-			ReasonTtl string `xml:"reason_ttl,attr"`
-		} `xml:"status"`
-		Address []struct {
-			Text     string `xml:",chardata"`
-			Addr     string `xml:"addr,attr"`
-			Addrtype string `xml:"addrtype,attr"`
-			Vendor   string `xml:"vendor,attr"`
-		} `xml:"address"`
-		Hostnames string `xml:"hostnames"`
-	} `xml:"hosthint"`
-	Taskprogress []struct {
-		Text      string `xml:",chardata"`
-		Task      string `xml:"task,attr"`
-		Time      string `xml:"time,attr"`
-		Percent   string `xml:"percent,attr"`
-		Remaining string `xml:"remaining,attr"`
-		Etc       string `xml:"etc,attr"`
-	} `xml:"taskprogress"`
-	Host struct {
-		Text      string `xml:",chardata"`
-		Starttime string `xml:"starttime,attr"`
-		Endtime   string `xml:"endtime,attr"`
-		Status    struct {
-			Text      string `xml:",chardata"`
-			State     string `xml:"state,attr"`
-			Reason    string `xml:"reason,attr"`
-			ReasonTtl string `xml:"reason_ttl,attr"`
-		} `xml:"status"`
-		Address []struct {
-			Text     string `xml:",chardata"`
-			Addr     string `xml:"addr,attr"`
-			Addrtype string `xml:"addrtype,attr"`
-			Vendor   string `xml:"vendor,attr"`
-		} `xml:"address"`
-		Hostnames string `xml:"hostnames"`
-		Ports     struct {
-			Text string `xml:",chardata"`
-			Port []struct {
-				Text     string `xml:",chardata"`
-				Protocol string `xml:"protocol,attr"`
-				Portid   string `xml:"portid,attr"`
-				State    struct {
-					Text      string `xml:",chardata"`
-					State     string `xml:"state,attr"`
-					Reason    string `xml:"reason,attr"`
-					ReasonTtl string `xml:"reason_ttl,attr"`
-				} `xml:"state"`
-				Service struct {
-					Text      string `xml:",chardata"`
-					Name      string `xml:"name,attr"`
-					Product   string `xml:"product,attr"`
-					Ostype    string `xml:"ostype,attr"`
-					Method    string `xml:"method,attr"`
-					Conf      string `xml:"conf,attr"`
-					Version   string `xml:"version,attr"`
-					Extrainfo string `xml:"extrainfo,attr"`
-					Cpe       string `xml:"cpe"`
-				} `xml:"service"`
-				Script []struct {
-					Text   string `xml:",chardata"`
-					ID     string `xml:"id,attr"`
-					Output string `xml:"output,attr"`
-					Elem   []struct {
-						Text string `xml:",chardata"`
-						Key  string `xml:"key,attr"`
-					} `xml:"elem"`
-					Table []struct {
-						Text string `xml:",chardata"`
-						Key  string `xml:"key,attr"`
-						Elem []struct {
-							Text string `xml:",chardata"`
-							Key  string `xml:"key,attr"`
-						} `xml:"elem"`
-						Table []struct {
-							Text string `xml:",chardata"`
-							Elem []struct {
-								Text string `xml:",chardata"`
-								Key  string `xml:"key,attr"`
-							} `xml:"elem"`
-						} `xml:"table"`
-					} `xml:"table"`
-				} `xml:"script"`
-			} `xml:"port"`
-		} `xml:"ports"`
-		Hostscript struct {
-			Text   string `xml:",chardata"`
-			Script []struct {
-				Text   string `xml:",chardata"`
-				ID     string `xml:"id,attr"`
-				Output string `xml:"output,attr"`
-				Elem   []struct {
-					Text string `xml:",chardata"`
-					Key  string `xml:"key,attr"`
-				} `xml:"elem"`
-				Table struct {
-					Text string `xml:",chardata"`
-					Key  string `xml:"key,attr"`
-					Elem string `xml:"elem"`
-				} `xml:"table"`
-			} `xml:"script"`
-		} `xml:"hostscript"`
-		Times struct {
-			Text   string `xml:",chardata"`
-			Srtt   string `xml:"srtt,attr"`
-			Rttvar string `xml:"rttvar,attr"`
-			To     string `xml:"to,attr"`
-		} `xml:"times"`
-	} `xml:"host"`
-	Runstats struct {
-		Text     string `xml:",chardata"`
-		Finished struct {
-			Text    string `xml:",chardata"`
-			Time    string `xml:"time,attr"`
-			Timestr string `xml:"timestr,attr"`
-			Summary string `xml:"summary,attr"`
-			Elapsed string `xml:"elapsed,attr"`
-			Exit    string `xml:"exit,attr"`
-		} `xml:"finished"`
-		Hosts struct {
-			Text  string `xml:",chardata"`
-			Up    string `xml:"up,attr"`
-			Down  string `xml:"down,attr"`
-			Total string `xml:"total,attr"`
-		} `xml:"hosts"`
-	} `xml:"runstats"`
+		return nil, fmt.Errorf("Error creating output file: %w", err)
+	}
+	outputManifest.output(outputFilename)
+	return outputFile, nil
 }
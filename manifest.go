@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// manifestPath is the --manifest flag shared by every command.
+var manifestPath string
+
+// outputManifest records the files read and written by the command, for the
+// --manifest written when it finishes; nil records nothing.
+var outputManifest *manifest
+
+// manifest is the chain-of-custody record of a run: the input files it read
+// and the output files it wrote, with their SHA-256 digests.
+type manifest struct {
+	path    string
+	inputs  []string
+	outputs []string
+	// seen are the files recorded, by kind and path: a file may be both read
+	// and written, such as the key of anonymize.
+	seen map[string]bool
+}
+
+// ManifestFile is a file of the manifest and its digest.
+type ManifestFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// ManifestDocument is the JSON form of a manifest, written when its file ends in
+// .json.
+type ManifestDocument struct {
+	Generated time.Time      `json:"generated"`
+	Inputs    []ManifestFile `json:"inputs"`
+	Outputs   []ManifestFile `json:"outputs"`
+}
+
+// newManifest returns the manifest written to path, or nil when path is
+// empty.
+func newManifest(path string) (*manifest, error) {
+	if path == "" {
+		return nil, nil
+	}
+	path, err := resolveAbsPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return &manifest{path: path, seen: make(map[string]bool)}, nil
+}
+
+// record adds path to files, the files of kind, once.
+func (m *manifest) record(files *[]string, kind, path string) {
+	if path == "" || path == stdinPath {
+		return
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if m.seen[kind+":"+path] || path == m.path {
+		return
+	}
+	m.seen[kind+":"+path] = true
+	*files = append(*files, path)
+}
+
+// input records an input file read by the command.
+func (m *manifest) input(path string) {
+	if m != nil {
+		m.record(&m.inputs, "input", path)
+	}
+}
+
+// output records an output file written by the command.
+func (m *manifest) output(path string) {
+	if m != nil {
+		m.record(&m.outputs, "output", path)
+	}
+}
+
+// hasOutputs reports whether the command wrote any output file.
+func (m *manifest) hasOutputs() bool {
+	return m != nil && len(m.outputs) > 0
+}
+
+// files returns the digests of paths, leaving out, with a warning, the files
+// that are gone, such as the reports the daemon archived.
+func (m *manifest) files(paths []string) []ManifestFile {
+	files := make([]ManifestFile, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			logger.Warn("left out of the manifest", "file", path, "err", err)
+			continue
+		}
+		digest, err := fileDigest(path)
+		if err != nil {
+			logger.Warn("left out of the manifest", "file", path, "err", err)
+			continue
+		}
+		files = append(files, ManifestFile{Path: path, SHA256: hex.EncodeToString(digest[:]), Size: info.Size()})
+	}
+	return files
+}
+
+// write writes the manifest: as JSON when its file ends in .json, or else in
+// the format of sha256sum, with the paths relative to the manifest so that
+// sha256sum -c checks them from its directory.
+func (m *manifest) write() error {
+	if m == nil {
+		return nil
+	}
+	document := ManifestDocument{Generated: time.Now().UTC().Truncate(time.Second), Inputs: m.files(m.inputs), Outputs: m.files(m.outputs)}
+	var buf bytes.Buffer
+	if strings.EqualFold(filepath.Ext(m.path), ".json") {
+		encoder := json.NewEncoder(&buf)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(document); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(&buf, "# nmapTables manifest, generated %s\n", document.Generated.Format(time.RFC3339))
+		for _, section := range []struct {
+			title string
+			files []ManifestFile
+		}{{"inputs", document.Inputs}, {"outputs", document.Outputs}} {
+			fmt.Fprintf(&buf, "# %s\n", section.title)
+			for _, file := range section.files {
+				path := file.Path
+				if rel, err := filepath.Rel(filepath.Dir(m.path), path); err == nil {
+					path = rel
+				}
+				fmt.Fprintf(&buf, "%s  %s\n", file.SHA256, filepath.ToSlash(path))
+			}
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(m.path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("Error writing the manifest: %w", err)
+	}
+	// Reports may be written to stdout, which the status must stay out of.
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Manifest of %d inputs and %d outputs written to %s\n", len(document.Inputs), len(document.Outputs), m.path)
+	}
+	return nil
+}
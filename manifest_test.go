@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The manifest lists the files of a report that matched nothing, which is
+// still written.
+func TestManifestNoMatches(t *testing.T) {
+	dir := t.TempDir()
+	scan := writeFile(t, dir, "scan.xml", redactTestScan)
+	manifestFile := filepath.Join(dir, "manifest.txt")
+	_, err := runCLI(t, "report", scan, "--service", "ftp", "--quiet", "-o", filepath.Join(dir, "ftp.html"), "--manifest", manifestFile)
+	if exitCode(err) != exitNoMatches {
+		t.Fatalf("report exited with %v, want no matches", err)
+	}
+	data, err := os.ReadFile(manifestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"scan.xml", "ftp.html"} {
+		if !strings.Contains(string(data), "  "+file+"\n") {
+			t.Errorf("manifest has no %s:\n%s", file, data)
+		}
+	}
+
+	// Failures without outputs leave no manifest.
+	os.Remove(manifestFile)
+	if _, err := runCLI(t, "report", filepath.Join(dir, "missing.xml"), "--quiet", "--manifest", manifestFile); err == nil {
+		t.Fatal("report of a missing file succeeded")
+	}
+	if _, err := os.Stat(manifestFile); !os.IsNotExist(err) {
+		t.Errorf("manifest written for a failed report: %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// MatrixPort is a column of the port matrix.
+type MatrixPort struct {
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	// Hosts is the number of hosts with the port in one of the matrix states.
+	Hosts int `json:"hosts"`
+}
+
+// String returns the port for display, with the protocol for UDP and other
+// protocols, e.g. "443" or "161/udp".
+func (p MatrixPort) String() string {
+	if p.Protocol == "tcp" {
+		return fmt.Sprint(p.Port)
+	}
+	return fmt.Sprintf("%d/%s", p.Port, p.Protocol)
+}
+
+// key returns the port as port/protocol.
+func (p MatrixPort) key() string {
+	return fmt.Sprintf("%d/%s", p.Port, p.Protocol)
+}
+
+// MatrixHost is a row of the port matrix: the state of every port column on
+// the host, "" for ports the scans did not report.
+type MatrixHost struct {
+	Host     string   `json:"host"`
+	Hostname string   `json:"hostname,omitempty"`
+	States   []string `json:"states"`
+}
+
+// PortMatrix is the hosts × ports matrix.
+type PortMatrix struct {
+	Ports []MatrixPort `json:"ports"`
+	Hosts []MatrixHost `json:"hosts"`
+}
+
+// matrixStates are the abbreviations of the port states in text matrices.
+var matrixStates = map[string]string{
+	"open":            "O",
+	"closed":          "C",
+	"filtered":        "F",
+	"unfiltered":      "U",
+	"open|filtered":   "O|F",
+	"closed|filtered": "C|F",
+}
+
+// BuildPortMatrix returns the matrix of the top ports of runs: the n ports
+// found in one of the opts.States on the most hosts, ordered by protocol and
+// port number, with a row per host that has one of them in those states.
+// Cells hold the state of the port on the host in whatever state it was
+// reported.
+func BuildPortMatrix(runs []Nmaprun, opts TableOptions, n int) PortMatrix {
+	hosts := opts.mergeHosts(runs)
+	counts := make(map[MatrixPort]int)
+	for i := range hosts {
+		host := &hosts[i]
+		if opts.excluded(hostAddress(host, opts.PreferIPv6)) {
+			continue
+		}
+		seen := make(map[MatrixPort]bool)
+		for _, port := range host.Ports.Port {
+			key := MatrixPort{Port: atoi(port.Portid), Protocol: port.Protocol}
+			if opts.States[port.State.State] && !seen[key] {
+				seen[key] = true
+				counts[key]++
+			}
+		}
+	}
+	var matrix PortMatrix
+	for port, hosts := range counts {
+		port.Hosts = hosts
+		matrix.Ports = append(matrix.Ports, port)
+	}
+	sort.Slice(matrix.Ports, func(i, j int) bool {
+		a, b := matrix.Ports[i], matrix.Ports[j]
+		if a.Hosts != b.Hosts {
+			return a.Hosts > b.Hosts
+		}
+		return lessPort(a, b)
+	})
+	if n > 0 && len(matrix.Ports) > n {
+		matrix.Ports = matrix.Ports[:n]
+	}
+	sort.Slice(matrix.Ports, func(i, j int) bool { return lessPort(matrix.Ports[i], matrix.Ports[j]) })
+
+	columns := make(map[string]int, len(matrix.Ports))
+	for i, port := range matrix.Ports {
+		columns[port.key()] = i
+	}
+	for i := range hosts {
+		host := &hosts[i]
+		addr := hostAddress(host, opts.PreferIPv6)
+		if opts.excluded(addr) {
+			continue
+		}
+		row := MatrixHost{Host: addr, States: make([]string, len(matrix.Ports))}
+		if len(host.Hostnames.Hostname) > 0 {
+			row.Hostname = host.Hostnames.Hostname[0].Name
+		}
+		matched := false
+		for _, port := range host.Ports.Port {
+			column, ok := columns[port.Portid+"/"+port.Protocol]
+			if !ok {
+				continue
+			}
+			row.States[column] = port.State.State
+			matched = matched || opts.States[port.State.State]
+		}
+		if matched {
+			matrix.Hosts = append(matrix.Hosts, row)
+		}
+	}
+	sort.Slice(matrix.Hosts, func(i, j int) bool { return lessAddr(matrix.Hosts[i].Host, matrix.Hosts[j].Host) })
+	return matrix
+}
+
+// lessPort orders TCP ports before UDP and other protocols, and then by port
+// number.
+func lessPort(a, b MatrixPort) bool {
+	if a.Protocol != b.Protocol {
+		if a.Protocol == "tcp" || b.Protocol == "tcp" {
+			return a.Protocol == "tcp"
+		}
+		return a.Protocol < b.Protocol
+	}
+	return a.Port < b.Port
+}
+
+// writeMatrix writes matrix to w in format: an aligned text table with
+// abbreviated states, csv or json.
+func writeMatrix(w io.Writer, matrix PortMatrix, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if matrix.Ports == nil {
+			matrix.Ports = []MatrixPort{}
+		}
+		if matrix.Hosts == nil {
+			matrix.Hosts = []MatrixHost{}
+		}
+		return encoder.Encode(matrix)
+	case "csv":
+		cw := csv.NewWriter(w)
+		header := []string{"host", "hostname"}
+		for _, port := range matrix.Ports {
+			header = append(header, port.key())
+		}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		for _, host := range matrix.Hosts {
+			if err := cw.Write(append([]string{host.Host, host.Hostname}, host.States...)); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "", "text":
+		tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+		header := []string{"HOST"}
+		for _, port := range matrix.Ports {
+			header = append(header, port.String())
+		}
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, host := range matrix.Hosts {
+			cells := []string{host.Host}
+			for _, state := range host.States {
+				cell, ok := matrixStates[state]
+				switch {
+				case state == "":
+					cell = "."
+				case !ok:
+					cell = state
+				}
+				cells = append(cells, cell)
+			}
+			fmt.Fprintln(tw, strings.Join(cells, "\t"))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		if len(matrix.Hosts) > 0 {
+			_, err := fmt.Fprintln(w, "\nO open, C closed, F filtered, U unfiltered, . not reported")
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported matrix format: %s (use text, csv or json)", format)
+}
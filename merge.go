@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Strategies of --merge for the ports seen in several scans.
+const (
+	// mergeConfidence keeps the most confident service detection.
+	mergeConfidence = "confidence"
+	// mergeLatest keeps the observation of the most recent scan.
+	mergeLatest = "latest"
+	// mergeAll keeps every observation, each with the time of its scan.
+	mergeAll = "all"
+)
+
+// MergeHosts combines every host of runs into a single entry per address, as
+// chosen by hostAddress.
+// Ports seen in several scans are unioned; when the same protocol/port was
+// detected more than once the most confident service detection wins.
+// Hosts are returned in the order their address was first seen.
+func MergeHosts(runs []Nmaprun, preferIPv6 bool) []NmapHost {
+	return mergeHosts(runs, preferIPv6, mergeConfidence)
+}
+
+// mergeHosts is MergeHosts with the --merge strategy of the ports detected
+// more than once.
+func mergeHosts(runs []Nmaprun, preferIPv6 bool, strategy string) []NmapHost {
+	var merged []NmapHost
+	index := make(map[string]int)
+
+	for i := range runs {
+		for _, host := range runs[i].Host {
+			addr := hostAddress(&host, preferIPv6)
+			pos, ok := index[addr]
+			if !ok {
+				index[addr] = len(merged)
+				// The merged host is appended to, which must not write
+				// into the scans, shared by the serve requests.
+				host.Address = append([]NmapAddress(nil), host.Address...)
+				host.Hostnames.Hostname = append([]NmapHostname(nil), host.Hostnames.Hostname...)
+				host.Ports.Port = append([]NmapPort(nil), host.Ports.Port...)
+				merged = append(merged, host)
+				continue
+			}
+			mergeHost(&merged[pos], &host, strategy)
+		}
+	}
+
+	for i := range merged {
+		sortPorts(merged[i].Ports.Port)
+	}
+	return merged
+}
+
+// mergeHost merges the addresses, hostnames, status and ports of src into dst,
+// choosing between the detections of the same port by strategy.
+func mergeHost(dst, src *NmapHost, strategy string) {
+	if dst.Status.State != "up" && src.Status.State != "" {
+		dst.Status = src.Status
+	}
+
+	if _, srcAcc := bestOSMatch(src); srcAcc > 0 {
+		if _, dstAcc := bestOSMatch(dst); srcAcc > dstAcc {
+			dst.Os = src.Os
+		}
+	}
+
+	if len(dst.Trace.Hop) == 0 {
+		dst.Trace = src.Trace
+	}
+
+	hostnames := make(map[string]struct{})
+	for _, hostname := range dst.Hostnames.Hostname {
+		hostnames[hostname.Name] = struct{}{}
+	}
+	for _, hostname := range src.Hostnames.Hostname {
+		if _, ok := hostnames[hostname.Name]; !ok {
+			dst.Hostnames.Hostname = append(dst.Hostnames.Hostname, hostname)
+		}
+	}
+
+	for _, address := range src.Address {
+		found := false
+		for _, existing := range dst.Address {
+			if existing.Addr == address.Addr {
+				found = true
+				break
+			}
+		}
+		if !found {
+			dst.Address = append(dst.Address, address)
+		}
+	}
+
+	for _, port := range src.Ports.Port {
+		if strategy == mergeAll {
+			dst.Ports.Port = append(dst.Ports.Port, port)
+			continue
+		}
+		found := false
+		for j := range dst.Ports.Port {
+			existing := &dst.Ports.Port[j]
+			if existing.Protocol != port.Protocol || existing.Portid != port.Portid {
+				continue
+			}
+			found = true
+			if strategy == mergeLatest && !port.Seen.Equal(existing.Seen) {
+				if port.Seen.After(existing.Seen) {
+					*existing = port
+				}
+			} else if moreConfident(&port, existing) {
+				*existing = port
+			}
+			break
+		}
+		if !found {
+			dst.Ports.Port = append(dst.Ports.Port, port)
+		}
+	}
+}
+
+// bestOSMatch returns the most accurate OS match of host and its accuracy.
+func bestOSMatch(host *NmapHost) (*NmapOsmatch, int) {
+	var best *NmapOsmatch
+	bestAccuracy := 0
+	for i := range host.Os.Osmatch {
+		accuracy := atoi(host.Os.Osmatch[i].Accuracy)
+		if best == nil || accuracy > bestAccuracy {
+			best = &host.Os.Osmatch[i]
+			bestAccuracy = accuracy
+		}
+	}
+	return best, bestAccuracy
+}
+
+// hostOS returns the best OS match of host formatted for display, or "" if nmap
+// did not fingerprint the host.
+func hostOS(host *NmapHost) string {
+	best, accuracy := bestOSMatch(host)
+	if best == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s (%d%%)", best.Name, accuracy)
+}
+
+// moreConfident reports whether the service detection of a should be preferred
+// over that of b. Higher nmap confidence wins, then a detected product.
+func moreConfident(a, b *NmapPort) bool {
+	confA, confB := atoi(a.Service.Conf), atoi(b.Service.Conf)
+	if confA != confB {
+		return confA > confB
+	}
+	return a.Service.Product != "" && b.Service.Product == ""
+}
+
+// sortPorts orders ports by protocol, numerically by port and then by the time
+// they were seen.
+func sortPorts(ports []NmapPort) {
+	sort.SliceStable(ports, func(i, j int) bool {
+		if ports[i].Protocol != ports[j].Protocol {
+			return ports[i].Protocol < ports[j].Protocol
+		}
+		if pi, pj := atoi(ports[i].Portid), atoi(ports[j].Portid); pi != pj {
+			return pi < pj
+		}
+		return ports[i].Seen.Before(ports[j].Seen)
+	})
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+// mergeTestRuns returns two scans of the same host by different names, whose
+// slices have room to be appended to in place.
+func mergeTestRuns() []Nmaprun {
+	runs := make([]Nmaprun, 2)
+	for i, name := range []string{"web1.corp.local", "www.corp.local"} {
+		var host NmapHost
+		host.Address = make([]NmapAddress, 1, 4)
+		host.Status.State = "up"
+		host.Address[0] = NmapAddress{Addr: "10.0.0.5", Addrtype: "ipv4"}
+		host.Hostnames.Hostname = make([]NmapHostname, 1, 4)
+		host.Hostnames.Hostname[0] = NmapHostname{Name: name, Type: "PTR"}
+		host.Ports.Port = make([]NmapPort, 1, 4)
+		host.Ports.Port[0].Protocol = "tcp"
+		host.Ports.Port[0].Portid = []string{"80", "443"}[i]
+		runs[i].Host = []NmapHost{host}
+	}
+	return runs
+}
+
+func TestMergeHostsLeavesScans(t *testing.T) {
+	runs := mergeTestRuns()
+	merged := MergeHosts(runs, false)
+	if len(merged) != 1 {
+		t.Fatalf("got %d hosts, want 1", len(merged))
+	}
+	if got := merged[0].Hostnames.Hostname; len(got) != 2 {
+		t.Errorf("merged hostnames %v, want both", got)
+	}
+	if got := len(merged[0].Ports.Port); got != 2 {
+		t.Errorf("got %d merged ports, want 2", got)
+	}
+	first := runs[0].Host[0]
+	if spare := first.Hostnames.Hostname[:2][1]; spare != (NmapHostname{}) {
+		t.Errorf("merging wrote %v into the hostnames of the scan", spare)
+	}
+	if spare := first.Ports.Port[:2][1]; spare.Portid != "" {
+		t.Errorf("merging wrote port %s into the ports of the scan", spare.Portid)
+	}
+}
+
+// TestMergeHostsConcurrent merges the same scans concurrently, as serve
+// requests do, for go test -race to catch the writes they share.
+func TestMergeHostsConcurrent(t *testing.T) {
+	runs := mergeTestRuns()
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, strategy := range []string{mergeConfidence, mergeLatest, mergeAll} {
+				if merged := mergeHosts(runs, false, strategy); len(merged) != 1 || len(merged[0].Hostnames.Hostname) != 2 {
+					t.Errorf("--merge %s: got %+v, want a host of 2 hostnames", strategy, merged)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
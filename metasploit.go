@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// msfHost is a host of the Metasploit exports with its ports, in order.
+type msfHost struct {
+	addr     string
+	hostname string
+	ports    []msfPort
+}
+
+// msfPort is a host port of the Metasploit exports. Product and version are
+// only known for rows of a single version.
+type msfPort struct {
+	port     string
+	protocol string
+	service  string
+	product  string
+	version  string
+}
+
+// msfHosts returns the hosts of the verified and unverified rows of
+// tableData, ordered by address and then by port.
+func msfHosts(tableData ReportData) []*msfHost {
+	index := make(map[string]*msfHost)
+	var hosts []*msfHost
+	for _, rows := range [][]ReportRow{tableData.Rows, tableData.Unverified} {
+		for i := range rows {
+			row := &rows[i]
+			var product, version string
+			if len(row.versions) == 1 {
+				product, version = row.versions[0].product, row.versions[0].version
+			}
+			for _, hostPort := range row.Hosts {
+				host, ok := index[hostPort.Addr]
+				if !ok {
+					host = &msfHost{addr: hostPort.Addr, hostname: hostPort.Hostname}
+					index[hostPort.Addr] = host
+					hosts = append(hosts, host)
+				}
+				service := row.Service
+				if strings.Contains(service, ",") || service == "" {
+					service = "unknown"
+				}
+				host.ports = append(host.ports, msfPort{port: hostPort.Port, protocol: row.Protocol, service: service, product: product, version: version})
+			}
+		}
+	}
+	sort.Slice(hosts, func(i, j int) bool { return lessAddr(hosts[i].addr, hosts[j].addr) })
+	for _, host := range hosts {
+		sort.SliceStable(host.ports, func(i, j int) bool { return atoi(host.ports[i].port) < atoi(host.ports[j].port) })
+	}
+	return hosts
+}
+
+// writeMetasploitRC writes the host ports of tableData to w as a msfconsole
+// resource script (msfconsole -r, or resource in a session) that adds them to
+// the hosts and services tables of the current workspace and sets RHOSTS to
+// the hosts of the service.
+func writeMetasploitRC(tableData ReportData, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	hosts := msfHosts(tableData)
+	fmt.Fprintf(bw, "# nmapTables: %s on %s\n", tableData.Service, plural(len(hosts), "host"))
+	for _, host := range hosts {
+		fmt.Fprintf(bw, "hosts -a %s\n", host.addr)
+		for _, port := range host.ports {
+			fmt.Fprintf(bw, "services -a -p %s -r %s -s %s %s\n", port.port, port.protocol, port.service, host.addr)
+		}
+	}
+	if len(hosts) > 0 && !strings.Contains(tableData.Service, ",") {
+		fmt.Fprintf(bw, "services -u -s %s -R\n", tableData.Service)
+	}
+	return bw.Flush()
+}
+
+// Nmap XML documents written for db_import, limited to what Metasploit reads.
+type (
+	msfNmapRun struct {
+		XMLName xml.Name      `xml:"nmaprun"`
+		Scanner string        `xml:"scanner,attr"`
+		Args    string        `xml:"args,attr"`
+		Start   string        `xml:"start,attr,omitempty"`
+		Hosts   []msfNmapHost `xml:"host"`
+	}
+	msfNmapHost struct {
+		Status    msfNmapStatus     `xml:"status"`
+		Address   msfNmapAddress    `xml:"address"`
+		Hostnames []msfNmapHostname `xml:"hostnames>hostname"`
+		Ports     []msfNmapPort     `xml:"ports>port"`
+	}
+	msfNmapStatus struct {
+		State string `xml:"state,attr"`
+	}
+	msfNmapAddress struct {
+		Addr     string `xml:"addr,attr"`
+		Addrtype string `xml:"addrtype,attr"`
+	}
+	msfNmapHostname struct {
+		Name string `xml:"name,attr"`
+		Type string `xml:"type,attr"`
+	}
+	msfNmapPort struct {
+		Protocol string          `xml:"protocol,attr"`
+		Portid   string          `xml:"portid,attr"`
+		State    msfNmapStatus   `xml:"state"`
+		Service  msfNmapServices `xml:"service"`
+	}
+	msfNmapServices struct {
+		Name    string `xml:"name,attr"`
+		Product string `xml:"product,attr,omitempty"`
+		Version string `xml:"version,attr,omitempty"`
+	}
+)
+
+// writeMetasploitXML writes the host ports of tableData to w as a minimal
+// nmap XML document for msfconsole's db_import, which unlike the resource
+// script keeps the product and version of each service.
+func writeMetasploitXML(tableData ReportData, w io.Writer) error {
+	run := msfNmapRun{Scanner: "nmap", Args: "nmapTables --service " + tableData.Service}
+	if start := tableData.Summary.ScanStart; !start.IsZero() {
+		run.Start = fmt.Sprint(start.Unix())
+	}
+	for _, host := range msfHosts(tableData) {
+		addrtype := "ipv4"
+		if strings.Contains(host.addr, ":") {
+			addrtype = "ipv6"
+		}
+		nmapHost := msfNmapHost{Status: msfNmapStatus{State: "up"}, Address: msfNmapAddress{Addr: host.addr, Addrtype: addrtype}}
+		if host.hostname != "" {
+			nmapHost.Hostnames = []msfNmapHostname{{Name: host.hostname, Type: "user"}}
+		}
+		for _, port := range host.ports {
+			nmapHost.Ports = append(nmapHost.Ports, msfNmapPort{
+				Protocol: port.protocol,
+				Portid:   port.port,
+				State:    msfNmapStatus{State: "open"},
+				Service:  msfNmapServices{Name: port.service, Product: port.product, Version: port.version},
+			})
+		}
+		run.Hosts = append(run.Hosts, nmapHost)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(run); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// metricsContentType is the content type of the Prometheus text exposition
+// format.
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// metricLabel escapes value for a label of the Prometheus text format.
+func metricLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// writeMetricFamily writes the HELP and TYPE lines of a gauge and a sample per
+// label value, sorted, or a single unlabelled sample when label is empty.
+func writeMetricFamily(w io.Writer, name, help, label string, values map[string]int) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	if label == "" {
+		fmt.Fprintf(w, "%s %d\n", name, values[""])
+		return
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %d\n", name, label, metricLabel(key), values[key])
+	}
+}
+
+// writeMetrics writes the exposure metrics of the served scans to w in the
+// Prometheus text format: the open ports by service, the hosts up, the input
+// files that failed to parse and, with a baseline, the ports that are new
+// since it by service.
+func (s *reportServer) writeMetrics(w io.Writer) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	openPorts := make(map[string]int)
+	hostsUp := 0
+	for i := range s.hosts {
+		host := &s.hosts[i]
+		if host.Status.State == "up" {
+			hostsUp++
+		}
+		for _, port := range host.Ports.Port {
+			if port.State.State != "open" {
+				continue
+			}
+			service := port.Service.Name
+			if service == "" {
+				service = "unknown"
+			}
+			openPorts[service]++
+		}
+	}
+	writeMetricFamily(w, "nmaptables_open_ports", "Open ports in the scans by service.", "service", openPorts)
+	writeMetricFamily(w, "nmaptables_hosts_up", "Hosts reported up by the scans.", "", map[string]int{"": hostsUp})
+	writeMetricFamily(w, "nmaptables_parse_errors", "Input files that could not be parsed.", "", map[string]int{"": len(s.errors)})
+	if s.baseline != nil {
+		newPorts := make(map[string]int)
+		for _, entry := range DiffScans(s.baseline, s.runs, s.opts) {
+			if entry.Change == "+" {
+				service := entry.New.Service
+				if service == "" {
+					service = "unknown"
+				}
+				newPorts[service]++
+			}
+		}
+		writeMetricFamily(w, "nmaptables_new_ports", "Ports in the scans that are not in the baseline scans, by service.", "service", newPorts)
+	}
+	fmt.Fprintf(w, "# HELP nmaptables_last_reload_timestamp_seconds When the scans were last parsed.\n"+
+		"# TYPE nmaptables_last_reload_timestamp_seconds gauge\nnmaptables_last_reload_timestamp_seconds %d\n", s.loadedAt.Unix())
+}
+
+// handleMetrics serves the Prometheus metrics of the scans.
+func (s *reportServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metricsContentType)
+	bw := bufio.NewWriter(w)
+	s.writeMetrics(bw)
+	if err := bw.Flush(); err != nil {
+		logger.Debug("could not write metrics", "err", err)
+	}
+}
+
+// loadBaseline parses baselineFiles as the baseline scans new ports are
+// counted against. No files leaves s without a baseline.
+func (s *reportServer) loadBaseline(baselineFiles []string) error {
+	if len(baselineFiles) == 0 {
+		return nil
+	}
+	runs, parseErrors := ParseNmapFiles(baselineFiles)
+	if err := checkParseErrors(parseErrors); err != nil {
+		return err
+	}
+	if runs == nil {
+		runs = []Nmaprun{}
+	}
+	s.baseline = runs
+	return nil
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+)
+
+// mmdbMetadataMarker starts the metadata at the end of a MaxMind DB file.
+var mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// mmdbReader looks up addresses in a MaxMind DB (.mmdb) file, such as the
+// GeoLite2 Country, City and ASN databases.
+type mmdbReader struct {
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	// dataStart is the offset of the data section.
+	dataStart uint
+	// ipv4Start is the node IPv4 lookups start at in IPv6 databases.
+	ipv4Start uint
+}
+
+// openMMDB reads the MaxMind DB at path.
+func openMMDB(path string) (*mmdbReader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	outputManifest.input(path)
+	start := bytes.LastIndex(data, mmdbMetadataMarker)
+	if start < 0 {
+		return nil, fmt.Errorf("%s is not a MaxMind DB file", path)
+	}
+	r := &mmdbReader{data: data}
+	decoder := mmdbDecoder{data: data[start+len(mmdbMetadataMarker):]}
+	value, _, err := decoder.decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MaxMind DB metadata: %w", err)
+	}
+	metadata, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("invalid MaxMind DB metadata")
+	}
+	r.nodeCount = mmdbUint(metadata["node_count"])
+	r.recordSize = mmdbUint(metadata["record_size"])
+	r.ipVersion = mmdbUint(metadata["ip_version"])
+	switch r.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("unsupported MaxMind DB record size %d", r.recordSize)
+	}
+	treeSize := r.recordSize * 2 / 8 * r.nodeCount
+	r.dataStart = treeSize + 16
+	if r.dataStart > uint(start) {
+		return nil, errors.New("invalid MaxMind DB search tree")
+	}
+
+	if r.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < r.nodeCount; i++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// mmdbUint returns a metadata value as an unsigned integer.
+func mmdbUint(value any) uint {
+	switch v := value.(type) {
+	case uint64:
+		return uint(v)
+	case int64:
+		return uint(v)
+	}
+	return 0
+}
+
+// record returns the left (bit 0) or right (bit 1) record of node.
+func (r *mmdbReader) record(node, bit uint) uint {
+	b := r.data[node*r.recordSize/4:]
+	switch r.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// lookup returns the record of addr, or nil when the database has none.
+func (r *mmdbReader) lookup(addr netip.Addr) (map[string]any, error) {
+	addr = addr.Unmap()
+	node := uint(0)
+	var ip []byte
+	if addr.Is4() {
+		four := addr.As4()
+		ip = four[:]
+		node = r.ipv4Start
+	} else {
+		if r.ipVersion == 4 {
+			return nil, nil
+		}
+		sixteen := addr.As16()
+		ip = sixteen[:]
+	}
+	for i := 0; i < len(ip)*8 && node < r.nodeCount; i++ {
+		node = r.record(node, uint(ip[i/8]>>(7-i%8))&1)
+	}
+	if node <= r.nodeCount {
+		return nil, nil
+	}
+	offset := node - r.nodeCount - 16
+	decoder := mmdbDecoder{data: r.data[r.dataStart:]}
+	value, _, err := decoder.decode(offset)
+	if err != nil {
+		return nil, err
+	}
+	record, _ := value.(map[string]any)
+	return record, nil
+}
+
+// mmdbDecoder decodes values of the MaxMind DB data section format.
+type mmdbDecoder struct {
+	data []byte
+}
+
+var errMMDBTruncated = errors.New("truncated MaxMind DB data")
+
+// decode decodes the value at offset and returns it along with the offset
+// that follows it.
+func (d *mmdbDecoder) decode(offset uint) (any, uint, error) {
+	if offset >= uint(len(d.data)) {
+		return nil, 0, errMMDBTruncated
+	}
+	control := d.data[offset]
+	offset++
+	typ := uint(control >> 5)
+	if typ == 1 {
+		pointer, next, err := d.pointer(control, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(pointer)
+		return value, next, err
+	}
+	if typ == 0 {
+		if offset >= uint(len(d.data)) {
+			return nil, 0, errMMDBTruncated
+		}
+		typ = 7 + uint(d.data[offset])
+		offset++
+	}
+	size := uint(control & 0x1F)
+	if size >= 29 {
+		extra := size - 28
+		if offset+extra > uint(len(d.data)) {
+			return nil, 0, errMMDBTruncated
+		}
+		n := uint(0)
+		for _, b := range d.data[offset : offset+extra] {
+			n = n<<8 | uint(b)
+		}
+		size = []uint{29, 285, 65821}[extra-1] + n
+		offset += extra
+	}
+
+	switch typ {
+	case 7: // map
+		m := make(map[string]any, size)
+		for range size {
+			key, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			value, next, err := d.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, _ := key.(string)
+			m[name] = value
+			offset = next
+		}
+		return m, offset, nil
+	case 11: // array
+		a := make([]any, 0, size)
+		for range size {
+			value, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+			offset = next
+		}
+		return a, offset, nil
+	case 14: // boolean, stored in the size
+		return size != 0, offset, nil
+	}
+	if offset+size > uint(len(d.data)) {
+		return nil, 0, errMMDBTruncated
+	}
+	b := d.data[offset : offset+size]
+	offset += size
+	switch typ {
+	case 2: // UTF-8 string
+		return string(b), offset, nil
+	case 3: // double
+		if size != 8 {
+			return nil, 0, errMMDBTruncated
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), offset, nil
+	case 15: // float
+		if size != 4 {
+			return nil, 0, errMMDBTruncated
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), offset, nil
+	case 5, 6, 9, 10: // unsigned integers; uint128 values are truncated
+		n := uint64(0)
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n, offset, nil
+	case 8: // int32
+		n := uint32(0)
+		for _, c := range b {
+			n = n<<8 | uint32(c)
+		}
+		return int64(int32(n)), offset, nil
+	case 4: // bytes
+		return b, offset, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported MaxMind DB data type %d", typ)
+}
+
+// pointer decodes the pointer starting with control, returning the offset it
+// points to and the offset that follows it.
+func (d *mmdbDecoder) pointer(control byte, offset uint) (uint, uint, error) {
+	size := uint(control>>3)&0x3 + 1
+	if offset+size > uint(len(d.data)) {
+		return 0, 0, errMMDBTruncated
+	}
+	n := uint(0)
+	if size < 4 {
+		n = uint(control & 0x7)
+	}
+	for _, b := range d.data[offset : offset+size] {
+		n = n<<8 | uint(b)
+	}
+	n += []uint{0, 2048, 526336, 0}[size-1]
+	return n, offset + size, nil
+}
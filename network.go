@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net"
+	"strings"
+
+	"github.com/mr-pmillz/nmapTables/nmap"
+)
+
+// parseNetworks parses a comma separated list of CIDRs and IP addresses.
+// Plain addresses are treated as single-host networks.
+func parseNetworks(value string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		network, err := nmap.ParseNetwork(part)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// excluded reports whether the host addr is left out by the --exclude and
+// --scope networks of opts.
+func (opts TableOptions) excluded(addr string) bool {
+	if inNetworks(addr, opts.Exclude) {
+		return true
+	}
+	return len(opts.Scope) > 0 && !inNetworks(addr, opts.Scope)
+}
+
+// inNetworks reports whether addr is contained in any of networks.
+func inNetworks(addr string, networks []*net.IPNet) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"time"
+
+	"github.com/mr-pmillz/nmapTables/nmap"
+)
+
+// The XML model of nmap output is that of the nmap package, shared with the
+// programs using it as a library. The runs, hosts and ports are wrapped to add
+// where they were read from, see setSource: their Host and Ports fields hold
+// the wrapped hosts and ports in place of those of the nmap package.
+type (
+	NmapOS       = nmap.XMLOS
+	NmapOsmatch  = nmap.XMLOSMatch
+	NmapTrace    = nmap.XMLTrace
+	NmapHop      = nmap.XMLHop
+	NmapAddress  = nmap.XMLAddress
+	NmapHostname = nmap.XMLHostname
+	NmapScript   = nmap.XMLScript
+	NmapElem     = nmap.XMLElem
+	NmapTable    = nmap.XMLTable
+	NmapService  = nmap.XMLService
+)
+
+// Nmaprun is a scan read from File.
+type Nmaprun struct {
+	nmap.XMLRun
+	Host []NmapHost `xml:"host"`
+	// File is the input file the run was read from.
+	File string `xml:"-"`
+}
+
+// NmapHost is a scanned host of an Nmaprun.
+type NmapHost struct {
+	nmap.XMLHost
+	Ports struct {
+		Text string     `xml:",chardata"`
+		Port []NmapPort `xml:"port"`
+	} `xml:"ports"`
+}
+
+// NmapPort is a scanned port of an NmapHost.
+type NmapPort struct {
+	nmap.XMLPort
+	// Source and Seen are the input file the port was read from and the start
+	// time of its scan.
+	Source string    `xml:"-"`
+	Seen   time.Time `xml:"-"`
+}
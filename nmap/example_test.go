@@ -0,0 +1,37 @@
+package nmap_test
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/mr-pmillz/nmapTables/nmap"
+)
+
+func ExampleParseFile() {
+	run, err := nmap.ParseFile("testdata/scan.xml")
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, host := range run.Hosts {
+		for _, port := range host.OpenPorts() {
+			fmt.Println(host.IP, port.Number, port.Service.Product, port.Service.Version)
+		}
+	}
+	// Output:
+	// 10.0.0.5 22 OpenSSH 8.2p1 Ubuntu 4ubuntu0.5
+	// 10.0.0.5 443 nginx 1.18.0
+}
+
+func ExampleParse() {
+	run, err := nmap.Parse(strings.NewReader(`<nmaprun scanner="nmap" version="7.94">
+<host><status state="up"/><address addr="2001:db8::1" addrtype="ipv6"/>
+<ports><port protocol="udp" portid="161"><state state="open"/><service name="snmp"/></port></ports></host>
+</nmaprun>`))
+	if err != nil {
+		log.Fatal(err)
+	}
+	host := run.Hosts[0]
+	fmt.Println(host.Address(), host.Ports[0].Protocol, host.Ports[0].Number, host.Ports[0].Service.Name)
+	// Output: 2001:db8::1 udp 161 snmp
+}
@@ -0,0 +1,131 @@
+// Package nmap parses nmap XML output (nmap -oX) into a typed model of hosts,
+// ports and services, for Go programs that want the data nmapTables reports on
+// without dealing with the raw XML.
+package nmap
+
+import (
+	"net"
+	"time"
+)
+
+// Run is a single nmap scan.
+type Run struct {
+	Scanner string
+	Args    string
+	Version string
+	Start   time.Time
+	// End is when the scan finished; zero for scans that were cut short.
+	End     time.Time
+	Elapsed time.Duration
+	Hosts   []Host
+}
+
+// Host is a scanned host.
+type Host struct {
+	// IP is the IPv4 address of the host, or its IPv6 address when it has none.
+	IP net.IP
+	// MAC and Vendor are only known for hosts on the scanner's network segment.
+	MAC       net.HardwareAddr
+	Vendor    string
+	Addresses []Address
+	Hostnames []string
+	// State is up, down or unknown.
+	State  string
+	Reason string
+	Start  time.Time
+	End    time.Time
+	Ports  []Port
+	// OS lists the OS fingerprint matches, best first.
+	OS      []OSMatch
+	Scripts []Script
+}
+
+// Address is an address of a host as reported by nmap.
+type Address struct {
+	Addr string
+	// Type is ipv4, ipv6 or mac.
+	Type   string
+	Vendor string
+}
+
+// Port is a scanned port of a host.
+type Port struct {
+	Number   int
+	Protocol string
+	// State is open, closed, filtered, open|filtered, ...
+	State  string
+	Reason string
+	// Owner is the user running the service, as reported by an ident server.
+	Owner   string
+	Service Service
+	Scripts []Script
+}
+
+// Service is the service detected on a port.
+type Service struct {
+	Name      string
+	Product   string
+	Version   string
+	ExtraInfo string
+	// Tunnel is ssl when the service was detected through an SSL tunnel,
+	// shown by nmap as ssl/<name>.
+	Tunnel string
+	OSType string
+	// Method is probed, or table when the name was only looked up by port.
+	Method string
+	// Confidence is the detection confidence from 0 to 10.
+	Confidence int
+	// CPE is the CPE name of the service, e.g. cpe:/a:openbsd:openssh:8.2p1.
+	CPE string
+}
+
+// OSMatch is an OS fingerprint match.
+type OSMatch struct {
+	Name string
+	// Accuracy is a percentage.
+	Accuracy int
+}
+
+// Script is the output of an NSE script.
+type Script struct {
+	ID       string
+	Output   string
+	Elements []Element
+	Tables   []Table
+}
+
+// Element is a key and value of structured script output.
+type Element struct {
+	Key   string
+	Value string
+}
+
+// Table is a nested table of structured script output.
+type Table struct {
+	Key      string
+	Elements []Element
+	Tables   []Table
+}
+
+// Address returns the address used to identify the host: its IP, or its
+// first address of any type.
+func (h *Host) Address() string {
+	if h.IP != nil {
+		return h.IP.String()
+	}
+	if len(h.Addresses) > 0 {
+		return h.Addresses[0].Addr
+	}
+	return ""
+}
+
+// OpenPorts returns the ports of the host in the open state.
+func (h *Host) OpenPorts() []Port {
+	var ports []Port
+	for _, port := range h.Ports {
+		if port.State == "open" {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
@@ -0,0 +1,161 @@
+package nmap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ParseFile reads the nmap XML document of the file at path.
+func ParseFile(path string) (*Run, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	run, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return run, nil
+}
+
+// Parse reads a single nmap XML document from r.
+func Parse(r io.Reader) (*Run, error) {
+	var raw XMLRun
+	if err := xml.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("parsing nmap XML: %w", err)
+	}
+	run := &Run{
+		Scanner: raw.Scanner,
+		Args:    raw.Args,
+		Version: raw.Version,
+		Start:   unixTime(raw.Start),
+		End:     unixTime(raw.Runstats.Finished.Time),
+		Hosts:   make([]Host, len(raw.Host)),
+	}
+	if elapsed, err := strconv.ParseFloat(raw.Runstats.Finished.Elapsed, 64); err == nil {
+		run.Elapsed = time.Duration(elapsed * float64(time.Second))
+	}
+	for i := range raw.Host {
+		run.Hosts[i] = convertHost(&raw.Host[i])
+	}
+	return run, nil
+}
+
+func convertHost(raw *XMLHost) Host {
+	host := Host{
+		State:  raw.Status.State,
+		Reason: raw.Status.Reason,
+		Start:  unixTime(raw.Starttime),
+		End:    unixTime(raw.Endtime),
+	}
+	var ipv6 net.IP
+	for _, address := range raw.Address {
+		host.Addresses = append(host.Addresses, Address{Addr: address.Addr, Type: address.Addrtype, Vendor: address.Vendor})
+		switch address.Addrtype {
+		case "ipv4":
+			if host.IP == nil {
+				host.IP = net.ParseIP(address.Addr)
+			}
+		case "ipv6":
+			if ipv6 == nil {
+				ipv6 = net.ParseIP(address.Addr)
+			}
+		case "mac":
+			if mac, err := net.ParseMAC(address.Addr); err == nil {
+				host.MAC = mac
+				host.Vendor = address.Vendor
+			}
+		}
+	}
+	if host.IP == nil {
+		host.IP = ipv6
+	}
+	for _, hostname := range raw.Hostnames.Hostname {
+		host.Hostnames = append(host.Hostnames, hostname.Name)
+	}
+	for i := range raw.Ports.Port {
+		host.Ports = append(host.Ports, convertPort(&raw.Ports.Port[i]))
+	}
+	for _, match := range raw.Os.Osmatch {
+		host.OS = append(host.OS, OSMatch{Name: match.Name, Accuracy: atoi(match.Accuracy)})
+	}
+	host.Scripts = convertScripts(raw.Hostscript.Script)
+	return host
+}
+
+func convertPort(raw *XMLPort) Port {
+	return Port{
+		Number:   atoi(raw.Portid),
+		Protocol: raw.Protocol,
+		State:    raw.State.State,
+		Reason:   raw.State.Reason,
+		Owner:    raw.Owner.Name,
+		Service: Service{
+			Name:       raw.Service.Name,
+			Product:    raw.Service.Product,
+			Version:    raw.Service.Version,
+			ExtraInfo:  raw.Service.Extrainfo,
+			Tunnel:     raw.Service.Tunnel,
+			OSType:     raw.Service.Ostype,
+			Method:     raw.Service.Method,
+			Confidence: atoi(raw.Service.Conf),
+			CPE:        raw.Service.Cpe,
+		},
+		Scripts: convertScripts(raw.Script),
+	}
+}
+
+func convertScripts(raw []XMLScript) []Script {
+	var scripts []Script
+	for _, script := range raw {
+		scripts = append(scripts, Script{
+			ID:       script.ID,
+			Output:   script.Output,
+			Elements: convertElements(script.Elem),
+			Tables:   convertTables(script.Table),
+		})
+	}
+	return scripts
+}
+
+func convertTables(raw []XMLTable) []Table {
+	var tables []Table
+	for _, table := range raw {
+		tables = append(tables, Table{
+			Key:      table.Key,
+			Elements: convertElements(table.Elem),
+			Tables:   convertTables(table.Table),
+		})
+	}
+	return tables
+}
+
+func convertElements(raw []XMLElem) []Element {
+	var elements []Element
+	for _, elem := range raw {
+		elements = append(elements, Element{Key: elem.Key, Value: elem.Text})
+	}
+	return elements
+}
+
+// unixTime converts a Unix timestamp attribute, returning the zero time when it
+// is missing.
+func unixTime(value string) time.Time {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0).UTC()
+}
+
+// atoi converts a numeric attribute, returning 0 when it is missing.
+func atoi(value string) int {
+	n, _ := strconv.Atoi(value)
+	return n
+}
@@ -0,0 +1,97 @@
+package nmap
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFile(t *testing.T) {
+	run, err := ParseFile("testdata/scan.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Scanner != "nmap" || run.Version != "7.94" {
+		t.Errorf("scanner %q %q, want nmap 7.94", run.Scanner, run.Version)
+	}
+	if want := time.Unix(1700000000, 0).UTC(); !run.Start.Equal(want) {
+		t.Errorf("Start = %v, want %v", run.Start, want)
+	}
+	if want := time.Unix(1700000120, 0).UTC(); !run.End.Equal(want) {
+		t.Errorf("End = %v, want %v", run.End, want)
+	}
+	if want := 120500 * time.Millisecond; run.Elapsed != want {
+		t.Errorf("Elapsed = %v, want %v", run.Elapsed, want)
+	}
+	if len(run.Hosts) != 1 {
+		t.Fatalf("got %d hosts, want 1", len(run.Hosts))
+	}
+
+	host := run.Hosts[0]
+	if !host.IP.Equal(net.ParseIP("10.0.0.5")) || host.Address() != "10.0.0.5" {
+		t.Errorf("IP = %v, want 10.0.0.5", host.IP)
+	}
+	if host.MAC.String() != "00:11:22:33:44:55" || host.Vendor != "Dell" {
+		t.Errorf("MAC = %v %q, want 00:11:22:33:44:55 Dell", host.MAC, host.Vendor)
+	}
+	if len(host.Hostnames) != 1 || host.Hostnames[0] != "sql01.corp.local" {
+		t.Errorf("Hostnames = %q, want sql01.corp.local", host.Hostnames)
+	}
+	if host.State != "up" || host.Reason != "arp-response" {
+		t.Errorf("State = %q %q, want up arp-response", host.State, host.Reason)
+	}
+	if len(host.OS) != 1 || host.OS[0].Name != "Linux 5.0 - 5.4" || host.OS[0].Accuracy != 98 {
+		t.Errorf("OS = %+v, want Linux 5.0 - 5.4 at 98%%", host.OS)
+	}
+	if len(host.Ports) != 3 {
+		t.Fatalf("got %d ports, want 3", len(host.Ports))
+	}
+
+	ssh := host.Ports[0]
+	want := Service{
+		Name:       "ssh",
+		Product:    "OpenSSH",
+		Version:    "8.2p1 Ubuntu 4ubuntu0.5",
+		ExtraInfo:  "Ubuntu Linux; protocol 2.0",
+		OSType:     "Linux",
+		Method:     "probed",
+		Confidence: 10,
+		CPE:        "cpe:/a:openbsd:openssh:8.2p1",
+	}
+	if ssh.Number != 22 || ssh.Protocol != "tcp" || ssh.State != "open" || ssh.Service != want {
+		t.Errorf("port 22 = %+v, want tcp/22 open %+v", ssh, want)
+	}
+	if len(ssh.Scripts) != 1 || ssh.Scripts[0].ID != "ssh-hostkey" {
+		t.Fatalf("port 22 scripts = %+v, want ssh-hostkey", ssh.Scripts)
+	}
+	tables := ssh.Scripts[0].Tables
+	if len(tables) != 1 || len(tables[0].Elements) != 2 || tables[0].Elements[1] != (Element{Key: "bits", Value: "3072"}) {
+		t.Errorf("ssh-hostkey tables = %+v, want the RSA key of 3072 bits", tables)
+	}
+	if https := host.Ports[1]; https.Service.Tunnel != "ssl" {
+		t.Errorf("port 443 tunnel = %q, want ssl", https.Service.Tunnel)
+	}
+	if open := host.OpenPorts(); len(open) != 2 {
+		t.Errorf("got %d open ports, want 2", len(open))
+	}
+}
+
+func TestParseNoHosts(t *testing.T) {
+	run, err := Parse(strings.NewReader(`<nmaprun scanner="nmap" start="0"></nmaprun>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(run.Hosts) != 0 || !run.Start.IsZero() || !run.End.IsZero() {
+		t.Errorf("got %+v, want a run with no hosts nor times", run)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := Parse(strings.NewReader("<nmaprun><host>")); err == nil {
+		t.Error("Parse of truncated XML returned no error")
+	}
+	if _, err := ParseFile("testdata/missing.xml"); err == nil {
+		t.Error("ParseFile of a missing file returned no error")
+	}
+}
@@ -0,0 +1,213 @@
+package nmap
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// HostPort is a port along with the host it was found on.
+type HostPort struct {
+	Host *Host
+	Port Port
+}
+
+// String returns the host address and port number, e.g. 10.0.0.5:443 or
+// [2001:db8::1]:443.
+func (hp HostPort) String() string {
+	return net.JoinHostPort(hp.Host.Address(), fmt.Sprint(hp.Port.Number))
+}
+
+// VersionGroup is every host port running the same version of a product over
+// the same protocol: a row of the nmapTables report.
+type VersionGroup struct {
+	Service  string
+	Product  string
+	Version  string
+	Protocol string
+	Ports    []HostPort
+}
+
+// Results is a query over the ports of parsed runs. Filters return a new,
+// narrower Results and can be chained:
+//
+//	groups := nmap.NewResults(run).ByState("open").ByService("http").GroupByVersion()
+//
+// A filter given an invalid argument, such as a malformed CIDR, matches
+// nothing and the error is kept for Err.
+type Results struct {
+	ports []HostPort
+	err   error
+}
+
+// NewResults returns the ports of every host of runs, in any state.
+func NewResults(runs ...*Run) *Results {
+	r := &Results{}
+	for _, run := range runs {
+		for i := range run.Hosts {
+			host := &run.Hosts[i]
+			for _, port := range host.Ports {
+				r.ports = append(r.ports, HostPort{Host: host, Port: port})
+			}
+		}
+	}
+	return r
+}
+
+// Err returns the error of the first filter that was given an invalid argument.
+func (r *Results) Err() error {
+	return r.err
+}
+
+// filter returns the ports of r matching keep.
+func (r *Results) filter(keep func(hp *HostPort) bool) *Results {
+	filtered := &Results{err: r.err}
+	for i := range r.ports {
+		if keep(&r.ports[i]) {
+			filtered.ports = append(filtered.ports, r.ports[i])
+		}
+	}
+	return filtered
+}
+
+// fail returns an empty Results that keeps the first error.
+func (r *Results) fail(err error) *Results {
+	if r.err != nil {
+		err = r.err
+	}
+	return &Results{err: err}
+}
+
+// ByService keeps the ports whose service is one of names.
+func (r *Results) ByService(names ...string) *Results {
+	return r.filter(func(hp *HostPort) bool {
+		return slices.Contains(names, hp.Port.Service.Name)
+	})
+}
+
+// ByPort keeps the ports numbered one of numbers.
+func (r *Results) ByPort(numbers ...int) *Results {
+	return r.filter(func(hp *HostPort) bool {
+		return slices.Contains(numbers, hp.Port.Number)
+	})
+}
+
+// ByState keeps the ports in one of states, e.g. "open" or "open|filtered".
+func (r *Results) ByState(states ...string) *Results {
+	return r.filter(func(hp *HostPort) bool {
+		return slices.Contains(states, hp.Port.State)
+	})
+}
+
+// ParseNetwork parses a CIDR, or an IP address as a single-host network.
+func ParseNetwork(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid address %q", s)
+		}
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, network, err := net.ParseCIDR(s)
+	return network, err
+}
+
+// ByCIDR keeps the ports of hosts in one of the networks cidrs, given as CIDRs
+// or single addresses.
+func (r *Results) ByCIDR(cidrs ...string) *Results {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		network, err := ParseNetwork(cidr)
+		if err != nil {
+			return r.fail(err)
+		}
+		networks = append(networks, network)
+	}
+	return r.filter(func(hp *HostPort) bool {
+		for _, network := range networks {
+			if hp.Host.IP != nil && network.Contains(hp.Host.IP) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ByProductRegex keeps the ports whose product matches the regular expression
+// expr.
+func (r *Results) ByProductRegex(expr string) *Results {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return r.fail(err)
+	}
+	return r.filter(func(hp *HostPort) bool {
+		return re.MatchString(hp.Port.Service.Product)
+	})
+}
+
+// Rows returns the matching host ports, in the order they were parsed.
+func (r *Results) Rows() []HostPort {
+	return append([]HostPort(nil), r.ports...)
+}
+
+// Hosts returns the distinct hosts with a matching port, in the order they were
+// parsed.
+func (r *Results) Hosts() []*Host {
+	var hosts []*Host
+	seen := make(map[*Host]bool)
+	for _, hp := range r.ports {
+		if !seen[hp.Host] {
+			seen[hp.Host] = true
+			hosts = append(hosts, hp.Host)
+		}
+	}
+	return hosts
+}
+
+// GroupByVersion groups the matching ports by service, product, version and
+// protocol, as the rows of the nmapTables report. Groups are ordered by
+// service, product and then numerically by version; their ports by host
+// address and port.
+func (r *Results) GroupByVersion() []VersionGroup {
+	type key struct{ service, product, version, protocol string }
+	index := make(map[key]int)
+	var groups []VersionGroup
+	for _, hp := range r.ports {
+		service := hp.Port.Service
+		k := key{service.Name, service.Product, service.Version, hp.Port.Protocol}
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, VersionGroup{Service: k.service, Product: k.product, Version: k.version, Protocol: k.protocol})
+		}
+		groups[i].Ports = append(groups[i].Ports, hp)
+	}
+
+	for _, group := range groups {
+		sort.Slice(group.Ports, func(i, j int) bool {
+			return group.Ports[i].String() < group.Ports[j].String()
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := &groups[i], &groups[j]
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		if a.Product != b.Product {
+			return a.Product < b.Product
+		}
+		if cmp := CompareVersions(a.Version, b.Version); cmp != 0 {
+			return cmp < 0
+		}
+		return a.Protocol < b.Protocol
+	})
+	return groups
+}
@@ -0,0 +1,171 @@
+package nmap
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+// testRuns returns two runs of the hosts the results are queried on.
+func testRuns() []*Run {
+	port := func(number int, protocol, state, service, product, version string) Port {
+		return Port{Number: number, Protocol: protocol, State: state, Service: Service{Name: service, Product: product, Version: version}}
+	}
+	return []*Run{
+		{Hosts: []Host{
+			{IP: net.ParseIP("10.0.0.5").To4(), Ports: []Port{
+				port(22, "tcp", "open", "ssh", "OpenSSH", "8.2p1"),
+				port(80, "tcp", "open", "http", "nginx", "1.18.0"),
+				port(161, "udp", "open|filtered", "snmp", "", ""),
+			}},
+			{IP: net.ParseIP("10.0.1.7").To4(), Ports: []Port{
+				port(22, "tcp", "open", "ssh", "OpenSSH", "10.0"),
+				port(443, "tcp", "closed", "https", "", ""),
+			}},
+		}},
+		{Hosts: []Host{
+			{IP: net.ParseIP("2001:db8::1"), Ports: []Port{
+				port(22, "tcp", "open", "ssh", "OpenSSH", "9.6"),
+				port(8080, "tcp", "open", "http", "Apache httpd", "2.4.58"),
+			}},
+		}},
+	}
+}
+
+func rowStrings(rows []HostPort) []string {
+	var out []string
+	for _, row := range rows {
+		out = append(out, row.String())
+	}
+	return out
+}
+
+func TestResultsFilters(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		query func(*Results) *Results
+		want  []string
+	}{
+		{"all", func(r *Results) *Results { return r }, []string{
+			"10.0.0.5:22", "10.0.0.5:80", "10.0.0.5:161", "10.0.1.7:22", "10.0.1.7:443", "[2001:db8::1]:22", "[2001:db8::1]:8080",
+		}},
+		{"service", func(r *Results) *Results { return r.ByService("http", "https") }, []string{
+			"10.0.0.5:80", "10.0.1.7:443", "[2001:db8::1]:8080",
+		}},
+		{"port", func(r *Results) *Results { return r.ByPort(22) }, []string{
+			"10.0.0.5:22", "10.0.1.7:22", "[2001:db8::1]:22",
+		}},
+		{"state", func(r *Results) *Results { return r.ByState("open|filtered", "closed") }, []string{
+			"10.0.0.5:161", "10.0.1.7:443",
+		}},
+		{"cidr", func(r *Results) *Results { return r.ByCIDR("10.0.1.0/24", "2001:db8::1") }, []string{
+			"10.0.1.7:22", "10.0.1.7:443", "[2001:db8::1]:22", "[2001:db8::1]:8080",
+		}},
+		{"product", func(r *Results) *Results { return r.ByProductRegex(`(?i)^(nginx|apache)`) }, []string{
+			"10.0.0.5:80", "[2001:db8::1]:8080",
+		}},
+		{"chained", func(r *Results) *Results { return r.ByState("open").ByService("ssh").ByCIDR("10.0.0.0/16") }, []string{
+			"10.0.0.5:22", "10.0.1.7:22",
+		}},
+		{"none", func(r *Results) *Results { return r.ByService("ftp") }, nil},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			results := test.query(NewResults(testRuns()...))
+			if err := results.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if got := rowStrings(results.Rows()); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Rows() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestResultsHosts(t *testing.T) {
+	var got []string
+	for _, host := range NewResults(testRuns()...).ByService("ssh", "http").Hosts() {
+		got = append(got, host.Address())
+	}
+	if want := []string{"10.0.0.5", "10.0.1.7", "2001:db8::1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Hosts() = %q, want %q", got, want)
+	}
+}
+
+func TestResultsErrors(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		query func(*Results) *Results
+	}{
+		{"invalid CIDR", func(r *Results) *Results { return r.ByCIDR("10.0.0.0/33") }},
+		{"invalid address", func(r *Results) *Results { return r.ByCIDR("10.0.0.0/24", "not-an-ip") }},
+		{"invalid regex", func(r *Results) *Results { return r.ByProductRegex("(") }},
+		{"error kept by later filters", func(r *Results) *Results { return r.ByProductRegex("[").ByState("open").ByCIDR("10.0.0.0/8") }},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			results := test.query(NewResults(testRuns()...))
+			if results.Err() == nil {
+				t.Error("Err() = nil, want an error")
+			}
+			if rows := results.Rows(); len(rows) != 0 {
+				t.Errorf("Rows() = %q, want none", rowStrings(rows))
+			}
+		})
+	}
+}
+
+func TestParseNetwork(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"10.0.0.5", "10.0.0.5/32"},
+		{"10.0.0.5/24", "10.0.0.0/24"},
+		{"2001:db8::1", "2001:db8::1/128"},
+		{"::ffff:10.0.0.5", "10.0.0.5/32"},
+	} {
+		network, err := ParseNetwork(test.in)
+		if err != nil || network.String() != test.want {
+			t.Errorf("ParseNetwork(%q) = %v, %v, want %s", test.in, network, err, test.want)
+		}
+	}
+	for _, in := range []string{"", "10.0.0.0/33", "web1.corp.local"} {
+		if _, err := ParseNetwork(in); err == nil {
+			t.Errorf("ParseNetwork(%q) returned no error", in)
+		}
+	}
+}
+
+func TestGroupByVersion(t *testing.T) {
+	groups := NewResults(testRuns()...).ByState("open").GroupByVersion()
+	type group struct {
+		service, product, version, protocol string
+		ports                               []string
+	}
+	var got []group
+	for _, g := range groups {
+		got = append(got, group{g.Service, g.Product, g.Version, g.Protocol, rowStrings(g.Ports)})
+	}
+	// Versions are ordered numerically: 8.2p1 before 9.6 before 10.0.
+	want := []group{
+		{"http", "Apache httpd", "2.4.58", "tcp", []string{"[2001:db8::1]:8080"}},
+		{"http", "nginx", "1.18.0", "tcp", []string{"10.0.0.5:80"}},
+		{"ssh", "OpenSSH", "8.2p1", "tcp", []string{"10.0.0.5:22"}},
+		{"ssh", "OpenSSH", "9.6", "tcp", []string{"[2001:db8::1]:22"}},
+		{"ssh", "OpenSSH", "10.0", "tcp", []string{"10.0.1.7:22"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GroupByVersion() =\n%v\nwant\n%v", got, want)
+	}
+
+	// The ports of a group are ordered by address and port.
+	runs := []*Run{{Hosts: []Host{
+		{IP: net.ParseIP("10.0.0.9").To4(), Ports: []Port{{Number: 80, Protocol: "tcp", Service: Service{Name: "http"}}}},
+		{IP: net.ParseIP("10.0.0.2").To4(), Ports: []Port{{Number: 8080, Protocol: "tcp", Service: Service{Name: "http"}}, {Number: 80, Protocol: "tcp", Service: Service{Name: "http"}}}},
+	}}}
+	groups = NewResults(runs...).GroupByVersion()
+	if len(groups) != 1 {
+		t.Fatalf("got %d groups, want 1", len(groups))
+	}
+	if got, want := rowStrings(groups[0].Ports), []string{"10.0.0.2:80", "10.0.0.2:8080", "10.0.0.9:80"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("group ports = %q, want %q", got, want)
+	}
+}
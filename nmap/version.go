@@ -0,0 +1,48 @@
+package nmap
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// VersionNumbers extracts the numeric components of the first word of version,
+// so that "7.4p1 Debian 10" yields [7 4 1].
+func VersionNumbers(version string) []int {
+	fields := strings.Fields(version)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	var numbers []int
+	for _, part := range strings.FieldsFunc(fields[0], func(r rune) bool { return !unicode.IsDigit(r) }) {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
+
+// CompareVersions compares two version strings numerically component by
+// component, returning -1, 0 or 1. Versions without numbers sort before those
+// with numbers, and versions with equal numbers fall back to string order.
+func CompareVersions(a, b string) int {
+	numsA, numsB := VersionNumbers(a), VersionNumbers(b)
+	for i := 0; i < len(numsA) && i < len(numsB); i++ {
+		if numsA[i] != numsB[i] {
+			if numsA[i] < numsB[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	if len(numsA) != len(numsB) {
+		if len(numsA) < len(numsB) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
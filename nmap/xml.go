@@ -0,0 +1,244 @@
+package nmap
+
+// The XML of nmap output as it is written, every attribute a string. Parse
+// reads it into the typed model of Run; programs needing what that model
+// leaves out, such as nmapTables itself, decode it into XMLRun with
+// encoding/xml.
+
+import "encoding/xml"
+
+// XMLRun is the nmaprun document of a scan.
+type XMLRun struct {
+	XMLName          xml.Name `xml:"nmaprun"`
+	Text             string   `xml:",chardata"`
+	Scanner          string   `xml:"scanner,attr"`
+	Args             string   `xml:"args,attr"`
+	Start            string   `xml:"start,attr"`
+	Startstr         string   `xml:"startstr,attr"`
+	Version          string   `xml:"version,attr"`
+	Xmloutputversion string   `xml:"xmloutputversion,attr"`
+	Scaninfo         []struct {
+		Text        string `xml:",chardata"`
+		Type        string `xml:"type,attr"`
+		Protocol    string `xml:"protocol,attr"`
+		Numservices string `xml:"numservices,attr"`
+		Services    string `xml:"services,attr"`
+	} `xml:"scaninfo"`
+	Verbose struct {
+		Text  string `xml:",chardata"`
+		Level string `xml:"level,attr"`
+	} `xml:"verbose"`
+	Debugging struct {
+		Text  string `xml:",chardata"`
+		Level string `xml:"level,attr"`
+	} `xml:"debugging"`
+	Taskbegin []struct {
+		Text string `xml:",chardata"`
+		Task string `xml:"task,attr"`
+		Time string `xml:"time,attr"`
+	} `xml:"taskbegin"`
+	Taskend []struct {
+		Text      string `xml:",chardata"`
+		Task      string `xml:"task,attr"`
+		Time      string `xml:"time,attr"`
+		Extrainfo string `xml:"extrainfo,attr"`
+	} `xml:"taskend"`
+	Hosthint struct {
+		Text   string `xml:",chardata"`
+		Status struct {
+			Text      string `xml:",chardata"`
+			State     string `xml:"state,attr"`
+			Reason    string `xml:"reason,attr"`
+			ReasonTtl string `xml:"reason_ttl,attr"`
+		} `xml:"status"`
+		Address []struct {
+			Text     string `xml:",chardata"`
+			Addr     string `xml:"addr,attr"`
+			Addrtype string `xml:"addrtype,attr"`
+			Vendor   string `xml:"vendor,attr"`
+		} `xml:"address"`
+		Hostnames string `xml:"hostnames"`
+	} `xml:"hosthint"`
+	Taskprogress []struct {
+		Text      string `xml:",chardata"`
+		Task      string `xml:"task,attr"`
+		Time      string `xml:"time,attr"`
+		Percent   string `xml:"percent,attr"`
+		Remaining string `xml:"remaining,attr"`
+		Etc       string `xml:"etc,attr"`
+	} `xml:"taskprogress"`
+	Host     []XMLHost `xml:"host"`
+	Runstats struct {
+		Text     string `xml:",chardata"`
+		Finished struct {
+			Text    string `xml:",chardata"`
+			Time    string `xml:"time,attr"`
+			Timestr string `xml:"timestr,attr"`
+			Summary string `xml:"summary,attr"`
+			Elapsed string `xml:"elapsed,attr"`
+			Exit    string `xml:"exit,attr"`
+		} `xml:"finished"`
+		Hosts struct {
+			Text  string `xml:",chardata"`
+			Up    string `xml:"up,attr"`
+			Down  string `xml:"down,attr"`
+			Total string `xml:"total,attr"`
+		} `xml:"hosts"`
+	} `xml:"runstats"`
+}
+
+// XMLHost is a scanned host.
+type XMLHost struct {
+	Text      string `xml:",chardata"`
+	Starttime string `xml:"starttime,attr"`
+	Endtime   string `xml:"endtime,attr"`
+	Status    struct {
+		Text      string `xml:",chardata"`
+		State     string `xml:"state,attr"`
+		Reason    string `xml:"reason,attr"`
+		ReasonTtl string `xml:"reason_ttl,attr"`
+	} `xml:"status"`
+	Address   []XMLAddress `xml:"address"`
+	Hostnames struct {
+		Text     string        `xml:",chardata"`
+		Hostname []XMLHostname `xml:"hostname"`
+	} `xml:"hostnames"`
+	Ports struct {
+		Text string    `xml:",chardata"`
+		Port []XMLPort `xml:"port"`
+	} `xml:"ports"`
+	Os         XMLOS    `xml:"os"`
+	Trace      XMLTrace `xml:"trace"`
+	Hostscript struct {
+		Text   string      `xml:",chardata"`
+		Script []XMLScript `xml:"script"`
+	} `xml:"hostscript"`
+	Times struct {
+		Text   string `xml:",chardata"`
+		Srtt   string `xml:"srtt,attr"`
+		Rttvar string `xml:"rttvar,attr"`
+		To     string `xml:"to,attr"`
+	} `xml:"times"`
+}
+
+// XMLOS is the OS detection of a host.
+type XMLOS struct {
+	Text     string `xml:",chardata"`
+	Portused []struct {
+		Text   string `xml:",chardata"`
+		State  string `xml:"state,attr"`
+		Proto  string `xml:"proto,attr"`
+		Portid string `xml:"portid,attr"`
+	} `xml:"portused"`
+	Osmatch []XMLOSMatch `xml:"osmatch"`
+}
+
+// XMLOSMatch is an OS fingerprint match.
+type XMLOSMatch struct {
+	Text     string `xml:",chardata"`
+	Name     string `xml:"name,attr"`
+	Accuracy string `xml:"accuracy,attr"`
+	Line     string `xml:"line,attr"`
+	Osclass  []struct {
+		Text     string   `xml:",chardata"`
+		Type     string   `xml:"type,attr"`
+		Vendor   string   `xml:"vendor,attr"`
+		Osfamily string   `xml:"osfamily,attr"`
+		Osgen    string   `xml:"osgen,attr"`
+		Accuracy string   `xml:"accuracy,attr"`
+		Cpe      []string `xml:"cpe"`
+	} `xml:"osclass"`
+}
+
+// XMLTrace is the traceroute of a host.
+type XMLTrace struct {
+	Text  string   `xml:",chardata"`
+	Port  string   `xml:"port,attr"`
+	Proto string   `xml:"proto,attr"`
+	Hop   []XMLHop `xml:"hop"`
+}
+
+// XMLHop is a hop of a traceroute.
+type XMLHop struct {
+	Text   string `xml:",chardata"`
+	Ttl    string `xml:"ttl,attr"`
+	Ipaddr string `xml:"ipaddr,attr"`
+	Rtt    string `xml:"rtt,attr"`
+	Host   string `xml:"host,attr"`
+}
+
+// XMLAddress is an IPv4, IPv6 or MAC address of a host.
+type XMLAddress struct {
+	Text     string `xml:",chardata"`
+	Addr     string `xml:"addr,attr"`
+	Addrtype string `xml:"addrtype,attr"`
+	Vendor   string `xml:"vendor,attr"`
+}
+
+// XMLHostname is a hostname of a host.
+type XMLHostname struct {
+	Text string `xml:",chardata"`
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// XMLPort is a scanned port of a host.
+type XMLPort struct {
+	Text     string `xml:",chardata"`
+	Protocol string `xml:"protocol,attr"`
+	Portid   string `xml:"portid,attr"`
+	State    struct {
+		Text      string `xml:",chardata"`
+		State     string `xml:"state,attr"`
+		Reason    string `xml:"reason,attr"`
+		ReasonTtl string `xml:"reason_ttl,attr"`
+	} `xml:"state"`
+	// Owner is the user running the service, as reported by an ident server
+	// to nmap's ident scan.
+	Owner struct {
+		Name string `xml:"name,attr"`
+	} `xml:"owner"`
+	Service XMLService  `xml:"service"`
+	Script  []XMLScript `xml:"script"`
+}
+
+// XMLScript is the output of an NSE script, along with the structured
+// elements and tables of scripts that produce them.
+type XMLScript struct {
+	Text   string     `xml:",chardata"`
+	ID     string     `xml:"id,attr"`
+	Output string     `xml:"output,attr"`
+	Elem   []XMLElem  `xml:"elem"`
+	Table  []XMLTable `xml:"table"`
+}
+
+// XMLElem is a key and value of structured script output.
+type XMLElem struct {
+	Text string `xml:",chardata"`
+	Key  string `xml:"key,attr"`
+}
+
+// XMLTable is a nested table of structured script output.
+type XMLTable struct {
+	Text  string     `xml:",chardata"`
+	Key   string     `xml:"key,attr"`
+	Elem  []XMLElem  `xml:"elem"`
+	Table []XMLTable `xml:"table"`
+}
+
+// XMLService is the service detected on a port.
+type XMLService struct {
+	Text      string `xml:",chardata"`
+	Name      string `xml:"name,attr"`
+	Product   string `xml:"product,attr"`
+	Ostype    string `xml:"ostype,attr"`
+	Method    string `xml:"method,attr"`
+	Conf      string `xml:"conf,attr"`
+	Version   string `xml:"version,attr"`
+	Extrainfo string `xml:"extrainfo,attr"`
+	Tunnel    string `xml:"tunnel,attr"`
+	// Devicetype is the kind of device nmap's version detection recognised,
+	// e.g. router or printer.
+	Devicetype string `xml:"devicetype,attr"`
+	Cpe        string `xml:"cpe"`
+}
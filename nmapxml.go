@@ -0,0 +1,261 @@
+package main
+
+import (
+	"cmp"
+	"encoding/xml"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nmapXMLOutputVersion is the nmap XML output version of the nmap-xml export.
+const nmapXMLOutputVersion = "1.05"
+
+// Nmap XML documents written by the nmap-xml export. Unlike Nmaprun they leave
+// out the empty elements and attributes, which some importers choke on.
+type (
+	nmapXMLRun struct {
+		XMLName          xml.Name        `xml:"nmaprun"`
+		Scanner          string          `xml:"scanner,attr"`
+		Args             string          `xml:"args,attr"`
+		Start            string          `xml:"start,attr,omitempty"`
+		Startstr         string          `xml:"startstr,attr,omitempty"`
+		Xmloutputversion string          `xml:"xmloutputversion,attr"`
+		Hosts            []nmapXMLHost   `xml:"host"`
+		Runstats         nmapXMLRunstats `xml:"runstats"`
+	}
+	nmapXMLHost struct {
+		Status     nmapXMLState      `xml:"status"`
+		Address    []nmapXMLAddress  `xml:"address"`
+		Hostnames  []nmapXMLHostname `xml:"hostnames>hostname"`
+		Ports      []nmapXMLPort     `xml:"ports>port"`
+		Os         *nmapXMLOs        `xml:"os"`
+		Hostscript *nmapXMLScripts   `xml:"hostscript"`
+	}
+	nmapXMLOs struct {
+		Osmatch []nmapXMLOsmatch `xml:"osmatch"`
+	}
+	nmapXMLScripts struct {
+		Script []nmapXMLScript `xml:"script"`
+	}
+	nmapXMLState struct {
+		State     string `xml:"state,attr"`
+		Reason    string `xml:"reason,attr,omitempty"`
+		ReasonTtl string `xml:"reason_ttl,attr,omitempty"`
+	}
+	nmapXMLAddress struct {
+		Addr     string `xml:"addr,attr"`
+		Addrtype string `xml:"addrtype,attr"`
+		Vendor   string `xml:"vendor,attr,omitempty"`
+	}
+	nmapXMLHostname struct {
+		Name string `xml:"name,attr"`
+		Type string `xml:"type,attr,omitempty"`
+	}
+	nmapXMLPort struct {
+		Protocol string          `xml:"protocol,attr"`
+		Portid   string          `xml:"portid,attr"`
+		State    nmapXMLState    `xml:"state"`
+		Service  nmapXMLService  `xml:"service"`
+		Script   []nmapXMLScript `xml:"script"`
+	}
+	nmapXMLService struct {
+		Name       string `xml:"name,attr"`
+		Product    string `xml:"product,attr,omitempty"`
+		Version    string `xml:"version,attr,omitempty"`
+		Extrainfo  string `xml:"extrainfo,attr,omitempty"`
+		Ostype     string `xml:"ostype,attr,omitempty"`
+		Devicetype string `xml:"devicetype,attr,omitempty"`
+		Tunnel     string `xml:"tunnel,attr,omitempty"`
+		Method     string `xml:"method,attr,omitempty"`
+		Conf       string `xml:"conf,attr,omitempty"`
+		Cpe        string `xml:"cpe,omitempty"`
+	}
+	nmapXMLScript struct {
+		ID     string      `xml:"id,attr"`
+		Output string      `xml:"output,attr"`
+		Elem   []NmapElem  `xml:"elem"`
+		Table  []NmapTable `xml:"table"`
+	}
+	nmapXMLOsmatch struct {
+		Name     string `xml:"name,attr"`
+		Accuracy string `xml:"accuracy,attr"`
+	}
+	nmapXMLRunstats struct {
+		Finished struct {
+			Time    string `xml:"time,attr,omitempty"`
+			Timestr string `xml:"timestr,attr,omitempty"`
+			Exit    string `xml:"exit,attr"`
+		} `xml:"finished"`
+		Hosts struct {
+			Up    string `xml:"up,attr"`
+			Down  string `xml:"down,attr"`
+			Total string `xml:"total,attr"`
+		} `xml:"hosts"`
+	}
+)
+
+// nmapXMLScriptsOf returns scripts without the whitespace nmap indents them
+// with.
+func nmapXMLScriptsOf(scripts []NmapScript) []nmapXMLScript {
+	var result []nmapXMLScript
+	for _, script := range scripts {
+		result = append(result, nmapXMLScript{ID: script.ID, Output: script.Output, Elem: script.Elem, Table: script.Table})
+	}
+	return result
+}
+
+// nmapXMLHostPort returns the port of hostPort, a host port of row, as written
+// by the nmap-xml export: the merged port it was built from or, for the host
+// ports of other tables, what the row knows of it.
+func nmapXMLHostPort(row *ReportRow, hostPort *HostPort) nmapXMLPort {
+	if port := hostPort.port; port != nil {
+		return nmapXMLPort{
+			Protocol: port.Protocol,
+			Portid:   port.Portid,
+			State:    nmapXMLState{State: port.State.State, Reason: port.State.Reason, ReasonTtl: port.State.ReasonTtl},
+			Service: nmapXMLService{
+				Name:       cmp.Or(port.Service.Name, "unknown"),
+				Product:    port.Service.Product,
+				Version:    port.Service.Version,
+				Extrainfo:  port.Service.Extrainfo,
+				Ostype:     port.Service.Ostype,
+				Devicetype: port.Service.Devicetype,
+				Tunnel:     port.Service.Tunnel,
+				Method:     port.Service.Method,
+				Conf:       port.Service.Conf,
+				Cpe:        port.Service.Cpe,
+			},
+			Script: nmapXMLScriptsOf(port.Script),
+		}
+	}
+	service := strings.TrimPrefix(row.Service, "ssl/")
+	if strings.Contains(service, ",") || service == "" {
+		service = "unknown"
+	}
+	var product, version string
+	if len(row.versions) == 1 {
+		product, version = row.versions[0].product, row.versions[0].version
+	}
+	return nmapXMLPort{
+		Protocol: row.Protocol,
+		Portid:   hostPort.Port,
+		State:    nmapXMLState{State: "open", Reason: hostPort.Reason, ReasonTtl: hostPort.ReasonTTL},
+		Service:  nmapXMLService{Name: service, Product: product, Version: version, Extrainfo: hostPort.ExtraInfo, Tunnel: hostPort.Tunnel, Cpe: hostPort.CPE},
+	}
+}
+
+// nmapXMLHostOf returns the host of hostPort, without ports, as written by the
+// nmap-xml export.
+func nmapXMLHostOf(hostPort *HostPort) nmapXMLHost {
+	host := hostPort.host
+	if host == nil {
+		result := nmapXMLHost{Status: nmapXMLState{State: "up"}, Address: []nmapXMLAddress{{Addr: hostPort.Addr, Addrtype: addrType(hostPort.Addr)}}}
+		if hostPort.Hostname != "" {
+			result.Hostnames = []nmapXMLHostname{{Name: hostPort.Hostname, Type: "user"}}
+		}
+		return result
+	}
+	result := nmapXMLHost{
+		Status: nmapXMLState{State: host.Status.State, Reason: host.Status.Reason, ReasonTtl: host.Status.ReasonTtl},
+	}
+	if len(host.Hostscript.Script) > 0 {
+		result.Hostscript = &nmapXMLScripts{Script: nmapXMLScriptsOf(host.Hostscript.Script)}
+	}
+	for _, address := range host.Address {
+		result.Address = append(result.Address, nmapXMLAddress{Addr: address.Addr, Addrtype: address.Addrtype, Vendor: address.Vendor})
+	}
+	for _, hostname := range host.Hostnames.Hostname {
+		result.Hostnames = append(result.Hostnames, nmapXMLHostname{Name: hostname.Name, Type: hostname.Type})
+	}
+	if len(host.Os.Osmatch) > 0 {
+		result.Os = &nmapXMLOs{}
+		for _, match := range host.Os.Osmatch {
+			result.Os.Osmatch = append(result.Os.Osmatch, nmapXMLOsmatch{Name: match.Name, Accuracy: match.Accuracy})
+		}
+	}
+	return result
+}
+
+// addrType returns the nmap addrtype of addr: ipv6 or ipv4.
+func addrType(addr string) string {
+	if strings.Contains(addr, ":") {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
+// writeNmapXML writes the host ports of tableData, verified or not, to w as a
+// synthetic nmap XML document with a host per address, in address order, and
+// each matching port once, so that tools that only read nmap XML, such as
+// EyeWitness or Metasploit's db_import, get the merged and filtered results.
+// Ports observed by several scans with --merge all are written as last seen.
+func writeNmapXML(tableData ReportData, w io.Writer) error {
+	run := nmapXMLRun{Scanner: "nmap", Args: "nmapTables --service " + tableData.Service, Xmloutputversion: nmapXMLOutputVersion}
+	if start := tableData.Summary.ScanStart; !start.IsZero() {
+		run.Start = strconv.FormatInt(start.Unix(), 10)
+		run.Startstr = start.Format(time.ANSIC)
+	}
+
+	hosts := make(map[string]*nmapXMLHost)
+	var addrs []string
+	// seen is when each written port, by address and protocol/port, was seen.
+	seen := make(map[string]time.Time)
+	for _, rows := range [][]ReportRow{tableData.Rows, tableData.Unverified} {
+		for i := range rows {
+			row := &rows[i]
+			for j := range row.Hosts {
+				hostPort := &row.Hosts[j]
+				host, ok := hosts[hostPort.Addr]
+				if !ok {
+					written := nmapXMLHostOf(hostPort)
+					host = &written
+					hosts[hostPort.Addr] = host
+					addrs = append(addrs, hostPort.Addr)
+				}
+				port := nmapXMLHostPort(row, hostPort)
+				key := hostPort.Addr + " " + port.Protocol + "/" + port.Portid
+				last, ok := seen[key]
+				if ok && !hostPort.Seen.After(last) {
+					continue
+				}
+				seen[key] = hostPort.Seen
+				host.Ports = slices.DeleteFunc(host.Ports, func(p nmapXMLPort) bool { return p.Protocol == port.Protocol && p.Portid == port.Portid })
+				host.Ports = append(host.Ports, port)
+			}
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool { return lessAddr(addrs[i], addrs[j]) })
+	for _, addr := range addrs {
+		host := hosts[addr]
+		sort.SliceStable(host.Ports, func(i, j int) bool {
+			if host.Ports[i].Protocol != host.Ports[j].Protocol {
+				return host.Ports[i].Protocol < host.Ports[j].Protocol
+			}
+			return atoi(host.Ports[i].Portid) < atoi(host.Ports[j].Portid)
+		})
+		run.Hosts = append(run.Hosts, *host)
+	}
+
+	if end := tableData.Summary.ScanEnd; !end.IsZero() {
+		run.Runstats.Finished.Time = strconv.FormatInt(end.Unix(), 10)
+		run.Runstats.Finished.Timestr = end.Format(time.ANSIC)
+	}
+	run.Runstats.Finished.Exit = "success"
+	up := strconv.Itoa(len(run.Hosts))
+	run.Runstats.Hosts.Up, run.Runstats.Hosts.Down, run.Runstats.Hosts.Total = up, "0", up
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(run); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
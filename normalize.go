@@ -0,0 +1,123 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed normalize.yaml
+var embeddedNormalizeRules []byte
+
+// NormalizeRule rewrites the product or version of the ports matched by its
+// RowMatcher, replacing the matches of Pattern with Replace.
+type NormalizeRule struct {
+	Name       string `yaml:"name"`
+	RowMatcher `yaml:",inline"`
+	Field      string `yaml:"field"`
+	Pattern    string `yaml:"pattern"`
+	Replace    string `yaml:"replace"`
+
+	pattern *regexp.Regexp
+}
+
+// normalizeFile is the layout of a YAML normalization rules file.
+type normalizeFile struct {
+	Rules []NormalizeRule `yaml:"rules"`
+}
+
+// LoadNormalizeRules returns the normalization rules: those of the file at
+// path, if any, followed by the embedded ones.
+func LoadNormalizeRules(path string) ([]NormalizeRule, error) {
+	var rules []NormalizeRule
+	if path != "" {
+		var file normalizeFile
+		if err := readYAMLFile(path, &file); err != nil {
+			return nil, err
+		}
+		if err := compileNormalizeRules(file.Rules); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		rules = append(rules, file.Rules...)
+	}
+
+	var embedded normalizeFile
+	if err := yaml.Unmarshal(embeddedNormalizeRules, &embedded); err != nil {
+		return nil, fmt.Errorf("parsing embedded normalization rules: %w", err)
+	}
+	if err := compileNormalizeRules(embedded.Rules); err != nil {
+		return nil, fmt.Errorf("embedded normalization rules: %w", err)
+	}
+	return append(rules, embedded.Rules...), nil
+}
+
+// compileNormalizeRules prepares rules for matching.
+func compileNormalizeRules(rules []NormalizeRule) error {
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Field != "product" && rule.Field != "version" {
+			return fmt.Errorf("rule %d (%s): invalid field %q (use product or version)", i+1, rule.Name, rule.Field)
+		}
+		if rule.Pattern == "" {
+			return fmt.Errorf("rule %d (%s): missing pattern", i+1, rule.Name)
+		}
+		var err error
+		if rule.pattern, err = compilePattern(rule.Pattern); err != nil {
+			return fmt.Errorf("rule %d (%s): %w", i+1, rule.Name, err)
+		}
+		if err := rule.compile(); err != nil {
+			return fmt.Errorf("rule %d (%s): %w", i+1, rule.Name, err)
+		}
+	}
+	return nil
+}
+
+// normalizeService rewrites the product and version of service with rules.
+// The extra info nmap also put into the product or version is trimmed
+// first, and runs of whitespace are collapsed.
+func normalizeService(service *NmapService, rules []NormalizeRule) {
+	if len(rules) == 0 {
+		return
+	}
+	product, version := strings.Join(strings.Fields(service.Product), " "), strings.Join(strings.Fields(service.Version), " ")
+	if extra := strings.TrimSpace(service.Extrainfo); extra != "" {
+		for _, suffix := range []string{"(" + extra + ")", extra} {
+			product = strings.TrimSpace(strings.TrimSuffix(product, suffix))
+			version = strings.TrimSpace(strings.TrimSuffix(version, suffix))
+		}
+	}
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Matches(&ReportRow{Service: service.Name, Product: product, ProductVersion: version}) {
+			continue
+		}
+		if rule.Field == "product" {
+			product = strings.TrimSpace(rule.pattern.ReplaceAllString(product, rule.Replace))
+		} else {
+			version = strings.TrimSpace(rule.pattern.ReplaceAllString(version, rule.Replace))
+		}
+	}
+	if product != service.Product || version != service.Version {
+		logger.Debug("normalized service", "product", service.Product, "version", service.Version, "to_product", product, "to_version", version)
+	}
+	service.Product, service.Version = product, version
+}
+
+// normalizeHosts normalizes the service of every port of hosts with the
+// --normalize rules.
+func normalizeHosts(hosts []NmapHost, rules []NormalizeRule) {
+	if len(rules) == 0 {
+		return
+	}
+	for i := range hosts {
+		// The merged hosts may share their ports with the parsed runs.
+		hosts[i].Ports.Port = slices.Clone(hosts[i].Ports.Port)
+		for j := range hosts[i].Ports.Port {
+			normalizeService(&hosts[i].Ports.Port[j].Service, rules)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// notifyListLimit is the number of new hosts and ports listed in the text of
+// a notification; the payload of generic webhooks has all of them.
+const notifyListLimit = 20
+
+// notification is the summary posted to the --notify webhook.
+type notification struct {
+	Title string `json:"title"`
+	// Text is the summary as a message, for webhooks that only show text.
+	Text     string   `json:"text"`
+	Report   string   `json:"report,omitempty"`
+	Hosts    int      `json:"hosts"`
+	Ports    int      `json:"ports"`
+	NewHosts []string `json:"new_hosts"`
+	NewPorts []string `json:"new_ports"`
+	Changed  []string `json:"changed,omitempty"`
+	Removed  []string `json:"removed,omitempty"`
+}
+
+// message returns the summary of n as text, listing at most notifyListLimit
+// new hosts and ports.
+func (n notification) message() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s, %s", n.Title, plural(n.Hosts, "host"), plural(n.Ports, "port"))
+	if len(n.Changed) > 0 || len(n.Removed) > 0 {
+		fmt.Fprintf(&b, ", %d changed, %d gone", len(n.Changed), len(n.Removed))
+	}
+	b.WriteString("\n")
+	if n.Report != "" {
+		fmt.Fprintf(&b, "Report: %s\n", n.Report)
+	}
+	list := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		shown := items[:min(len(items), notifyListLimit)]
+		fmt.Fprintf(&b, "%s (%d): %s", title, len(items), strings.Join(shown, ", "))
+		if len(items) > len(shown) {
+			fmt.Fprintf(&b, " and %d more", len(items)-len(shown))
+		}
+		b.WriteString("\n")
+	}
+	list("New hosts", n.NewHosts)
+	list("New open ports", n.NewPorts)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// reportNotification summarizes the reports of services written to the report
+// path, with the hosts and ports that are new since the previous reports of
+// --watch mode.
+func reportNotification(services []string, reports []ReportData, report string, newHosts, newPorts []string) notification {
+	n := notification{Report: report, NewHosts: newHosts, NewPorts: newPorts}
+	hosts := make(map[string]struct{})
+	for _, data := range reports {
+		for _, rows := range [][]ReportRow{data.Rows, data.Unverified} {
+			for _, row := range rows {
+				for _, host := range row.Hosts {
+					hosts[host.Addr] = struct{}{}
+					n.Ports++
+				}
+			}
+		}
+	}
+	n.Title = fmt.Sprintf("nmapTables %s report", strings.Join(services, ", "))
+	n.Hosts = len(hosts)
+	n.Text = n.message()
+	return n
+}
+
+// diffNotification summarizes the entries of the diff between oldRuns and
+// newRuns, counting the hosts and ports of newRuns.
+func diffNotification(entries []DiffEntry, oldRuns, newRuns []Nmaprun, opts TableOptions) notification {
+	n := notification{Title: "nmapTables diff"}
+	hosts := make(map[string]struct{})
+	for key := range observePorts(newRuns, opts) {
+		hosts[diffHost(key)] = struct{}{}
+		n.Ports++
+	}
+	n.Hosts = len(hosts)
+	n.NewHosts, n.NewPorts = scanNews(oldRuns, entries, opts)
+	for _, entry := range entries {
+		switch entry.Change {
+		case "~":
+			n.Changed = append(n.Changed, entry.HostPort)
+		case "-":
+			n.Removed = append(n.Removed, entry.HostPort)
+		}
+	}
+	n.Text = n.message()
+	return n
+}
+
+// scanNews returns the hosts of the new ports of entries, a diff from oldRuns,
+// that had no port matching opts in oldRuns, and the new ports.
+func scanNews(oldRuns []Nmaprun, entries []DiffEntry, opts TableOptions) (newHosts, newPorts []string) {
+	known := make(map[string]bool)
+	for key := range observePorts(oldRuns, opts) {
+		known[diffHost(key)] = true
+	}
+	for _, entry := range entries {
+		if entry.Change != "+" {
+			continue
+		}
+		newPorts = append(newPorts, entry.HostPort)
+		if host := diffHost(entry.HostPort); !known[host] {
+			known[host] = true
+			newHosts = append(newHosts, host)
+		}
+	}
+	sort.Slice(newHosts, func(i, j int) bool { return lessAddr(newHosts[i], newHosts[j]) })
+	return newHosts, newPorts
+}
+
+// diffHost returns the address of a DiffEntry host port.
+func diffHost(hostPort string) string {
+	hostPort, _, _ = strings.Cut(hostPort, "/")
+	host, _, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return hostPort
+	}
+	return host
+}
+
+// notifier posts notifications to a Slack, Microsoft Teams or generic JSON
+// webhook.
+type notifier struct {
+	endpoint string
+	// kind is "slack", "teams" or "generic", chosen by the webhook host.
+	kind   string
+	client *http.Client
+}
+
+// newNotifier returns a notifier for the webhook at rawURL.
+func newNotifier(rawURL string) (*notifier, error) {
+	endpoint, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme: %q (use https or http)", endpoint.Scheme)
+	}
+	kind := "generic"
+	switch host := endpoint.Hostname(); {
+	case host == "hooks.slack.com":
+		kind = "slack"
+	case strings.HasSuffix(host, ".webhook.office.com") || host == "outlook.office.com" || strings.HasSuffix(host, ".logic.azure.com"):
+		kind = "teams"
+	}
+	return &notifier{
+		endpoint: endpoint.String(),
+		kind:     kind,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// payload returns the body of the webhook request of n.
+func (c *notifier) payload(n notification) any {
+	switch c.kind {
+	case "slack":
+		return map[string]string{"text": n.Text}
+	case "teams":
+		// A MessageCard, shown as is by Teams incoming webhooks.
+		return map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  n.Title,
+			"title":    n.Title,
+			"text":     strings.ReplaceAll(strings.TrimPrefix(n.Text, n.Title+": "), "\n", "\n\n"),
+		}
+	}
+	// Empty lists rather than null, for the receivers of generic webhooks.
+	if n.NewHosts == nil {
+		n.NewHosts = []string{}
+	}
+	if n.NewPorts == nil {
+		n.NewPorts = []string{}
+	}
+	return n
+}
+
+// send posts n to the webhook.
+func (c *notifier) send(n notification) error {
+	body, err := json.Marshal(c.payload(n))
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Post(c.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("notify: webhook returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	logger.Debug("posted notification", "kind", c.kind, "webhook", webhookHost(c.endpoint))
+	return nil
+}
+
+// webhookHost returns rawURL without its path, which holds the secret of
+// Slack and Teams webhooks, for logging.
+func webhookHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
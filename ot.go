@@ -0,0 +1,151 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// otProtocol is an industrial control protocol, recognized by the nmap
+// service names, well-known ports and NSE scripts of its devices.
+type otProtocol struct {
+	name     string
+	services []string
+	// ports are the well-known ports of the protocol, as protocol/port.
+	ports   []string
+	scripts []string
+}
+
+// otProtocols are the industrial protocols that are tagged in reports.
+var otProtocols = []otProtocol{
+	{name: "Modbus", services: []string{"modbus", "mbap"}, ports: []string{"tcp/502"}, scripts: []string{"modbus-discover"}},
+	{name: "Siemens S7", services: []string{"iso-tsap", "s7"}, ports: []string{"tcp/102"}, scripts: []string{"s7-info"}},
+	{name: "DNP3", services: []string{"dnp", "dnp3"}, ports: []string{"tcp/20000", "udp/20000"}},
+	{name: "EtherNet/IP", services: []string{"EtherNetIP-1", "EtherNetIP-2", "EtherNet-IP-1", "EtherNet-IP-2", "enip"}, ports: []string{"tcp/44818", "udp/44818", "udp/2222"}, scripts: []string{"enip-info"}},
+	{name: "BACnet", services: []string{"bacnet"}, ports: []string{"udp/47808"}, scripts: []string{"bacnet-info"}},
+}
+
+// otProtocolName returns the name of the industrial protocol of the service
+// on protocol/port, or "" when it is not one.
+func otProtocolName(service, protocol, port string) string {
+	for _, ot := range otProtocols {
+		for _, name := range ot.services {
+			if service == name {
+				return ot.name
+			}
+		}
+		for _, p := range ot.ports {
+			if p == protocol+"/"+port {
+				return ot.name
+			}
+		}
+	}
+	return ""
+}
+
+// portOTProtocol returns the industrial protocol of port, also recognized by
+// the output of its NSE scripts.
+func portOTProtocol(port *NmapPort) string {
+	if name := otProtocolName(port.Service.Name, port.Protocol, port.Portid); name != "" {
+		return name
+	}
+	for _, ot := range otProtocols {
+		for _, id := range ot.scripts {
+			for _, script := range port.Script {
+				if script.ID == id {
+					return ot.name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// OTExposure is a host port speaking an industrial protocol.
+type OTExposure struct {
+	Host     HostPort `json:"host"`
+	Protocol string   `json:"protocol"`
+	Service  string   `json:"service"`
+	Product  string   `json:"product,omitempty"`
+	Version  string   `json:"version,omitempty"`
+}
+
+// Label returns the product and version for display.
+func (e OTExposure) Label() string {
+	return strings.TrimSpace(e.Product + " " + e.Version)
+}
+
+// FindOTExposures returns the ports of runs speaking an industrial protocol
+// that match the state and exclusion filters of opts, ordered by host and
+// port. opts.ServiceName is ignored.
+func FindOTExposures(runs []Nmaprun, opts TableOptions) []OTExposure {
+	var exposures []OTExposure
+	hosts := opts.mergeHosts(runs)
+	for i := range hosts {
+		host := &hosts[i]
+		addr := hostAddress(host, opts.PreferIPv6)
+		if opts.excluded(addr) {
+			continue
+		}
+		for j := range host.Ports.Port {
+			port := &host.Ports.Port[j]
+			if !opts.States[port.State.State] {
+				continue
+			}
+			name := portOTProtocol(port)
+			if name == "" {
+				continue
+			}
+			exposure := OTExposure{
+				Host:     HostPort{Addr: addr, Port: port.Portid},
+				Protocol: name,
+				Service:  port.Service.Name,
+				Product:  port.Service.Product,
+				Version:  port.Service.Version,
+			}
+			if len(host.Hostnames.Hostname) > 0 {
+				exposure.Host.Hostname = host.Hostnames.Hostname[0].Name
+			}
+			exposures = append(exposures, exposure)
+		}
+	}
+	sort.SliceStable(exposures, func(i, j int) bool {
+		if exposures[i].Host.Addr != exposures[j].Host.Addr {
+			return lessAddr(exposures[i].Host.Addr, exposures[j].Host.Addr)
+		}
+		return atoi(exposures[i].Host.Port) < atoi(exposures[j].Host.Port)
+	})
+	return exposures
+}
+
+// otWarning is the note at the start of the OT section of reports.
+const otWarning = "These hosts speak industrial control protocols. Active scanning and testing can disrupt the physical processes they control: coordinate with the asset owner before touching them."
+
+// Row fills of PDF and DOCX tables, matching the HTML report.
+const (
+	eolFill = "FFCDD2"
+	otFill  = "FFE0B2"
+)
+
+// rowFill returns the fill color of row in PDF and DOCX tables: end-of-life
+// versions like the HTML report, then industrial protocols.
+func rowFill(row *ReportRow) string {
+	switch {
+	case row.EOL != "":
+		return eolFill
+	case row.OT != "":
+		return otFill
+	}
+	return ""
+}
+
+// applyOT tags the rows whose ports speak an industrial protocol.
+func applyOT(rows []ReportRow) {
+	for i := range rows {
+		for _, host := range rows[i].Hosts {
+			if name := otProtocolName(rows[i].Service, rows[i].Protocol, host.Port); name != "" {
+				rows[i].OT = name
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// directlyConnected is the router name used for hosts reached without an
+	// intermediate hop.
+	directlyConnected = "directly connected"
+	// noTraceData is the router name used for hosts without traceroute data.
+	noTraceData = "no trace data"
+)
+
+// routerKey identifies the last-hop router in front of a host.
+type routerKey struct {
+	Router string
+	TTL    int
+}
+
+// PathRow groups the hosts reached through the same last-hop router.
+type PathRow struct {
+	Router string `json:"router"`
+	// TTL is the hop distance from the scanner to the router.
+	TTL   int        `json:"ttl"`
+	Hosts []HostPort `json:"hosts"`
+}
+
+// lastHopRouter returns the router immediately in front of host according to
+// its traceroute.
+func lastHopRouter(host *NmapHost) routerKey {
+	hops := append([]NmapHop(nil), host.Trace.Hop...)
+	if len(hops) == 0 {
+		return routerKey{Router: noTraceData}
+	}
+	sort.SliceStable(hops, func(i, j int) bool {
+		return atoi(hops[i].Ttl) < atoi(hops[j].Ttl)
+	})
+
+	// The final hop is the target itself.
+	if len(hops) == 1 {
+		return routerKey{Router: directlyConnected}
+	}
+	hop := hops[len(hops)-2]
+	router := hop.Ipaddr
+	if hop.Host != "" {
+		router = fmt.Sprintf("%s (%s)", hop.Ipaddr, hop.Host)
+	}
+	return routerKey{Router: router, TTL: atoi(hop.Ttl)}
+}
+
+// buildPathRows turns host ports grouped by router into rows sorted by hop
+// distance and router. Hosts without trace data are listed last.
+func buildPathRows(pathMap map[routerKey][]HostPort) []PathRow {
+	var rows []PathRow
+	for key, hosts := range pathMap {
+		sort.Slice(hosts, func(i, j int) bool {
+			return hosts[i].String() < hosts[j].String()
+		})
+		rows = append(rows, PathRow{Router: key.Router, TTL: key.TTL, Hosts: hosts})
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if (rows[i].Router == noTraceData) != (rows[j].Router == noTraceData) {
+			return rows[j].Router == noTraceData
+		}
+		if rows[i].TTL != rows[j].TTL {
+			return rows[i].TTL < rows[j].TTL
+		}
+		return rows[i].Router < rows[j].Router
+	})
+	return rows
+}
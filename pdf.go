@@ -0,0 +1,383 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// pdfLineHeight is the height in mm of a line of table text.
+const pdfLineHeight = 5
+
+// pdfReport writes a paginated, print-ready report with a cover page and one
+// section per service.
+type pdfReport struct {
+	pdf *fpdf.Fpdf
+	// tr converts UTF-8 text to the encoding of the built-in fonts.
+	tr func(string) string
+	// messages translates the labels of the report, see --lang.
+	messages messages
+	// branding is the client branding of the report, see --title.
+	branding Branding
+}
+
+// pdfRenderer writes paginated, print-ready PDF reports.
+type pdfRenderer struct{}
+
+func (pdfRenderer) Render(data ReportData, w io.Writer) error {
+	return pdfRenderer{}.RenderAll([]ReportData{data}, w)
+}
+
+func (pdfRenderer) Extension() string { return "pdf" }
+
+// RenderAll writes reports, one section per service, as a PDF document to w.
+// The Engagement and Branding of the first report are shown on the cover page
+// when set, and its classification at the top and bottom of every page.
+func (pdfRenderer) RenderAll(reports []ReportData, w io.Writer) error {
+	r := &pdfReport{pdf: fpdf.New("L", "mm", "A4", "")}
+	r.tr = r.pdf.UnicodeTranslatorFromDescriptor("")
+	if len(reports) > 0 {
+		r.messages = reports[0].messages
+		r.branding = reports[0].Branding
+	}
+	r.pdf.SetTitle(r.branding.TitleOr(r.messages.T("Nmap service report")), true)
+	r.pdf.AliasNbPages("")
+	if r.branding.Classification != "" {
+		r.pdf.SetHeaderFunc(func() {
+			_, top, _, _ := r.pdf.GetMargins()
+			r.classificationBanner(3)
+			r.pdf.SetY(top)
+		})
+	}
+	r.pdf.SetFooterFunc(func() {
+		if r.branding.Classification != "" {
+			r.classificationBanner(-7)
+		}
+		if r.pdf.PageNo() == 1 {
+			return
+		}
+		r.pdf.SetY(-15)
+		r.pdf.SetFont("Helvetica", "I", 8)
+		r.pdf.CellFormat(0, 10, r.text("Page %d of {nb}", r.pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	var engagement string
+	if len(reports) > 0 {
+		engagement = reports[0].Engagement
+	}
+	r.coverPage(engagement, reports)
+	if len(reports) > 0 && len(reports[0].OT) > 0 {
+		r.otSection(reports[0].OT)
+	}
+	if len(reports) > 0 && len(reports[0].QuickWins) > 0 {
+		r.quickWinsSection(reports[0].QuickWins)
+	}
+	if len(reports) > 0 && len(reports[0].Top) > 0 {
+		r.topSection(reports[0].Top)
+	}
+	for i := range reports {
+		r.serviceSection(&reports[i])
+	}
+	if len(reports) > 0 && len(reports[0].DownHosts) > 0 {
+		r.downHostsSection(reports[0].DownHosts)
+	}
+	if len(reports) > 0 && len(reports[0].Scans) > 0 {
+		r.scansSection(reports[0].Scans)
+	}
+	if len(reports) > 0 && len(reports[0].Errors) > 0 {
+		r.errorSection(reports[0].Errors)
+	}
+	if err := r.pdf.Error(); err != nil {
+		return fmt.Errorf("Error building PDF: %w", err)
+	}
+	return r.pdf.Output(w)
+}
+
+// coverPage writes the title page with the logo, title, client and engagement
+// name and the scan summary.
+func (r *pdfReport) coverPage(engagement string, reports []ReportData) {
+	pdf := r.pdf
+	pdf.AddPage()
+	pdf.SetY(60)
+	if len(r.branding.logo) > 0 {
+		logo, imageType := r.branding.logoReader()
+		info := pdf.RegisterImageOptionsReader("logo", fpdf.ImageOptions{ImageType: imageType}, logo)
+		if info != nil && info.Height() > 0 {
+			const height = 25
+			width := info.Width() * height / info.Height()
+			pageWidth, _ := pdf.GetPageSize()
+			pdf.ImageOptions("logo", (pageWidth-width)/2, 28, width, height, false, fpdf.ImageOptions{ImageType: imageType}, 0, "")
+		}
+	}
+	pdf.SetFont("Helvetica", "B", 28)
+	pdf.CellFormat(0, 14, r.tr(r.branding.TitleOr(r.messages.T("Nmap Service Report"))), "", 1, "C", false, 0, "")
+	if r.branding.Client != "" {
+		pdf.SetFont("Helvetica", "", 16)
+		pdf.CellFormat(0, 10, r.text("Prepared for %s", r.branding.Client), "", 1, "C", false, 0, "")
+	}
+	if engagement != "" {
+		pdf.SetFont("Helvetica", "", 18)
+		pdf.CellFormat(0, 12, r.tr(engagement), "", 1, "C", false, 0, "")
+	}
+	pdf.Ln(12)
+
+	var services []string
+	for _, report := range reports {
+		services = append(services, report.Service)
+	}
+	var summary ScanSummary
+	if len(reports) > 0 {
+		summary = reports[0].Summary
+	}
+	lines := [][2]string{
+		{r.text("Services"), strings.Join(services, ", ")},
+		{r.text("Hosts Scanned"), fmt.Sprint(summary.HostsScanned)},
+		{r.text("Hosts Up"), fmt.Sprint(summary.HostsUp)},
+		{r.text("Scan Date Range"), r.messages.T(summary.DateRange())},
+		{r.text("Generated"), time.Now().Format("2006-01-02 15:04 MST")},
+	}
+	for _, line := range lines {
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.CellFormat(138, 8, line[0]+":", "", 0, "R", false, 0, "")
+		pdf.SetFont("Helvetica", "", 12)
+		pdf.CellFormat(0, 8, " "+r.tr(line[1]), "", 1, "L", false, 0, "")
+	}
+}
+
+// serviceSection writes the tables of a single service, starting on a new page.
+func (r *pdfReport) serviceSection(tableData *ReportData) {
+	pdf := r.pdf
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, r.tr(tableData.Service), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 6, r.text("%d matching ports, %d distinct versions",
+		tableData.Summary.MatchingPorts, tableData.Summary.DistinctVersions), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	left, _, right, _ := pdf.GetMargins()
+	pageWidth, _ := pdf.GetPageSize()
+	columns := sizedColumns(tableData, pageWidth-left-right)
+	if len(tableData.Rows) == 0 {
+		pdf.CellFormat(0, 6, r.text("No matching ports."), "", 1, "L", false, 0, "")
+	} else {
+		r.table(columns, tableData.Rows)
+	}
+
+	if len(tableData.Unverified) > 0 {
+		pdf.Ln(6)
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.CellFormat(0, 8, r.text("Unverified detections (confidence below %d)", tableData.MinConf), "", 1, "L", false, 0, "")
+		r.table(columns, tableData.Unverified)
+	}
+}
+
+// topSection ranks the most common service versions of the whole dataset.
+func (r *pdfReport) topSection(top []VersionCount) {
+	pdf := r.pdf
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, r.text("Top %d service versions", len(top)), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+	left, _, right, _ := pdf.GetMargins()
+	pageWidth, _ := pdf.GetPageSize()
+	width := pageWidth - left - right
+
+	widths := []float64{15, 50, width - 115, 25, 25}
+	header := func() { r.header([]string{"Rank", "Service", "Version", "Hosts", "Ports"}, widths) }
+	header()
+	for _, count := range top {
+		r.row([]string{fmt.Sprint(count.Rank), count.Service, count.Label(), fmt.Sprint(count.Hosts), fmt.Sprint(count.Ports)}, widths, "", header)
+	}
+}
+
+// otSection lists the ports speaking an industrial protocol, with a warning.
+func (r *pdfReport) otSection(exposures []OTExposure) {
+	pdf := r.pdf
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, r.text("Industrial (OT) protocols"), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.MultiCell(0, 5, r.text(otWarning), "", "L", false)
+	pdf.Ln(4)
+	left, _, right, _ := pdf.GetMargins()
+	pageWidth, _ := pdf.GetPageSize()
+	width := pageWidth - left - right
+
+	widths := []float64{45, 35, 35, width - 115}
+	header := func() { r.header([]string{"Host", "Protocol", "Service", "Product"}, widths) }
+	header()
+	for _, exposure := range exposures {
+		r.row([]string{exposure.Host.String(), exposure.Protocol, exposure.Service, exposure.Label()}, widths, otFill, header)
+	}
+}
+
+// quickWinsSection lists the services exposed with default or no credentials.
+func (r *pdfReport) quickWinsSection(wins []QuickWin) {
+	pdf := r.pdf
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, r.text("Quick Wins"), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+	left, _, right, _ := pdf.GetMargins()
+	pageWidth, _ := pdf.GetPageSize()
+	width := pageWidth - left - right
+
+	widths := []float64{45, 30, width - 100, 25}
+	header := func() { r.header([]string{"Host", "Service", "Finding", "Status"}, widths) }
+	header()
+	for _, win := range wins {
+		r.row([]string{win.Host.String(), win.Service, win.Finding, win.Status()}, widths, "", header)
+	}
+}
+
+// scansSection lists the input scans with their nmap command and times.
+func (r *pdfReport) scansSection(scans []ScanSource) {
+	pdf := r.pdf
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, r.text("Scans"), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+	left, _, right, _ := pdf.GetMargins()
+	pageWidth, _ := pdf.GetPageSize()
+	width := pageWidth - left - right
+
+	widths := []float64{70, width - 195, 35, 35, 25, 15, 15}
+	header := func() { r.header([]string{"File", "Command", "Start", "Finish", "Duration", "Up", "Down"}, widths) }
+	header()
+	for _, scan := range scans {
+		r.row([]string{scan.File, scan.Args, formatDate(scan.Start, scanTimeLayout), formatDate(scan.End, scanTimeLayout), scan.Duration(),
+			fmt.Sprint(scan.HostsUp), fmt.Sprint(scan.HostsDown)}, widths, "", header)
+	}
+}
+
+// downHostsSection lists the hosts the scans reported down.
+func (r *pdfReport) downHostsSection(hosts []DownHost) {
+	pdf := r.pdf
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, r.text("Down Hosts"), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+	left, _, right, _ := pdf.GetMargins()
+	pageWidth, _ := pdf.GetPageSize()
+	width := pageWidth - left - right
+
+	widths := []float64{45, width - 85, 40}
+	header := func() { r.header([]string{"Host", "Hostname", "Reason"}, widths) }
+	header()
+	for _, host := range hosts {
+		r.row([]string{host.Addr, host.Hostname, host.Reason}, widths, "", header)
+	}
+}
+
+// errorSection lists the input files that could not be parsed.
+func (r *pdfReport) errorSection(parseErrors []ParseError) {
+	pdf := r.pdf
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(0, 8, r.text("Files that could not be parsed"), "", 1, "L", false, 0, "")
+	left, _, right, _ := pdf.GetMargins()
+	pageWidth, _ := pdf.GetPageSize()
+	width := pageWidth - left - right
+
+	widths := []float64{width * 0.45, 25, width*0.55 - 25}
+	header := func() { r.header([]string{"File", "Byte Offset", "Reason"}, widths) }
+	header()
+	for _, parseErr := range parseErrors {
+		reason := parseErr.Reason
+		if parseErr.Recovered > 0 {
+			reason += " (" + r.messages.T("%d complete host(s) recovered", parseErr.Recovered) + ")"
+		}
+		r.row([]string{parseErr.File, fmt.Sprint(parseErr.Offset), reason}, widths, "", header)
+	}
+}
+
+// table writes rows with a header that is repeated on every page.
+func (r *pdfReport) table(columns []Column, rows []ReportRow) {
+	titles := make([]string, len(columns))
+	widths := make([]float64, len(columns))
+	for i, column := range columns {
+		titles[i] = column.Title
+		widths[i] = column.width
+	}
+	header := func() { r.header(titles, widths) }
+	header()
+	for i := range rows {
+		cells := make([]string, len(columns))
+		for j, column := range columns {
+			cells[j] = column.text(&rows[i])
+		}
+		r.row(cells, widths, rowFill(&rows[i]), header)
+	}
+}
+
+// classificationBanner writes the classification of the report across the
+// page at y, from the bottom of the page when negative.
+func (r *pdfReport) classificationBanner(y float64) {
+	pdf := r.pdf
+	pdf.SetY(y)
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.SetFillColor(183, 28, 28)
+	pdf.SetTextColor(255, 255, 255)
+	pdf.CellFormat(0, 5, r.tr(r.branding.Classification), "", 0, "C", true, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+}
+
+// text returns the translation of the label msg, see messages.T, in the
+// encoding of the built-in fonts.
+func (r *pdfReport) text(msg string, args ...any) string {
+	return r.tr(r.messages.T(msg, args...))
+}
+
+// header writes a row of column titles, translated.
+func (r *pdfReport) header(titles []string, widths []float64) {
+	r.pdf.SetFont("Helvetica", "B", 10)
+	r.pdf.SetFillColor(242, 242, 242)
+	for i, title := range titles {
+		r.pdf.CellFormat(widths[i], 7, r.text(title), "1", 0, "C", true, 0, "")
+	}
+	r.pdf.Ln(-1)
+	r.pdf.SetFont("Helvetica", "", 9)
+}
+
+// row writes a table row whose cells wrap onto as many lines as needed. When
+// the row does not fit on the page a new page is started and header called.
+// fill is the hex color the row is filled with, or "" for none; see rowFill.
+func (r *pdfReport) row(cells []string, widths []float64, fill string, header func()) {
+	pdf := r.pdf
+	lines := make([][]string, len(cells))
+	height := 0.0
+	for i, cell := range cells {
+		lines[i] = pdf.SplitText(r.tr(cell), widths[i])
+		height = max(height, float64(max(len(lines[i]), 1))*pdfLineHeight)
+	}
+
+	_, pageHeight := pdf.GetPageSize()
+	_, _, _, bottom := pdf.GetMargins()
+	if pdf.GetY()+height > pageHeight-bottom {
+		pdf.AddPage()
+		header()
+	}
+
+	x, y := pdf.GetXY()
+	style := "D"
+	if fill != "" {
+		var red, green, blue int
+		fmt.Sscanf(fill, "%02x%02x%02x", &red, &green, &blue)
+		pdf.SetFillColor(red, green, blue)
+		style = "FD"
+	}
+	for i := range cells {
+		pdf.Rect(x, y, widths[i], height, style)
+		for j, line := range lines[i] {
+			pdf.SetXY(x, y+float64(j)*pdfLineHeight)
+			pdf.CellFormat(widths[i], pdfLineHeight, line, "", 0, "L", false, 0, "")
+		}
+		x += widths[i]
+	}
+	left, _, _, _ := pdf.GetMargins()
+	pdf.SetXY(left, y+height)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// portProfiles are the named --expect-ports profiles.
+var portProfiles = map[string]string{
+	"full-tcp": "tcp:65535",
+	"full":     "tcp:65535,udp:65535",
+	"top1000":  "tcp:1000",
+}
+
+// portProfile is the number of ports each protocol is expected to have been
+// scanned on, see --expect-ports.
+type portProfile map[string]int
+
+// parsePortProfile parses a comma separated list of named profiles and
+// protocol:ports pairs, e.g. full-tcp or tcp:65535,udp:100. An empty value
+// returns a nil profile, which expects nothing.
+func parsePortProfile(value string) (portProfile, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	profile := make(portProfile)
+	for _, part := range splitList(value) {
+		if named, ok := portProfiles[part]; ok {
+			for _, pair := range splitList(named) {
+				protocol, ports, _ := strings.Cut(pair, ":")
+				profile[protocol] = max(profile[protocol], atoi(ports))
+			}
+			continue
+		}
+		protocol, ports, ok := strings.Cut(part, ":")
+		n, err := strconv.Atoi(ports)
+		if !ok || err != nil || n < 1 || n > 65535 {
+			return nil, fmt.Errorf("invalid port profile %q (use full-tcp, full, top1000 or PROTOCOL:PORTS, e.g. tcp:65535)", part)
+		}
+		profile[strings.ToLower(protocol)] = max(profile[strings.ToLower(protocol)], n)
+	}
+	return profile, nil
+}
+
+// scannedPorts returns the number of ports run scanned per protocol, from the
+// numservices of its scan information. Scans of several types of the same
+// protocol, e.g. -sS and -sA, count the largest.
+func scannedPorts(run *Nmaprun) map[string]int {
+	ports := make(map[string]int)
+	for _, info := range run.Scaninfo {
+		ports[info.Protocol] = max(ports[info.Protocol], atoi(info.Numservices))
+	}
+	return ports
+}
+
+// shortfalls returns the protocols run scanned fewer ports of than the
+// profile expects, e.g. "tcp 1000/65535", sorted. Runs without scan
+// information, such as ping scans, fall short of every protocol.
+func (p portProfile) shortfalls(run *Nmaprun) []string {
+	ports := scannedPorts(run)
+	var short []string
+	for protocol, expected := range p {
+		if ports[protocol] < expected {
+			short = append(short, fmt.Sprintf("%s %d/%d", protocol, ports[protocol], expected))
+		}
+	}
+	sort.Strings(short)
+	return short
+}
+
+// underScannedHosts returns the addresses of the hosts up in runs of which no
+// scan met the profile, in address order. The hosts left out by opts are not
+// checked.
+func (p portProfile) underScannedHosts(runs []Nmaprun, opts TableOptions) []string {
+	met := make(map[string]bool)
+	for i := range runs {
+		run := &runs[i]
+		full := len(p.shortfalls(run)) == 0
+		for j := range run.Host {
+			host := &run.Host[j]
+			addr := hostAddress(host, opts.PreferIPv6)
+			if isDown(host) || opts.excluded(addr) {
+				continue
+			}
+			met[addr] = met[addr] || full
+		}
+	}
+	var hosts []string
+	for addr, full := range met {
+		if !full {
+			hosts = append(hosts, addr)
+		}
+	}
+	sort.Slice(hosts, func(i, j int) bool { return lessAddr(hosts[i], hosts[j]) })
+	return hosts
+}
+
+// maxWarnedHosts is the number of under-scanned hosts named in the warning of
+// warn; the others are only counted.
+const maxWarnedHosts = 20
+
+// warn logs a warning for every scan of runs that covered fewer ports than the
+// profile expects, and names the hosts that no scan covered fully, so that a
+// port such as 8443 left out of a top 1000 scan is not missed unknowingly.
+func (p portProfile) warn(runs []Nmaprun, opts TableOptions) {
+	for i := range runs {
+		if short := p.shortfalls(&runs[i]); len(short) > 0 {
+			logger.Warn("scan covered fewer ports than expected", "file", runs[i].File, "ports", strings.Join(short, ", "))
+		}
+	}
+	if hosts := p.underScannedHosts(runs, opts); len(hosts) > 0 {
+		named := hosts[:min(len(hosts), maxWarnedHosts)]
+		addresses := strings.Join(named, ", ")
+		if len(hosts) > len(named) {
+			addresses += fmt.Sprintf(" and %d more", len(hosts)-len(named))
+		}
+		logger.Warn("hosts were only scanned on fewer ports than expected", "hosts", len(hosts), "addresses", addresses)
+	}
+}
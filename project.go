@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// projectFileName is the name of the project file written by init and looked
+// up in the working directory and its parents.
+const projectFileName = "nmaptables.yaml"
+
+// projectPathKeys are the project keys holding paths, which are relative to
+// the directory of the project file.
+var projectPathKeys = map[string]bool{
+	"nmap-dir":       true,
+	"output-dir":     true,
+	"rules":          true,
+	"eol-file":       true,
+	"normalize-file": true,
+	"template":       true,
+	"hosts-file":     true,
+	"inventory":      true,
+	"geoip":          true,
+	"logo":           true,
+	"redact-map":     true,
+	"key-file":       true,
+}
+
+// Project is the engagement project file written by init. Besides these keys
+// it may hold any other flag, as in the config file.
+type Project struct {
+	Name         string   `yaml:"name,omitempty"`
+	NmapDir      string   `yaml:"nmap-dir,omitempty"`
+	Scope        []string `yaml:"scope,omitempty"`
+	Exclude      []string `yaml:"exclude,omitempty"`
+	OutputDir    string   `yaml:"output-dir,omitempty"`
+	OutputPrefix string   `yaml:"output-prefix,omitempty"`
+}
+
+// findProject returns the project file of dir or of its closest parent that
+// has one, or "" when there is none.
+func findProject(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, projectFileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// applyProject sets the flags of flagSet that were not given on the command
+// line from the project file at path, or the one found from the working
+// directory when path is empty, and returns the path of the file applied.
+// Keys are flag names, as in the config file; name sets --engagement and
+// relative paths are resolved against the directory of the project file.
+func applyProject(flagSet *pflag.FlagSet, path string) (string, error) {
+	if path == "" {
+		var err error
+		if path, err = findProject("."); err != nil || path == "" {
+			return "", err
+		}
+	}
+	path, err := resolveAbsPath(path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var project map[string]interface{}
+	if err := yaml.Unmarshal(data, &project); err != nil {
+		return "", fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	keys := make([]string, 0, len(project))
+	for key := range project {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	dir := filepath.Dir(path)
+	for _, key := range keys {
+		value := configValue(project[key])
+		flagName := key
+		if key == "name" {
+			flagName = "engagement"
+		}
+		f := flagSet.Lookup(flagName)
+		if f == nil || f.Changed {
+			continue
+		}
+		if projectPathKeys[key] {
+			paths := splitList(value)
+			for i, p := range paths {
+				paths[i] = projectPath(dir, p)
+			}
+			value = strings.Join(paths, ",")
+		}
+		if err := flagSet.Set(flagName, value); err != nil {
+			return "", fmt.Errorf("%s: invalid value for %s: %w", path, key, err)
+		}
+	}
+	return path, nil
+}
+
+// projectPath resolves the project path p against dir, the directory of the
+// project file.
+func projectPath(dir, p string) string {
+	if p == "-" || filepath.IsAbs(p) || strings.HasPrefix(p, "~") {
+		return p
+	}
+	return filepath.Join(dir, p)
+}
+
+// writeProject writes project to the project file of dir, creating dir and
+// the scan and output directories of project; an existing project file is
+// only replaced with force.
+func writeProject(dir string, project Project, force bool) (string, error) {
+	var content bytes.Buffer
+	content.WriteString("# nmapTables project, read by every command run in this directory or below it.\n")
+	content.WriteString("# Keys are flag names, as in the config file; flags given on the command line\n")
+	content.WriteString("# win. Relative paths are relative to this file.\n")
+	encoder := yaml.NewEncoder(&content)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(project); err != nil {
+		return "", err
+	}
+
+	for _, sub := range []string{"", project.NmapDir, project.OutputDir} {
+		if sub == "" && dir == "" {
+			continue
+		}
+		if err := os.MkdirAll(projectPath(dir, sub), 0o755); err != nil {
+			return "", err
+		}
+	}
+	path := filepath.Join(dir, projectFileName)
+	file, err := createOutputFile(path, force)
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.Write(content.Bytes()); err != nil {
+		file.Close()
+		return "", err
+	}
+	return path, file.Close()
+}
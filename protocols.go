@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// protocolLayouts are the --protocol-layout values: combined keeps every
+// protocol in one table, sections writes a table per protocol and badges
+// keeps one table with the protocol of each row shown as a badge.
+var protocolLayouts = []string{"combined", "sections", "badges"}
+
+// RowSection is a table of an HTML report, with a heading when the rows are
+// split by protocol.
+type RowSection struct {
+	Title string
+	Rows  []ReportRow
+}
+
+// Sections returns rows as the tables of the report: one per protocol, TCP
+// first, with --protocol-layout sections, or else a single untitled one.
+func (r ReportData) Sections(rows []ReportRow) []RowSection {
+	if r.ProtocolLayout != "sections" {
+		return []RowSection{{Rows: rows}}
+	}
+	var sections []RowSection
+	index := make(map[string]int)
+	for _, row := range rows {
+		i, ok := index[row.Protocol]
+		if !ok {
+			i = len(sections)
+			index[row.Protocol] = i
+			sections = append(sections, RowSection{Title: strings.ToUpper(row.Protocol)})
+		}
+		sections[i].Rows = append(sections[i].Rows, row)
+	}
+	// Rows keep their order within a section; TCP leads, as the usual bulk
+	// of a report, and the other protocols follow in their first appearance.
+	if i, ok := index["tcp"]; ok && i > 0 {
+		tcp := sections[i]
+		copy(sections[1:i+1], sections[:i])
+		sections[0] = tcp
+	}
+	return sections
+}
+
+// ProtocolBadges reports whether the protocol column is drawn as badges.
+func (r ReportData) ProtocolBadges() bool {
+	return r.ProtocolLayout == "badges"
+}
+
+// checkProtocolLayout returns an error for an unknown --protocol-layout.
+func checkProtocolLayout(layout string) error {
+	if slices.Contains(protocolLayouts, layout) {
+		return nil
+	}
+	return fmt.Errorf("unsupported --protocol-layout: %s (use %s)", layout, strings.Join(protocolLayouts, ", "))
+}
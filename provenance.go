@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strconv"
+	"time"
+)
+
+// scanTimeLayout is the layout of the scan times of pdf and docx reports.
+const scanTimeLayout = "2006-01-02 15:04 MST"
+
+// ScanSource describes an input scan for the provenance section of reports:
+// the file it was read from, the nmap command line and when it ran.
+type ScanSource struct {
+	File  string    `json:"file"`
+	Args  string    `json:"args"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Elapsed is the run time in seconds nmap reported, or the time between
+	// Start and End.
+	Elapsed float64 `json:"elapsed"`
+	// HostsUp, HostsDown and HostsTotal are the host counts of the run
+	// statistics of the scan.
+	HostsUp    int `json:"hosts_up"`
+	HostsDown  int `json:"hosts_down"`
+	HostsTotal int `json:"hosts_total"`
+}
+
+// Duration returns the elapsed time of the scan for display, or "unknown" for
+// scans that did not finish.
+func (s ScanSource) Duration() string {
+	if s.Elapsed <= 0 {
+		return "unknown"
+	}
+	return time.Duration(s.Elapsed * float64(time.Second)).Round(time.Second).String()
+}
+
+// scanSources returns the provenance of runs, in input order.
+func scanSources(runs []Nmaprun) []ScanSource {
+	sources := make([]ScanSource, 0, len(runs))
+	for i := range runs {
+		run := &runs[i]
+		source := ScanSource{
+			File:  run.File,
+			Args:  run.Args,
+			Start: parseUnixTime(run.Start),
+			End:   parseUnixTime(run.Runstats.Finished.Time),
+			// The run statistics count the down hosts nmap did not list.
+			HostsUp:    atoi(run.Runstats.Hosts.Up),
+			HostsDown:  atoi(run.Runstats.Hosts.Down),
+			HostsTotal: atoi(run.Runstats.Hosts.Total),
+		}
+		if elapsed, err := strconv.ParseFloat(run.Runstats.Finished.Elapsed, 64); err == nil && elapsed > 0 {
+			source.Elapsed = elapsed
+		} else if !source.Start.IsZero() && source.End.After(source.Start) {
+			source.Elapsed = source.End.Sub(source.Start).Seconds()
+		}
+		sources = append(sources, source)
+	}
+	return sources
+}
+
+// setSource records file as the source of run and of every one of its ports,
+// along with the start time of the run, which ports keep through MergeHosts
+// for --show-source and --merge.
+func setSource(run *Nmaprun, file string) {
+	run.File = file
+	start := parseUnixTime(run.Start)
+	for i := range run.Host {
+		for j := range run.Host[i].Ports.Port {
+			run.Host[i].Ports.Port[j].Source = file
+			run.Host[i].Ports.Port[j].Seen = start
+		}
+	}
+}
+
+// formatSeen formats the time a port was seen for display, or returns "" for
+// the zero time.
+func formatSeen(seen time.Time) string {
+	if seen.IsZero() {
+		return ""
+	}
+	return seen.Format(scanTimeLayout)
+}
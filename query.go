@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+)
+
+// QueryMatch is a host port matching a query, see SearchPorts.
+type QueryMatch struct {
+	Host     HostPort `json:"host"`
+	Protocol string   `json:"protocol"`
+	Service  string   `json:"service"`
+	Product  string   `json:"product,omitempty"`
+	Version  string   `json:"version,omitempty"`
+	// Score ranks the matches: exact substrings count more than fuzzy ones.
+	Score int `json:"score"`
+	// Fields are the fields the terms of the query were found in, e.g.
+	// product or script:http-title.
+	Fields []string `json:"fields"`
+}
+
+// queryField is a searchable field of a host port.
+type queryField struct {
+	name  string
+	value string
+}
+
+// SearchPorts returns the host ports of runs matching the state and network
+// filters of opts where every term of query is found in the service,
+// product, version, extra info, CPE, hostnames or script output, best match
+// first. Terms match case-insensitively as substrings or, from four
+// characters on, as words one typo away, so "apache 2.4" finds Apache httpd
+// 2.4.49 and "tomact" finds Tomcat.
+func SearchPorts(runs []Nmaprun, opts TableOptions, query string) []QueryMatch {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil
+	}
+	var matches []QueryMatch
+	hosts := opts.mergeHosts(runs)
+	for i := range hosts {
+		host := &hosts[i]
+		addr := hostAddress(host, opts.PreferIPv6)
+		if opts.excluded(addr) {
+			continue
+		}
+		var hostnames []string
+		for _, hostname := range host.Hostnames.Hostname {
+			hostnames = append(hostnames, hostname.Name)
+		}
+		for j := range host.Ports.Port {
+			port := &host.Ports.Port[j]
+			if !opts.States[port.State.State] || (opts.ServiceName != "" && !matchesService(port, opts.ServiceName)) {
+				continue
+			}
+			fields := []queryField{
+				{"service", tunnelService(port.Service.Name, port.Service.Tunnel)},
+				{"product", port.Service.Product},
+				{"version", port.Service.Version},
+				{"extrainfo", port.Service.Extrainfo},
+				{"cpe", port.Service.Cpe},
+				{"hostname", strings.Join(hostnames, " ")},
+			}
+			for _, script := range port.Script {
+				fields = append(fields, queryField{"script:" + script.ID, script.Output})
+			}
+			score, matched := scoreQuery(terms, fields)
+			if score == 0 {
+				continue
+			}
+			match := QueryMatch{
+				Host:     HostPort{Addr: addr, Port: port.Portid},
+				Protocol: port.Protocol,
+				Service:  tunnelService(port.Service.Name, port.Service.Tunnel),
+				Product:  port.Service.Product,
+				Version:  port.Service.Version,
+				Score:    score,
+				Fields:   matched,
+			}
+			if len(hostnames) > 0 {
+				match.Host.Hostname = hostnames[0]
+			}
+			matches = append(matches, match)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Host.Addr != b.Host.Addr {
+			return lessAddr(a.Host.Addr, b.Host.Addr)
+		}
+		return atoi(a.Host.Port) < atoi(b.Host.Port)
+	})
+	return matches
+}
+
+// scoreQuery returns the score of fields for terms, 0 unless every term is
+// found in one of them, and the names of the fields a term was found in.
+func scoreQuery(terms []string, fields []queryField) (int, []string) {
+	total := 0
+	var matched []string
+	for _, term := range terms {
+		best, bestField := 0, ""
+		for _, field := range fields {
+			if score := scoreTerm(term, strings.ToLower(field.value)); score > best {
+				best, bestField = score, field.name
+			}
+		}
+		if best == 0 {
+			return 0, nil
+		}
+		total += best
+		if !slices.Contains(matched, bestField) {
+			matched = append(matched, bestField)
+		}
+	}
+	return total, matched
+}
+
+// scoreTerm returns 3 when term is a substring of text, 2 when it is
+// one typo away from a word of text and 0 when it is not found. Terms without
+// letters, such as version numbers, only match exactly: 2019 is not 2012.
+func scoreTerm(term, text string) int {
+	if text == "" {
+		return 0
+	}
+	if strings.Contains(text, term) {
+		return 3
+	}
+	if len(term) < 4 || !strings.ContainsFunc(term, unicode.IsLetter) {
+		return 0
+	}
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '-' && r != '_'
+	})
+	for _, word := range words {
+		if editDistance(term, word, 1) <= 1 {
+			return 2
+		}
+	}
+	return 0
+}
+
+// editDistance returns the edit distance between a and b, counting a swap of
+// two adjacent characters as one edit, or limit+1 once it is known to exceed
+// limit.
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > limit || -d > limit {
+		return limit + 1
+	}
+	prevPrev := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prevPrev[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prevPrev, prev, cur = prev, cur, prevPrev
+	}
+	return prev[len(rb)]
+}
+
+// writeQueryMatches writes matches to w in format: an aligned text table,
+// hostports (one host:port per line) or json.
+func writeQueryMatches(w io.Writer, matches []QueryMatch, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if matches == nil {
+			matches = []QueryMatch{}
+		}
+		return encoder.Encode(matches)
+	case "hostports":
+		for _, match := range matches {
+			if _, err := fmt.Fprintln(w, match.Host.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "", "text":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "HOST\tPROTO\tSERVICE\tVERSION\tMATCHED")
+		for _, match := range matches {
+			host := match.Host.String()
+			if match.Host.Hostname != "" {
+				host += " (" + match.Host.Hostname + ")"
+			}
+			version := strings.TrimSpace(match.Product + " " + match.Version)
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", host, match.Protocol, match.Service, version, strings.Join(match.Fields, ", "))
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unsupported query format: %s (use text, hostports or json)", format)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// QuickWin is a service commonly exposed with default or no credentials.
+type QuickWin struct {
+	Host    HostPort `json:"host"`
+	Service string   `json:"service"`
+	Finding string   `json:"finding"`
+	// Confirmed is set when an NSE script verified the finding, rather than it
+	// being inferred from the service alone.
+	Confirmed bool `json:"confirmed"`
+}
+
+// Status returns whether the finding was confirmed, for display.
+func (q QuickWin) Status() string {
+	if q.Confirmed {
+		return "confirmed"
+	}
+	return "likely"
+}
+
+// unauthenticatedServices are the databases and caches that are often
+// deployed without authentication, with the NSE scripts that only produce
+// output when they answered without credentials.
+var unauthenticatedServices = map[string]struct {
+	product string
+	scripts []string
+}{
+	"redis":     {"Redis", []string{"redis-info"}},
+	"memcached": {"Memcached", []string{"memcached-info"}},
+	"mongodb":   {"MongoDB", []string{"mongodb-info", "mongodb-databases"}},
+}
+
+// quickWin returns the quick win finding of port and whether a script
+// confirmed it, or "" when port is not one.
+func quickWin(port *NmapPort) (string, bool) {
+	name := port.Service.Name
+	switch name {
+	case "telnet":
+		return "Telnet exposes a cleartext login, often with default credentials", false
+	case "ftp":
+		for _, script := range port.Script {
+			if script.ID == "ftp-anon" && strings.Contains(script.Output, "Anonymous FTP login allowed") {
+				return "Anonymous FTP login allowed", true
+			}
+		}
+	case "vnc":
+		for _, script := range port.Script {
+			switch script.ID {
+			case "vnc-info":
+				for _, securityType := range tableTables(script.Table, "Security types") {
+					if elemValue(securityType.Elem, "name") == "None" {
+						return "VNC requires no authentication", true
+					}
+				}
+			case "realvnc-auth-bypass":
+				if strings.Contains(script.Output, "VULNERABLE") {
+					return "RealVNC authentication bypass", true
+				}
+			}
+		}
+	case "snmp":
+		details := make(map[string]string)
+		snmpDetails(port, func(key, value string) { details[key] = value })
+		if communities := details["snmp-communities"]; communities != "" {
+			return "SNMP community strings accepted: " + communities, true
+		}
+	case "redis", "memcached", "mongodb":
+		service := unauthenticatedServices[name]
+		for _, script := range port.Script {
+			if slices.Contains(service.scripts, script.ID) && !strings.Contains(strings.ToLower(script.Output), "auth") {
+				return service.product + " answers without authentication", true
+			}
+		}
+		return service.product + " is often deployed without authentication", false
+	}
+	return "", false
+}
+
+// FindQuickWins returns the quick wins among the ports of runs matching the
+// state and exclusion filters of opts, ordered by host and port.
+// opts.ServiceName is ignored.
+func FindQuickWins(runs []Nmaprun, opts TableOptions) []QuickWin {
+	var wins []QuickWin
+	hosts := opts.mergeHosts(runs)
+	for i := range hosts {
+		host := &hosts[i]
+		addr := hostAddress(host, opts.PreferIPv6)
+		if opts.excluded(addr) {
+			continue
+		}
+		for j := range host.Ports.Port {
+			port := &host.Ports.Port[j]
+			if !opts.States[port.State.State] {
+				continue
+			}
+			finding, confirmed := quickWin(port)
+			if finding == "" {
+				continue
+			}
+			win := QuickWin{Host: HostPort{Addr: addr, Port: port.Portid}, Service: port.Service.Name, Finding: finding, Confirmed: confirmed}
+			if len(host.Hostnames.Hostname) > 0 {
+				win.Host.Hostname = host.Hostnames.Hostname[0].Name
+			}
+			wins = append(wins, win)
+		}
+	}
+	sort.SliceStable(wins, func(i, j int) bool {
+		if wins[i].Host.Addr != wins[j].Host.Addr {
+			return lessAddr(wins[i].Host.Addr, wins[j].Host.Addr)
+		}
+		return atoi(wins[i].Host.Port) < atoi(wins[j].Host.Port)
+	})
+	return wins
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// isTruncated reports whether err means the XML document ended early, as it
+// does when nmap is killed before writing </nmaprun>.
+func isTruncated(err error) bool {
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return syntaxErr.Msg == "unexpected EOF"
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// recoverHosts salvages every complete <host> element of the truncated scan in
+// data by cutting it after the last one and closing the document. The host
+// counts of the missing <runstats> are filled in from the recovered hosts. It
+// returns false when there is no complete host to recover.
+func recoverHosts(data []byte) (Nmaprun, bool) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	var end int64
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth == 1 && t.Name.Local == "host" {
+				end = decoder.InputOffset()
+			}
+		}
+	}
+	if end == 0 {
+		return Nmaprun{}, false
+	}
+
+	var nmapRun Nmaprun
+	repaired := append(data[:end:end], "\n</nmaprun>\n"...)
+	if err := xml.Unmarshal(repaired, &nmapRun); err != nil {
+		return Nmaprun{}, false
+	}
+	up := 0
+	for i := range nmapRun.Host {
+		if nmapRun.Host[i].Status.State == "up" {
+			up++
+		}
+	}
+	nmapRun.Runstats.Hosts.Total = strconv.Itoa(len(nmapRun.Host))
+	nmapRun.Runstats.Hosts.Up = strconv.Itoa(up)
+	nmapRun.Runstats.Hosts.Down = strconv.Itoa(len(nmapRun.Host) - up)
+	return nmapRun, true
+}
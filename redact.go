@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/netip"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// redactedName replaces the hostnames and domains of the scanned hosts in
+// redacted reports.
+const redactedName = "[redacted]"
+
+var (
+	// ipv4Pattern matches the IPv4 addresses of free text, such as script
+	// output, keeping the first three octets apart, and the prefix length of
+	// networks such as the 10.0.0.0/24 targets of the nmap command line.
+	ipv4Pattern = regexp.MustCompile(`\b(\d{1,3}\.\d{1,3}\.\d{1,3})\.\d{1,3}(?:/\d{1,2})?\b`)
+	// ipv6Pattern matches what may be IPv6 addresses, confirmed by parsing
+	// them.
+	ipv6Pattern = regexp.MustCompile(`(?i)\b[0-9a-f]{1,4}(?::[0-9a-f]{0,4}){2,7}\b`)
+	// macPattern matches MAC addresses, keeping the vendor prefix apart.
+	macPattern = regexp.MustCompile(`(?i)\b([0-9a-f]{2}:[0-9a-f]{2}:[0-9a-f]{2})(?::[0-9a-f]{2}){3}\b`)
+)
+
+// redactor sanitizes reports to be shared outside the engagement, see
+// --redact: the last octet of IPv4 addresses and the last group of IPv6
+// addresses are masked, MAC addresses keep only their vendor prefix and the
+// hostnames and domains of the scanned hosts are stripped, unless the
+// --redact-map gives them a replacement.
+type redactor struct {
+	// mapping replaces addresses, hostnames or any other text, e.g. the name
+	// of the client, keyed by their lowercase form.
+	mapping map[string]string
+	// names are the lowercase hostnames and domains of the scanned hosts,
+	// learnt as they are merged, and namePattern matches them and the text
+	// keys of mapping, rebuilt when it is nil.
+	names       map[string]bool
+	namePattern *regexp.Regexp
+	// domains are the names that are domains of others.
+	domains map[string]bool
+	// hosts are the redacted copies of the merged hosts of the report being
+	// redacted, shared by their host ports.
+	hosts map[*NmapHost]*NmapHost
+	// screenshots are the redacted paths of the screenshots by their
+	// original path, and originals the reverse, which the screenshots are
+	// embedded from.
+	screenshots map[string]string
+	originals   map[string]string
+	// pseudonymize gives what is not mapped a new pseudonym, added to the
+	// mapping, instead of masking it; see anonymize. counts are the
+	// pseudonyms given of each kind, and used the pseudonyms of the mapping.
+	pseudonymize bool
+	counts       map[string]int
+	used         map[string]bool
+	// networks are the IPv4 networks given a pseudonym network, which the
+	// pseudonyms of their addresses are in.
+	networks []networkPseudonym
+}
+
+// loadRedactor returns the redactor of --redact, with the replacements of
+// mapFile when it is not empty: a line per original and replacement separated
+// by whitespace, with # comments. A nil redactor is returned unless enabled.
+func loadRedactor(enabled bool, mapFile string) (*redactor, error) {
+	if !enabled && mapFile == "" {
+		return nil, nil
+	}
+	r := &redactor{mapping: make(map[string]string), names: make(map[string]bool), domains: make(map[string]bool), screenshots: make(map[string]string), originals: make(map[string]string)}
+	if mapFile == "" {
+		return r, nil
+	}
+	mapFile, err := resolveAbsPath(mapFile)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(mapFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	outputManifest.input(mapFile)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected an original and its replacement", mapFile, line)
+		}
+		r.mapping[strings.ToLower(fields[0])] = fields[1]
+	}
+	return r, scanner.Err()
+}
+
+// learn records the hostnames of hosts and their domains, to strip them from
+// the free text of the reports.
+func (r *redactor) learn(hosts []NmapHost) {
+	if r == nil {
+		return
+	}
+	for i := range hosts {
+		for _, hostname := range hosts[i].Hostnames.Hostname {
+			r.learnName(hostname.Name)
+		}
+		for _, hop := range hosts[i].Trace.Hop {
+			r.learnName(hop.Host)
+		}
+	}
+}
+
+// learnName records the hostname name and its domain.
+func (r *redactor) learnName(name string) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if name == "" || r.names[name] {
+		return
+	}
+	r.names[name] = true
+	// The domain of a FQDN names the client as much as the host.
+	if _, domain, ok := strings.Cut(name, "."); ok && strings.Contains(domain, ".") {
+		r.names[domain] = true
+		r.domains[domain] = true
+	}
+	r.namePattern = nil
+}
+
+// pattern returns the regular expression matching the learnt names and the
+// text keys of the mapping, longest first, or nil when there are none.
+func (r *redactor) pattern() *regexp.Regexp {
+	if r.namePattern != nil {
+		return r.namePattern
+	}
+	var names []string
+	for name := range r.names {
+		names = append(names, name)
+	}
+	for key := range r.mapping {
+		// Addresses and networks are replaced by their own patterns.
+		if _, err := netip.ParseAddr(key); err != nil && !isPrefix(key) && !macPattern.MatchString(key) && !r.names[key] {
+			names = append(names, key)
+		}
+	}
+	r.namePattern = wordsPattern(names)
+	return r.namePattern
+}
+
+// wordsPattern returns the case-insensitive regular expression matching any
+// of words, longest first, or nil when there are none.
+func wordsPattern(words []string) *regexp.Regexp {
+	if len(words) == 0 {
+		return nil
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if len(words[i]) != len(words[j]) {
+			return len(words[i]) > len(words[j])
+		}
+		return words[i] < words[j]
+	})
+	alternatives := make([]string, len(words))
+	for i, word := range words {
+		// Words only match whole, so that a host called db does not redact
+		// mongodb.
+		alternative := regexp.QuoteMeta(word)
+		if isWordByte(word[0]) {
+			alternative = `\b` + alternative
+		}
+		if isWordByte(word[len(word)-1]) {
+			alternative += `\b`
+		}
+		alternatives[i] = alternative
+	}
+	return regexp.MustCompile(`(?i)` + strings.Join(alternatives, "|"))
+}
+
+func isPrefix(s string) bool {
+	_, err := netip.ParsePrefix(s)
+	return err == nil
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// text redacts the addresses and names of free text.
+func (r *redactor) text(s string) string {
+	if s == "" {
+		return s
+	}
+	if pattern := r.pattern(); pattern != nil {
+		s = pattern.ReplaceAllStringFunc(s, func(name string) string {
+			return r.replace(pseudonymName, name, redactedName)
+		})
+	}
+	s = ipv4Pattern.ReplaceAllStringFunc(s, func(match string) string {
+		address, bits, isPrefix := strings.Cut(match, "/")
+		if addr, err := netip.ParseAddr(address); err != nil || !addr.Is4() {
+			return match
+		}
+		if replacement, ok := r.mapping[match]; ok && isPrefix {
+			return replacement
+		}
+		replacement := r.replace(pseudonymIPv4, address, ipv4Pattern.ReplaceAllString(address, "$1.x"))
+		if isPrefix {
+			replacement += "/" + bits
+		}
+		return replacement
+	})
+	s = ipv6Pattern.ReplaceAllStringFunc(s, func(match string) string {
+		// Matches ending with a colon are rather C++ or Perl names, e.g.
+		// std::vector.
+		addr, err := netip.ParseAddr(match)
+		if err != nil || !addr.Is6() || strings.HasSuffix(match, ":") {
+			return match
+		}
+		return r.replace(pseudonymIPv6, match, match[:strings.LastIndex(match, ":")+1]+"x")
+	})
+	return macPattern.ReplaceAllStringFunc(s, func(match string) string {
+		return r.replace(pseudonymMAC, match, macPattern.ReplaceAllString(match, "$1:xx:xx:xx"))
+	})
+}
+
+// replace returns the replacement of original from the mapping, or else its
+// pseudonym of kind when pseudonymizing, or masked.
+func (r *redactor) replace(kind pseudonymKind, original, masked string) string {
+	if replacement, ok := r.mapping[strings.ToLower(original)]; ok {
+		return replacement
+	}
+	if r.pseudonymize {
+		return r.pseudonym(kind, original)
+	}
+	return masked
+}
+
+// hostname returns the replacement of a hostname, stripping it unless the
+// mapping has one.
+func (r *redactor) hostname(name string) string {
+	if replacement, ok := r.mapping[strings.ToLower(strings.TrimSuffix(name, "."))]; ok {
+		return replacement
+	}
+	return ""
+}
+
+// screenshot returns the redacted path of the screenshot at original, of the
+// web server at url. The file is named after the redacted URL, as screenshot
+// tools spell the address in file names in ways free text is not redacted
+// from, e.g. aquatone's https__10_0_0_5__443; a number tells apart the names
+// that redact alike.
+func (r *redactor) screenshot(original, url string) string {
+	if original == "" {
+		return ""
+	}
+	if redacted, ok := r.screenshots[original]; ok {
+		return redacted
+	}
+	dir, file := path.Split(original)
+	dir, ext := r.text(dir), path.Ext(file)
+	name := screenshotKey(r.text(url))
+	if name == "" {
+		name = "screenshot"
+	}
+	redacted := dir + name + ext
+	for n := 2; r.originals[redacted] != ""; n++ {
+		redacted = fmt.Sprintf("%s%s-%d%s", dir, name, n, ext)
+	}
+	r.screenshots[original] = redacted
+	r.originals[redacted] = original
+	return redacted
+}
+
+var (
+	hostPortType   = reflect.TypeOf(HostPort{})
+	reportDataType = reflect.TypeOf(ReportData{})
+)
+
+// value returns a redacted copy of v, leaving the original untouched: every
+// exported string is redacted as text, but for hostnames, which are stripped,
+// and screenshot paths, which are renamed, see screenshot. The merged host and
+// port of host ports are redacted too, for the exports writing them.
+func (r *redactor) value(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.String:
+		out := reflect.New(v.Type()).Elem()
+		out.SetString(r.text(v.String()))
+		return out
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(r.value(v.Elem()))
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(r.value(v.Index(i)))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			out.SetMapIndex(iter.Key(), r.value(iter.Value()))
+		}
+		return out
+	case reflect.Struct:
+		out := reflect.New(v.Type())
+		out.Elem().Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			switch {
+			case field.Name == "Hostname" && field.Type.Kind() == reflect.String:
+				out.Elem().Field(i).SetString(r.hostname(v.Field(i).String()))
+			case field.Name == "Screenshot" && v.Type() == hostPortType:
+				hostPort := v.Interface().(HostPort)
+				out.Elem().Field(i).SetString(r.screenshot(hostPort.Screenshot, hostPort.URL))
+			default:
+				out.Elem().Field(i).Set(r.value(v.Field(i)))
+			}
+		}
+		if v.Type() == hostPortType {
+			hostPort := out.Interface().(*HostPort)
+			if host := hostPort.host; host != nil {
+				if hostPort.host = r.hosts[host]; hostPort.host == nil {
+					hostPort.host = redactCopy(r, host)
+					r.hosts[host] = hostPort.host
+				}
+			}
+			if hostPort.port != nil {
+				hostPort.port = redactCopy(r, hostPort.port)
+			}
+		}
+		if v.Type() == reportDataType {
+			data := out.Interface().(*ReportData)
+			data.screenshots = data.screenshots.redacted(r.originals)
+		}
+		return out.Elem()
+	}
+	return v
+}
+
+// redactCopy returns a redacted copy of v with r.
+func redactCopy[T any](r *redactor, v T) T {
+	return r.value(reflect.ValueOf(v)).Interface().(T)
+}
+
+// report returns a redacted copy of data, or data itself when r is nil.
+func (r *redactor) report(data ReportData) ReportData {
+	if r == nil {
+		return data
+	}
+	r.hosts = make(map[*NmapHost]*NmapHost)
+	defer func() { r.hosts = nil }()
+	return redactCopy(r, data)
+}
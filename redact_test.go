@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const redactTestScan = `<?xml version="1.0" encoding="UTF-8"?>
+<nmaprun scanner="nmap" args="nmap -sV 10.0.0.5 10.0.0.6" start="1700000000" version="7.94" xmloutputversion="1.05">
+<host starttime="1700000000" endtime="1700000100"><status state="up" reason="syn-ack" reason_ttl="0"/>
+<address addr="10.0.0.5" addrtype="ipv4"/>
+<hostnames><hostname name="web1.corp.local" type="PTR"/></hostnames>
+<ports>
+<port protocol="tcp" portid="443"><state state="open" reason="syn-ack" reason_ttl="64"/><service name="http" product="nginx" version="1.18.0" tunnel="ssl" method="probed" conf="10"/></port>
+</ports>
+</host>
+<host starttime="1700000000" endtime="1700000100"><status state="up" reason="syn-ack" reason_ttl="0"/>
+<address addr="10.0.0.6" addrtype="ipv4"/>
+<ports>
+<port protocol="tcp" portid="80"><state state="open" reason="syn-ack" reason_ttl="64"/><service name="http" product="nginx" version="1.18.0" method="probed" conf="10"/></port>
+</ports>
+</host>
+</nmaprun>
+`
+
+func TestRedactScreenshots(t *testing.T) {
+	dir := t.TempDir()
+	scan := writeFile(t, dir, "scan.xml", redactTestScan)
+	shots := filepath.Join(dir, "shots")
+	// As named by gowitness and aquatone.
+	writeFile(t, shots, "https---web1.corp.local-443.png", "web1")
+	writeFile(t, shots, "http__10_0_0_6__80__0123456789abcdef.png", "web2")
+	originals := []string{"10.0.0.5", "10.0.0.6", "10_0_0_6", "web1", "corp.local"}
+
+	report := []string{"report", scan, "--service", "http", "--screenshots", shots, "--redact", "--quiet"}
+	outputs := make(map[string]string)
+	for _, format := range []string{"csv", "json", "nmap-xml"} {
+		out, err := runCLI(t, append(report, "--output-format", format, "--columns", "hostport,url,screenshot")...)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		outputs[format] = out
+	}
+	for _, link := range []bool{false, true} {
+		file := filepath.Join(dir, "embedded.html")
+		args := append(report, "-o", file)
+		if link {
+			file = filepath.Join(dir, "linked.html")
+			args = append(report, "-o", file, "--link-screenshots")
+		}
+		if _, err := runCLI(t, args...); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		outputs[filepath.Base(file)] = string(data)
+	}
+	for name, out := range outputs {
+		for _, original := range originals {
+			if strings.Contains(out, original) {
+				t.Errorf("%s has %q", name, original)
+			}
+		}
+	}
+	if !strings.Contains(outputs["csv"], ".png") {
+		t.Errorf("csv has no screenshots:\n%s", outputs["csv"])
+	}
+	if got := strings.Count(outputs["embedded.html"], "data:image/png;base64,"); got != 2 {
+		t.Errorf("embedded.html embeds %d screenshots, want 2", got)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Renderer writes a report in an output format. Formats are looked up by name
+// in renderers, where the formats of this package register.
+type Renderer interface {
+	Render(data ReportData, w io.Writer) error
+}
+
+// RendererFunc adapts a function to a Renderer.
+type RendererFunc func(data ReportData, w io.Writer) error
+
+// Render calls f(data, w).
+func (f RendererFunc) Render(data ReportData, w io.Writer) error {
+	return f(data, w)
+}
+
+// documentRenderer is implemented by renderers of document formats, which
+// report writes to a file with the extension rather than to stdout.
+type documentRenderer interface {
+	Renderer
+	Extension() string
+}
+
+// multiRenderer is implemented by renderers that write the reports of several
+// services as sections of a single document.
+type multiRenderer interface {
+	RenderAll(reports []ReportData, w io.Writer) error
+}
+
+// renderers are the output formats, keyed by name.
+var renderers = map[string]Renderer{}
+
+// registerRenderer makes renderer available as the output format name,
+// replacing any renderer registered under that name before.
+func registerRenderer(name string, renderer Renderer) {
+	renderers[name] = renderer
+}
+
+func init() {
+	registerRenderer("html", htmlRenderer{})
+	registerRenderer("pdf", pdfRenderer{})
+	registerRenderer("docx", docxRenderer{})
+	registerRenderer("hostports", RendererFunc(writeHostPorts))
+	registerRenderer("csv", RendererFunc(writeCSV))
+	registerRenderer("json", RendererFunc(writeJSON))
+	registerRenderer("splunk", splunkRenderer{})
+	registerRenderer("defectdojo", RendererFunc(writeDefectDojo))
+	registerRenderer("faraday", RendererFunc(writeFaraday))
+	registerRenderer("dradis", RendererFunc(writeDradis))
+	registerRenderer("nuclei-targets", RendererFunc(writeNucleiTargets))
+	registerRenderer("urls", RendererFunc(writeNucleiTargets))
+	registerRenderer("msf-rc", RendererFunc(writeMetasploitRC))
+	registerRenderer("msf-xml", RendererFunc(writeMetasploitXML))
+	registerRenderer("nmap-xml", RendererFunc(writeNmapXML))
+	registerRenderer("ips", RendererFunc(writeIPs))
+	registerRenderer("cidrs", RendererFunc(writeCIDRs))
+	registerRenderer("etc-hosts", RendererFunc(writeEtcHosts))
+	registerRenderer("burp-scope", RendererFunc(writeBurpScope))
+}
+
+// lookupRenderer returns the renderer of the output format name.
+func lookupRenderer(name string) (Renderer, error) {
+	renderer, ok := renderers[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported output format: %s (available: %s)", name, strings.Join(rendererNames(), ", "))
+	}
+	return renderer, nil
+}
+
+// rendererNames returns the sorted names of the output formats.
+func rendererNames() []string {
+	names := make([]string, 0, len(renderers))
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// render writes reports to w with renderer: as one document when it supports
+// several, or else only the first.
+func render(renderer Renderer, reports []ReportData, w io.Writer) error {
+	if multi, ok := renderer.(multiRenderer); ok {
+		return multi.RenderAll(reports, w)
+	}
+	return renderer.Render(reports[0], w)
+}
+
+// writeRendered renders reports as format into outputFilename, refusing to
+// replace an existing file unless overwrite is set. The file is only created
+// once rendering succeeded.
+func writeRendered(outputFilename string, overwrite bool, format string, renderer Renderer, reports []ReportData) error {
+	var buf bytes.Buffer
+	if err := render(renderer, reports, &buf); err != nil {
+		return fmt.Errorf("Error writing %s: %w", format, err)
+	}
+	outputFile, err := createOutputFile(outputFilename, overwrite)
+	if err != nil {
+		return err
+	}
+	defer outputFile.Close()
+	if _, err := buf.WriteTo(outputFile); err != nil {
+		return fmt.Errorf("Error writing %s: %w", format, err)
+	}
+	statusf("%s report written to %s\n", strings.ToUpper(format), outputFilename)
+	return nil
+}
+
+// htmlRenderer renders HTML reports with tmpl, or the built-in table template
+// when it is nil.
+type htmlRenderer struct {
+	tmpl *template.Template
+}
+
+func (r htmlRenderer) Render(data ReportData, w io.Writer) error {
+	tmpl := r.tmpl
+	if tmpl == nil {
+		var err error
+		if tmpl, err = loadTemplate("template.html", ""); err != nil {
+			return fmt.Errorf("Error parsing template: %w", err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("Error executing template: %w", err)
+	}
+	// Reports are opened on air-gapped networks: everything they load must be
+	// embedded, unless screenshots were linked on purpose.
+	if data.screenshots == nil || !data.screenshots.link {
+		if refs := externalResources(buf.Bytes()); len(refs) > 0 {
+			logger.Warn("HTML report loads resources it does not embed and will not render fully offline", "count", len(refs), "first", refs[0])
+		}
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// externalResource matches the URLs an HTML page loads resources from: the
+// src of images and scripts, linked stylesheets and the url() and @import of
+// styles.
+var externalResource = regexp.MustCompile(`(?i)(?:\s(?:src|srcset|poster)\s*=\s*|<link\b[^>]*\shref\s*=\s*|url\(\s*|@import\s+)["']?([^"'\s)>]*)`)
+
+// externalResources returns the distinct resources html loads from outside
+// itself, those not embedded as data URIs or referring to the page itself.
+func externalResources(html []byte) []string {
+	seen := make(map[string]bool)
+	var refs []string
+	for _, match := range externalResource.FindAllSubmatch(html, -1) {
+		ref := string(match[1])
+		if ref == "" || ref[0] == '#' || strings.HasPrefix(strings.ToLower(ref), "data:") || seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// RenderAll renders the reports of several services as a single HTML report,
+// executing the template with the first report and setting its Services to
+// every report. A single report is rendered as it is.
+func (r htmlRenderer) RenderAll(reports []ReportData, w io.Writer) error {
+	if len(reports) == 1 {
+		return r.Render(reports[0], w)
+	}
+	data := reports[0]
+	data.Services = reports
+	return r.Render(data, w)
+}
+
+func (htmlRenderer) Extension() string { return "html" }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHTMLReportSelfContained(t *testing.T) {
+	dir := t.TempDir()
+	scan := writeFile(t, dir, "scan.xml", redactTestScan)
+	shots := filepath.Join(dir, "shots")
+	writeFile(t, shots, "https---web1.corp.local-443.png", "web1")
+	writeFile(t, shots, "http---10.0.0.6.png", "web2")
+	var logo bytes.Buffer
+	if err := png.Encode(&logo, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	logoFile := writeFile(t, dir, "logo.png", logo.String())
+
+	for _, service := range []string{"http", "all"} {
+		file := filepath.Join(dir, service+".html")
+		if _, err := runCLI(t, "report", scan, "--service", service, "--screenshots", shots, "--logo", logoFile, "--quiet", "-o", file); err != nil {
+			t.Fatalf("--service %s: %v", service, err)
+		}
+		html, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if refs := externalResources(html); len(refs) > 0 {
+			t.Errorf("--service %s report loads %q", service, refs)
+		}
+		if got := bytes.Count(html, []byte("data:image/png;base64,")); got < 3 {
+			t.Errorf("--service %s report embeds %d images, want the logo and 2 screenshots", service, got)
+		}
+	}
+}
+
+func TestExternalResources(t *testing.T) {
+	for _, test := range []struct {
+		html string
+		want int
+	}{
+		{`<img src="data:image/png;base64,AAAA"><a href="https://example.com/">link</a><a href="#top">top</a>`, 0},
+		{`<style>body { background: url(data:image/png;base64,AAAA) }</style>`, 0},
+		{`<script src="https://cdn.jsdelivr.net/npm/chart.js"></script>`, 1},
+		{`<link rel="stylesheet" href="https://cdn.example.com/style.css">`, 1},
+		{`<style>@import "https://fonts.googleapis.com/css?family=Roboto";</style>`, 1},
+		{`<img src="shots/web1.png"><img src='shots/web1.png'>`, 1},
+	} {
+		if got := externalResources([]byte(test.html)); len(got) != test.want {
+			t.Errorf("externalResources(%s) = %q, want %d resources", test.html, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostResolver looks up the hostnames of hosts the scans have none for: in a
+// hosts file, then with reverse DNS when enabled. Results are cached, so every
+// address is looked up at most once per run.
+type hostResolver struct {
+	hostsFile map[string]string
+	dns       bool
+	timeout   time.Duration
+	workers   int
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+// newHostResolver returns a resolver of the hosts file at hostsFile, if not
+// empty, and of reverse DNS with timeout per lookup and up to workers lookups
+// at a time if dns is set. It returns nil when neither is used.
+func newHostResolver(hostsFile string, dns bool, timeout time.Duration, workers int) (*hostResolver, error) {
+	if hostsFile == "" && !dns {
+		return nil, nil
+	}
+	r := &hostResolver{dns: dns, timeout: timeout, workers: max(workers, 1), cache: make(map[string]string)}
+	if hostsFile != "" {
+		var err error
+		if r.hostsFile, err = loadHostsFile(hostsFile); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// loadHostsFile reads a file in /etc/hosts format: an address followed by its
+// hostname and aliases on each line, with # comments. The first hostname of
+// an address wins.
+func loadHostsFile(path string) (map[string]string, error) {
+	path, err := resolveAbsPath(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	outputManifest.input(path)
+	names := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 || net.ParseIP(fields[0]) == nil {
+			continue
+		}
+		if _, ok := names[fields[0]]; !ok {
+			names[fields[0]] = fields[1]
+		}
+	}
+	return names, scanner.Err()
+}
+
+// resolve fills in the hostname of every host without one that the resolver
+// finds a name for.
+func (r *hostResolver) resolve(hosts []NmapHost, preferIPv6 bool) {
+	if r == nil {
+		return
+	}
+	var pending []string
+	r.mu.Lock()
+	for i := range hosts {
+		addr := hostAddress(&hosts[i], preferIPv6)
+		if len(hosts[i].Hostnames.Hostname) > 0 || addr == "" {
+			continue
+		}
+		if _, ok := r.cache[addr]; ok {
+			continue
+		}
+		if name, ok := r.hostsFile[addr]; ok {
+			r.cache[addr] = name
+			continue
+		}
+		if r.dns {
+			// Reserve the address so that it is only looked up once.
+			r.cache[addr] = ""
+			pending = append(pending, addr)
+		}
+	}
+	r.mu.Unlock()
+	r.lookupAll(pending)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range hosts {
+		if len(hosts[i].Hostnames.Hostname) > 0 {
+			continue
+		}
+		if name := r.cache[hostAddress(&hosts[i], preferIPv6)]; name != "" {
+			hosts[i].Hostnames.Hostname = []NmapHostname{{Name: name, Type: "PTR"}}
+		}
+	}
+}
+
+// lookupAll looks up the PTR records of addrs, with up to r.workers lookups at
+// a time, and caches the first name found for each.
+func (r *hostResolver) lookupAll(addrs []string) {
+	if len(addrs) == 0 {
+		return
+	}
+	start := time.Now()
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for range min(r.workers, len(addrs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for addr := range jobs {
+				ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+				names, err := net.DefaultResolver.LookupAddr(ctx, addr)
+				cancel()
+				if err != nil || len(names) == 0 {
+					logger.Debug("reverse DNS lookup failed", "addr", addr, "err", err)
+					continue
+				}
+				r.mu.Lock()
+				r.cache[addr] = strings.TrimSuffix(names[0], ".")
+				r.mu.Unlock()
+			}
+		}()
+	}
+	for _, addr := range addrs {
+		jobs <- addr
+	}
+	close(jobs)
+	wg.Wait()
+	logger.Info("resolved hostnames", "lookups", len(addrs), "duration", time.Since(start).Round(time.Millisecond))
+}
+
+// mergeHosts merges the hosts of runs with the --merge strategy, filling in the
+// hostnames found by the --resolve and --hosts-file resolver, normalizing
+// their services with the --normalize rules and learning the hostnames
+// --redact strips.
+func (opts TableOptions) mergeHosts(runs []Nmaprun) []NmapHost {
+	hosts := mergeHosts(runs, opts.PreferIPv6, opts.Merge)
+	normalizeHosts(hosts, opts.Normalize)
+	opts.Resolver.resolve(hosts, opts.PreferIPv6)
+	opts.Redact.learn(hosts)
+	return hosts
+}
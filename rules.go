@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/mr-pmillz/nmapTables/nmap"
+	"gopkg.in/yaml.v3"
+)
+
+// RowMatcher matches report rows by service, product and version. Service
+// and Product are case-insensitive regular expressions; Version is a
+// comma separated list of comparisons such as ">= 2.4, < 2.4.50", or a regular
+// expression when it does not start with a comparison operator. Empty fields
+// match everything.
+type RowMatcher struct {
+	Service string `yaml:"service"`
+	Product string `yaml:"product"`
+	Version string `yaml:"version"`
+
+	service *regexp.Regexp
+	product *regexp.Regexp
+	version *regexp.Regexp
+	bounds  []versionBound
+}
+
+// RiskRule maps service/product/version patterns to a risk label.
+type RiskRule struct {
+	Name       string `yaml:"name"`
+	RowMatcher `yaml:",inline"`
+	Risk       string `yaml:"risk"`
+}
+
+// versionBound is a single comparison of a version constraint.
+type versionBound struct {
+	op      string
+	version string
+}
+
+// riskRulesFile is the layout of a YAML risk rules file.
+type riskRulesFile struct {
+	Rules []RiskRule `yaml:"rules"`
+}
+
+// versionOperators are the supported comparison operators, longest first so
+// that "<=" is not parsed as "<".
+var versionOperators = []string{"<=", ">=", "!=", "==", "<", ">", "="}
+
+// readYAMLFile resolves path and unmarshals the YAML document in it into v.
+func readYAMLFile(path string, v interface{}) error {
+	path, err := resolveAbsPath(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	outputManifest.input(path)
+	if err := yaml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadRiskRules reads and compiles the risk rules in path. An empty path
+// returns no rules.
+func LoadRiskRules(path string) ([]RiskRule, error) {
+	if path == "" {
+		return nil, nil
+	}
+	var file riskRulesFile
+	if err := readYAMLFile(path, &file); err != nil {
+		return nil, err
+	}
+	for i := range file.Rules {
+		rule := &file.Rules[i]
+		if rule.Risk == "" {
+			return nil, fmt.Errorf("rule %d (%s): missing risk label", i+1, rule.Name)
+		}
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %w", i+1, rule.Name, err)
+		}
+	}
+	return file.Rules, nil
+}
+
+// compile prepares the patterns of m for matching.
+func (m *RowMatcher) compile() error {
+	var err error
+	if m.service, err = compilePattern(m.Service); err != nil {
+		return err
+	}
+	if m.product, err = compilePattern(m.Product); err != nil {
+		return err
+	}
+
+	version := strings.TrimSpace(m.Version)
+	if !hasVersionOperator(version) {
+		m.version, err = compilePattern(version)
+		return err
+	}
+	for _, part := range strings.Split(version, ",") {
+		part = strings.TrimSpace(part)
+		bound, ok := parseVersionBound(part)
+		if !ok {
+			return fmt.Errorf("invalid version constraint %q", part)
+		}
+		m.bounds = append(m.bounds, bound)
+	}
+	return nil
+}
+
+// compilePattern compiles a case-insensitive pattern, returning nil for "".
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile("(?i)" + pattern)
+}
+
+func hasVersionOperator(version string) bool {
+	_, ok := parseVersionBound(version)
+	return ok
+}
+
+// parseVersionBound parses a comparison such as "< 7.4".
+func parseVersionBound(constraint string) (versionBound, bool) {
+	for _, op := range versionOperators {
+		if strings.HasPrefix(constraint, op) {
+			version := strings.TrimSpace(strings.TrimPrefix(constraint, op))
+			if version == "" {
+				return versionBound{}, false
+			}
+			return versionBound{op: op, version: version}, true
+		}
+	}
+	return versionBound{}, false
+}
+
+// matches reports whether version satisfies b.
+func (b versionBound) matches(version string) bool {
+	cmp := nmap.CompareVersions(version, b.version)
+	switch b.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "!=":
+		return cmp != 0
+	default:
+		return cmp == 0
+	}
+}
+
+// Matches reports whether row is covered by m.
+func (m *RowMatcher) Matches(row *ReportRow) bool {
+	if m.service != nil && !m.service.MatchString(row.Service) {
+		return false
+	}
+	if m.product != nil && !m.product.MatchString(row.Product) {
+		return false
+	}
+	if m.version != nil && !m.version.MatchString(row.ProductVersion) {
+		return false
+	}
+	if len(m.bounds) > 0 && nmap.VersionNumbers(row.ProductVersion) == nil {
+		// Nothing to compare against, e.g. a product without a version.
+		return false
+	}
+	for _, bound := range m.bounds {
+		if !bound.matches(row.ProductVersion) {
+			return false
+		}
+	}
+	return true
+}
+
+// applyRiskRules tags every row with the risk of the first rule matching it.
+// Rows running several versions take the risk of their oldest matching one.
+func applyRiskRules(rows []ReportRow, rules []RiskRule) {
+	for i := range rows {
+	variants:
+		for _, variant := range rows[i].variants() {
+			for j := range rules {
+				if rules[j].Matches(&variant) {
+					rows[i].Risk = rules[j].Risk
+					rows[i].RiskRule = rules[j].Name
+					break variants
+				}
+			}
+		}
+	}
+}
+
+// riskClass returns the CSS class used to color a risk label.
+func riskClass(risk string) string {
+	if risk == "" {
+		return ""
+	}
+	return "risk-" + strings.ToLower(strings.Join(strings.Fields(risk), "-"))
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultScanDir is the directory of the scan command's nmap XML output when
+// --nmap-dir is not set.
+const defaultScanDir = "scans"
+
+// scanOutputFlags are the nmap options that choose the output or the targets,
+// which the scan command sets itself.
+var scanOutputFlags = []string{"-oX", "-oA", "-oN", "-oG", "-oS", "-oM", "-iL"}
+
+// runScan runs nmap with nmapArgs against the targets file into the scan
+// directory, and then writes the report of the directory's scans.
+func runScan(f *scanFlags, nmapArgs []string) error {
+	if f.targets == "" {
+		return errors.New("Please provide the file of targets to scan using the --targets flag")
+	}
+	if f.report.input.watch {
+		return errors.New("--watch cannot be used with scan")
+	}
+	for _, arg := range nmapArgs {
+		for _, flag := range scanOutputFlags {
+			if arg == flag || strings.HasPrefix(arg, flag+"=") {
+				return fmt.Errorf("%s is set by scan; pass only the scan options after --", flag)
+			}
+		}
+	}
+	nmapPath, err := exec.LookPath(f.nmap)
+	if err != nil {
+		return fmt.Errorf("Error finding nmap: %w", err)
+	}
+
+	dir := defaultScanDir
+	if len(f.report.input.nmapDirs) > 0 {
+		dir = f.report.input.nmapDirs[0]
+	}
+	dir, err = resolveAbsPath(dir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	output := filepath.Join(dir, "scan-"+time.Now().Format("20060102-150405")+".xml")
+
+	args := append(append([]string{}, nmapArgs...), "-iL", f.targets, "-oX", output)
+	cmd := exec.Command(nmapPath, args...)
+	// nmap's progress goes to stderr, so export formats written to stdout by
+	// the report stay clean.
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if quiet {
+		cmd.Stdout = io.Discard
+	}
+	logger.Info("running nmap", "args", strings.Join(args, " "))
+	start := time.Now()
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error running nmap: %w", err)
+	}
+	logger.Info("nmap finished", "output", output, "duration", time.Since(start).Round(time.Second))
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Nmap XML written to %s\n", output)
+	}
+
+	// The report covers every scan of the directory, so repeated scans of an
+	// engagement merge into one report.
+	f.report.input.nmapDirs = []string{dir}
+	return runReport(&f.report, nil)
+}
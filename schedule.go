@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scheduleAliases are the shorthands of common cron schedules.
+var scheduleAliases = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// schedule is when the daemon command runs: either every interval, or at the
+// times matching the five fields of a cron expression.
+type schedule struct {
+	interval time.Duration
+	// minute, hour, day, month and weekday are the values each field of a
+	// cron expression matches.
+	minute, hour, day, month, weekday map[int]bool
+	// anyDay and anyWeekday are set when the day of month or the day of week
+	// field is *. As in cron, a time matches when either of the two fields
+	// matches if both are restricted.
+	anyDay, anyWeekday bool
+}
+
+// parseSchedule parses spec, a cron expression of minute, hour, day of month,
+// month and day of week fields (e.g. "0 6 * * 1-5"), one of scheduleAliases
+// such as @daily, or @every followed by a duration (e.g. "@every 30m").
+func parseSchedule(spec string) (schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if interval, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil {
+			return schedule{}, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		if d < time.Minute {
+			return schedule{}, fmt.Errorf("invalid schedule %q: the interval must be at least a minute", spec)
+		}
+		return schedule{interval: d}, nil
+	}
+	if alias, ok := scheduleAliases[spec]; ok {
+		spec = alias
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return schedule{}, fmt.Errorf("invalid schedule %q: want 5 fields (minute hour day month weekday), @every DURATION or @hourly, @daily, @weekly or @monthly", spec)
+	}
+	var s schedule
+	var err error
+	ranges := []struct {
+		values   *map[int]bool
+		min, max int
+		name     string
+	}{
+		{&s.minute, 0, 59, "minute"},
+		{&s.hour, 0, 23, "hour"},
+		{&s.day, 1, 31, "day of month"},
+		{&s.month, 1, 12, "month"},
+		// 7 is also Sunday.
+		{&s.weekday, 0, 7, "day of week"},
+	}
+	for i, r := range ranges {
+		if *r.values, err = parseScheduleField(fields[i], r.min, r.max); err != nil {
+			return schedule{}, fmt.Errorf("invalid schedule %q: %s: %w", spec, r.name, err)
+		}
+	}
+	if s.weekday[7] {
+		s.weekday[0] = true
+	}
+	s.anyDay = fields[2] == "*"
+	s.anyWeekday = fields[4] == "*"
+	return s, nil
+}
+
+// parseScheduleField returns the values between min and max that field, a
+// comma separated list of *, values, ranges (1-5) and steps (*/15, 0-30/10),
+// matches.
+func parseScheduleField(field string, min, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+		low, high := min, max
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(lowPart); err != nil {
+				return nil, fmt.Errorf("invalid value %q", lowPart)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(highPart); err != nil {
+					return nil, fmt.Errorf("invalid value %q", highPart)
+				}
+			} else if hasStep {
+				high = max
+			}
+			if low < min || high > max || low > high {
+				return nil, fmt.Errorf("%q is out of range %d-%d", rangePart, min, max)
+			}
+		}
+		for value := low; value <= high; value += step {
+			values[value] = true
+		}
+	}
+	return values, nil
+}
+
+// matchesDay reports whether the day of t is one of the schedule's.
+func (s schedule) matchesDay(t time.Time) bool {
+	day, weekday := s.day[t.Day()], s.weekday[int(t.Weekday())]
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	}
+	return day || weekday
+}
+
+// next returns the first time after after that the schedule runs, or the zero
+// time if it never does.
+func (s schedule) next(after time.Time) time.Time {
+	if s.interval > 0 {
+		return after.Add(s.interval)
+	}
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	// A schedule that can run at all, unlike 30 February, runs within a few
+	// years: 29 February at worst.
+	for limit := t.AddDate(8, 0, 0); t.Before(limit); {
+		switch {
+		case !s.month[int(t.Month())]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !s.hour[t.Hour()]:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !s.minute[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
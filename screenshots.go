@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/base64"
+	"html/template"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// screenshotExtensions are the image files read from a --screenshots
+// directory.
+var screenshotExtensions = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".webp": true}
+
+var (
+	// screenshotSeparators are the characters that screenshot tools replace,
+	// each in its own way, in the URLs they name their files after.
+	screenshotSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+	// screenshotHash is the hash of the URL aquatone ends its file names with.
+	screenshotHash = regexp.MustCompile(`-[0-9a-f]{16,40}$`)
+)
+
+// screenshotKey returns name, a URL or the name of a screenshot file without
+// its extension, reduced to its lowercase letters and digits separated by
+// dashes, so that https://10.0.0.5:8443/ matches gowitness' https---10.0.0.5-8443,
+// aquatone's https__10_0_0_5__8443__<hash> and EyeWitness' https.10.0.0.5.8443.
+func screenshotKey(name string) string {
+	return strings.Trim(screenshotSeparators.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// screenshotIndex is the screenshots of a --screenshots directory by the URL
+// they were taken of.
+type screenshotIndex struct {
+	files map[string]string
+	// base is the directory the paths of the screenshots are relative to,
+	// that of the report linking them; absolute paths are returned when it is
+	// empty.
+	base string
+	// link links the screenshots from HTML reports instead of embedding them,
+	// see --link-screenshots.
+	link bool
+	// originals are the original paths of the screenshots by their redacted
+	// paths, see redactor.screenshot.
+	originals map[string]string
+}
+
+// loadScreenshots indexes the images of dir and its subdirectories, as written
+// by gowitness, aquatone or EyeWitness, to be linked from HTML reports when
+// link is set or else embedded in them. A nil index is returned when dir is
+// empty.
+func loadScreenshots(dir string, link bool) (*screenshotIndex, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	dir, err := resolveAbsPath(dir)
+	if err != nil {
+		return nil, err
+	}
+	index := &screenshotIndex{files: make(map[string]string), link: link}
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if entry.IsDir() || !screenshotExtensions[ext] {
+			return nil
+		}
+		key := screenshotKey(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+		if _, ok := index.files[key]; !ok {
+			index.files[key] = path
+		}
+		if unhashed := screenshotHash.ReplaceAllString(key, ""); unhashed != key {
+			if _, ok := index.files[unhashed]; !ok {
+				index.files[unhashed] = path
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("indexed screenshots", "dir", dir, "files", len(index.files))
+	return index, nil
+}
+
+// lookup returns the path of the screenshot of the web server of hostPort, or
+// "" if there is none. Screenshots are matched by the hostname or address of
+// the URL, with or without its port when it is the default one.
+func (s *screenshotIndex) lookup(hostPort *HostPort) string {
+	u, err := url.Parse(hostPort.URL)
+	if err != nil || hostPort.URL == "" {
+		return ""
+	}
+	for _, host := range []string{u.Hostname(), hostPort.Addr} {
+		names := []string{u.Scheme + "://" + host + ":" + hostPort.Port}
+		if u.Port() == "" {
+			names = append(names, u.Scheme+"://"+host)
+		}
+		for _, name := range names {
+			if path, ok := s.files[screenshotKey(name)]; ok {
+				return s.relative(path)
+			}
+		}
+	}
+	return ""
+}
+
+// relative returns path relative to the base of s, with forward slashes as in
+// URLs.
+func (s *screenshotIndex) relative(path string) string {
+	if s.base != "" {
+		if rel, err := filepath.Rel(s.base, path); err == nil {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+// linkFrom makes the paths of the screenshots relative to the directory of
+// the report file, so that the report and the screenshots can be moved
+// together.
+func (s *screenshotIndex) linkFrom(reportFile string) {
+	if s == nil {
+		return
+	}
+	if abs, err := filepath.Abs(reportFile); err == nil {
+		s.base = filepath.Dir(abs)
+	}
+}
+
+// redacted returns a copy of s embedding the screenshots of redacted reports,
+// read from originals by their redacted paths.
+func (s *screenshotIndex) redacted(originals map[string]string) *screenshotIndex {
+	if s == nil {
+		return nil
+	}
+	redacted := *s
+	redacted.originals = originals
+	return &redacted
+}
+
+// dataURI returns the screenshot at path, as returned by lookup, as a data URI
+// to embed in an HTML report, or "" when the screenshots are linked or it
+// cannot be read.
+func (s *screenshotIndex) dataURI(path string) template.URL {
+	if s == nil || s.link || path == "" {
+		return ""
+	}
+	if original, ok := s.originals[path]; ok {
+		path = original
+	}
+	path = filepath.FromSlash(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.base, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Warn("could not embed screenshot", "file", path, "err", err)
+		return ""
+	}
+	outputManifest.input(path)
+	mediaType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+	if !strings.HasPrefix(mediaType, "image/") {
+		mediaType = "image/png"
+	}
+	// The URI is built from the file contents, which html/template would
+	// otherwise refuse as an unsafe URL.
+	return template.URL("data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data))
+}
+
+// ScreenshotData returns the screenshot at path, a value of the screenshot
+// column, as a data URI to embed in HTML reports, or "" when the screenshots
+// are linked instead, see --link-screenshots.
+func (r ReportData) ScreenshotData(path string) template.URL {
+	return r.screenshots.dataURI(path)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ServePage is the context passed to the serve.html template.
+type ServePage struct {
+	Services []string
+	Report   ReportData
+}
+
+// HostPage is the context passed to the host.html template.
+type HostPage struct {
+	Addr string
+	// Addresses are every address of the host, including its MAC address.
+	Addresses []NmapAddress
+	Hostnames []string
+	Status    string
+	OS        string
+	Ports     []NmapPort
+}
+
+// reportServer serves interactive reports for a set of parsed nmap runs.
+type reportServer struct {
+	mu     sync.RWMutex
+	runs   []Nmaprun
+	hosts  []NmapHost
+	errors []ParseError
+	// loadedAt is when the runs were last set.
+	loadedAt time.Time
+	// baseline are the scans new ports are counted against, nil without
+	// --baseline.
+	baseline []Nmaprun
+	// opts are applied to every report; the service is chosen per request.
+	opts TableOptions
+	tmpl *template.Template
+}
+
+// ServiceNames returns the sorted, distinct service names of all ports of hosts
+// whose state is in states.
+func ServiceNames(hosts []NmapHost, states map[string]bool) []string {
+	seen := make(map[string]struct{})
+	for i := range hosts {
+		for _, port := range hosts[i].Ports.Port {
+			if states[port.State.State] && port.Service.Name != "" {
+				seen[port.Service.Name] = struct{}{}
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// setRuns replaces the runs served by s and the files that failed to parse.
+func (s *reportServer) setRuns(runs []Nmaprun, parseErrors []ParseError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs = runs
+	s.errors = parseErrors
+	s.loadedAt = time.Now()
+	var hosts []NmapHost
+	for _, host := range s.opts.mergeHosts(runs) {
+		if !s.opts.excluded(hostAddress(&host, s.opts.PreferIPv6)) {
+			hosts = append(hosts, host)
+		}
+	}
+	s.hosts = hosts
+}
+
+// load parses nmapFiles into s. When watchDir is set the directory is watched
+// in the background and s updated as files change; load returns once the
+// files have been parsed for the first time.
+func (s *reportServer) load(nmapFiles []string, watchDir string, walkOpts WalkOptions) error {
+	if watchDir == "" {
+		runs, parseErrors := ParseNmapFiles(nmapFiles)
+		if err := checkParseErrors(parseErrors); err != nil {
+			return err
+		}
+		s.setRuns(runs, parseErrors)
+		return nil
+	}
+	loaded := make(chan struct{})
+	go func() {
+		err := watchNmapDir(watchDir, walkOpts, nmapFiles, func(runs []Nmaprun, parseErrors []ParseError) {
+			s.setRuns(runs, parseErrors)
+			select {
+			case <-loaded:
+			default:
+				close(loaded)
+			}
+		})
+		logger.Error("could not watch directory", "dir", watchDir, "err", err)
+		os.Exit(1)
+	}()
+	<-loaded
+	return nil
+}
+
+func (s *reportServer) handleReport(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	services := ServiceNames(s.hosts, s.opts.States)
+	service := r.URL.Query().Get("service")
+	if service == "" && len(services) > 0 {
+		service = services[0]
+	}
+
+	opts := s.opts
+	opts.ServiceName = service
+	page := ServePage{
+		Services: services,
+		Report:   BuildTableData(s.runs, opts),
+	}
+	page.Report.Errors = s.errors
+	if err := s.tmpl.ExecuteTemplate(w, "serve.html", page); err != nil {
+		logger.Error("could not render report page", "service", service, "err", err)
+	}
+}
+
+func (s *reportServer) handleHost(w http.ResponseWriter, r *http.Request) {
+	addr := r.URL.Query().Get("addr")
+	page := HostPage{Addr: addr}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var host *NmapHost
+	for i := range s.hosts {
+		if hostAddress(&s.hosts[i], s.opts.PreferIPv6) == addr {
+			host = &s.hosts[i]
+			break
+		}
+	}
+	if host == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	page.Status = host.Status.State
+	page.Addresses = host.Address
+	page.OS = hostOS(host)
+	for _, hostname := range host.Hostnames.Hostname {
+		page.Hostnames = append(page.Hostnames, hostname.Name)
+	}
+	page.Ports = host.Ports.Port
+	if err := s.tmpl.ExecuteTemplate(w, "host.html", page); err != nil {
+		logger.Error("could not render host page", "host", addr, "err", err)
+	}
+}
+
+// runServe implements the serve subcommand, which parses nmapFiles once and
+// serves an interactive report over HTTP, and Prometheus metrics at /metrics.
+// When watchDir is set the directory is watched and the report reloaded as
+// files change. The metrics count the ports that are new since baselineFiles.
+func runServe(nmapFiles []string, opts TableOptions, port int, watchDir string, walkOpts WalkOptions, baselineFiles []string) error {
+	tmpl, err := template.New("serve").Funcs(templateFuncs).ParseFS(templateFS, "serve.html", "host.html")
+	if err != nil {
+		return fmt.Errorf("Error parsing template: %w", err)
+	}
+
+	srv := &reportServer{
+		opts: opts,
+		tmpl: tmpl,
+	}
+	if err := srv.loadBaseline(baselineFiles); err != nil {
+		return err
+	}
+	if err := srv.load(nmapFiles, watchDir, walkOpts); err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", srv.handleReport)
+	mux.HandleFunc("/host", srv.handleHost)
+	mux.HandleFunc("/metrics", srv.handleMetrics)
+
+	listenAddr := fmt.Sprintf("localhost:%d", port)
+	statusf("Serving report for %d files at http://%s/\n", len(nmapFiles), listenAddr)
+	return http.ListenAndServe(listenAddr, mux)
+}
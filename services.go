@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// ServiceCount is the number of hosts and ports on which a service was found.
+type ServiceCount struct {
+	Service string `json:"service"`
+	Hosts   int    `json:"hosts"`
+	Ports   int    `json:"ports"`
+}
+
+// CountServices counts the hosts and ports of every service in runs that match
+// the state and exclusion filters of opts. opts.ServiceName is ignored. The
+// result is sorted by number of ports, most common first.
+func CountServices(runs []Nmaprun, opts TableOptions) []ServiceCount {
+	counts := make(map[string]*ServiceCount)
+	hosts := MergeHosts(runs, opts.PreferIPv6)
+	for i := range hosts {
+		host := &hosts[i]
+		if opts.excluded(hostAddress(host, opts.PreferIPv6)) {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, port := range host.Ports.Port {
+			if !opts.States[port.State.State] {
+				continue
+			}
+			name := port.Service.Name
+			if name == "" {
+				name = "unknown"
+			}
+			count, ok := counts[name]
+			if !ok {
+				count = &ServiceCount{Service: name}
+				counts[name] = count
+			}
+			count.Ports++
+			if !seen[name] {
+				seen[name] = true
+				count.Hosts++
+			}
+		}
+	}
+
+	result := make([]ServiceCount, 0, len(counts))
+	for _, count := range counts {
+		result = append(result, *count)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Ports != result[j].Ports {
+			return result[i].Ports > result[j].Ports
+		}
+		return result[i].Service < result[j].Service
+	})
+	return result
+}
+
+// writeServiceCounts writes counts to w as an aligned text table.
+func writeServiceCounts(w io.Writer, counts []ServiceCount) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVICE\tHOSTS\tPORTS")
+	for _, count := range counts {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", count.Service, count.Hosts, count.Ports)
+	}
+	return tw.Flush()
+}
+
+// VersionCount is the number of hosts and ports running a version of a service.
+type VersionCount struct {
+	// Rank is the position of the combination in the ranking, from 1.
+	Rank    int    `json:"rank"`
+	Service string `json:"service"`
+	Product string `json:"product"`
+	Version string `json:"version"`
+	Hosts   int    `json:"hosts"`
+	Ports   int    `json:"ports"`
+}
+
+// Label returns the product and version for display.
+func (c VersionCount) Label() string {
+	label := strings.TrimSpace(c.Product + " " + c.Version)
+	if label == "" {
+		return "unknown"
+	}
+	return label
+}
+
+// CountVersions ranks the service and version combinations of every service in
+// runs that match the state and exclusion filters of opts, most ports first,
+// and returns the top n. opts.ServiceName is ignored.
+func CountVersions(runs []Nmaprun, opts TableOptions, n int) []VersionCount {
+	counts := make(map[VersionCount]*VersionCount)
+	hosts := MergeHosts(runs, opts.PreferIPv6)
+	normalizeHosts(hosts, opts.Normalize)
+	for i := range hosts {
+		host := &hosts[i]
+		if opts.excluded(hostAddress(host, opts.PreferIPv6)) {
+			continue
+		}
+		seen := make(map[VersionCount]bool)
+		for _, port := range host.Ports.Port {
+			if !opts.States[port.State.State] {
+				continue
+			}
+			key := VersionCount{Service: port.Service.Name, Product: port.Service.Product, Version: port.Service.Version}
+			if key.Service == "" {
+				key.Service = "unknown"
+			}
+			count, ok := counts[key]
+			if !ok {
+				count = &VersionCount{Service: key.Service, Product: key.Product, Version: key.Version}
+				counts[key] = count
+			}
+			count.Ports++
+			if !seen[key] {
+				seen[key] = true
+				count.Hosts++
+			}
+		}
+	}
+
+	result := make([]VersionCount, 0, len(counts))
+	for _, count := range counts {
+		result = append(result, *count)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Ports != result[j].Ports {
+			return result[i].Ports > result[j].Ports
+		}
+		if result[i].Hosts != result[j].Hosts {
+			return result[i].Hosts > result[j].Hosts
+		}
+		if result[i].Service != result[j].Service {
+			return result[i].Service < result[j].Service
+		}
+		return result[i].Label() < result[j].Label()
+	})
+	if n > 0 && len(result) > n {
+		result = result[:n]
+	}
+	for i := range result {
+		result[i].Rank = i + 1
+	}
+	return result
+}
+
+// writeVersionCounts writes counts to w as an aligned, ranked text table.
+func writeVersionCounts(w io.Writer, counts []VersionCount) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RANK\tSERVICE\tVERSION\tHOSTS\tPORTS")
+	for _, count := range counts {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%d\n", count.Rank, count.Service, count.Label(), count.Hosts, count.Ports)
+	}
+	return tw.Flush()
+}
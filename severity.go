@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// severityLevels are the row severities of --color-rows, from least to most
+// severe, named as the risk labels.
+var severityLevels = []string{"info", "low", "medium", "high", "critical"}
+
+// hostThreshold raises the rows of at least hosts hosts to level.
+type hostThreshold struct {
+	level string
+	hosts int
+}
+
+// rowSeverity colors the rows of HTML reports by severity, see --color-rows:
+// that of their risk label, medium for end-of-life versions, or that of the
+// largest host count threshold they reach, whichever is the most severe.
+type rowSeverity struct {
+	hosts []hostThreshold
+}
+
+// parseRowSeverity parses the --severity-hosts thresholds, a comma separated
+// list of LEVEL=HOSTS pairs such as medium=10,high=25.
+func parseRowSeverity(value string) (*rowSeverity, error) {
+	s := &rowSeverity{}
+	for _, part := range splitList(value) {
+		level, hosts, ok := strings.Cut(part, "=")
+		level = strings.ToLower(strings.TrimSpace(level))
+		n, err := strconv.Atoi(strings.TrimSpace(hosts))
+		if !ok || err != nil || n < 1 || !slices.Contains(severityLevels, level) {
+			return nil, fmt.Errorf("invalid threshold %q (use LEVEL=HOSTS, e.g. high=25, with a level of %s)", part, strings.Join(severityLevels, ", "))
+		}
+		s.hosts = append(s.hosts, hostThreshold{level: level, hosts: n})
+	}
+	slices.SortFunc(s.hosts, func(a, b hostThreshold) int { return a.hosts - b.hosts })
+	return s, nil
+}
+
+// severityLevel returns the level of a risk label such as "High", or "" for
+// labels that are not one.
+func severityLevel(risk string) string {
+	level := strings.ToLower(strings.TrimSpace(risk))
+	if level == "informational" {
+		return "info"
+	}
+	if slices.Contains(severityLevels, level) {
+		return level
+	}
+	return ""
+}
+
+// level returns the severity of row, or "" if nothing makes it stand out.
+func (s *rowSeverity) level(row *ReportRow) string {
+	level := severityLevel(row.Risk)
+	raise := func(to string) {
+		if slices.Index(severityLevels, to) > slices.Index(severityLevels, level) {
+			level = to
+		}
+	}
+	if row.EOL != "" {
+		raise("medium")
+	}
+	for _, threshold := range s.hosts {
+		if row.HostCount() >= threshold.hosts {
+			raise(threshold.level)
+		}
+	}
+	return level
+}
+
+// RowClasses returns the CSS classes of row in HTML reports: its Classes and,
+// with --color-rows, severity-<level>.
+func (r ReportData) RowClasses(row ReportRow) string {
+	classes := row.Classes()
+	if r.Severity == nil {
+		return classes
+	}
+	if level := r.Severity.level(&row); level != "" {
+		classes = strings.TrimSpace(classes + " severity-" + level)
+	}
+	return classes
+}
+
+// SeverityLegend is a row color of the legend of HTML reports, with what gives
+// rows that color.
+type SeverityLegend struct {
+	Level    string
+	Title    string
+	Criteria string
+}
+
+// SeverityLegend returns the legend of the row colors of --color-rows, most
+// severe first, or nil when rows are not colored.
+func (r ReportData) SeverityLegend() []SeverityLegend {
+	if r.Severity == nil {
+		return nil
+	}
+	var legend []SeverityLegend
+	for i := len(severityLevels) - 1; i >= 0; i-- {
+		level := severityLevels[i]
+		title := r.T(strings.ToUpper(level[:1]) + level[1:])
+		criteria := []string{r.T("risk rules labelled %s", title)}
+		if level == "medium" {
+			criteria = append(criteria, r.T("end-of-life versions"))
+		}
+		for _, threshold := range r.Severity.hosts {
+			if threshold.level == level {
+				criteria = append(criteria, r.T("%d or more hosts", threshold.hosts))
+			}
+		}
+		legend = append(legend, SeverityLegend{Level: level, Title: title, Criteria: strings.Join(criteria, ", ")})
+	}
+	return legend
+}
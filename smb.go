@@ -0,0 +1,57 @@
+package main
+
+import "strings"
+
+// smbColumns are the default columns of the smb posture table.
+var smbColumns = []string{"host", "hostname", "smb-os", "smb-v1", "smb-signing", "smb-guest", "smb-issues"}
+
+// isSMBPort reports whether port is the SMB port of a host. NetBIOS session
+// ports are left out, as they report the same host scripts again.
+func isSMBPort(port *NmapPort) bool {
+	return port.Service.Name == "microsoft-ds"
+}
+
+// smbDetails sets the SMB posture found by the host scripts of a host: whether
+// SMBv1 is enabled, guest access and the issues found. smb-security-mode only
+// runs over SMBv1, so its output implies it.
+func smbDetails(scripts []NmapScript, set func(key, value string)) {
+	v1 := ""
+	var guest, signingNotRequired bool
+	for i := range scripts {
+		script := &scripts[i]
+		switch script.ID {
+		case "smb-protocols":
+			v1 = "disabled"
+			for _, dialect := range tableTexts(script.Table, "dialects") {
+				if strings.Contains(dialect, "SMBv1") || strings.HasPrefix(dialect, "NT LM 0.12") {
+					v1 = "enabled"
+				}
+			}
+		case "smb-security-mode":
+			v1 = "enabled"
+			guest = guest || elemValue(script.Elem, "account_used") == "guest"
+			switch elemValue(script.Elem, "message_signing") {
+			case "disabled", "supported":
+				signingNotRequired = true
+			}
+		case "smb2-security-mode":
+			signingNotRequired = signingNotRequired || strings.Contains(findElem(script.Table, ""), "not required")
+		case "smb-enum-shares":
+			guest = guest || elemValue(script.Elem, "account_used") == "guest"
+		}
+	}
+
+	set("smb-v1", v1)
+	var issues []string
+	if v1 == "enabled" {
+		issues = append(issues, "SMBv1 enabled")
+	}
+	if signingNotRequired {
+		issues = append(issues, "signing not required")
+	}
+	if guest {
+		set("smb-guest", "yes")
+		issues = append(issues, "guest access")
+	}
+	set("smb-issues", strings.Join(issues, "; "))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// snmpColumns are the default columns of the snmp findings table.
+var snmpColumns = []string{"host", "hostname", "snmp-device", "snmp-sysdescr", "snmp-communities"}
+
+// snmpDevices guess the device type of an SNMP agent from its sysDescr when
+// nmap did not report one, first match wins.
+var snmpDevices = []struct {
+	pattern *regexp.Regexp
+	device  string
+}{
+	{regexp.MustCompile(`(?i)cisco ios.*(c2960|c3560|c3750|c9200|c9300|catalyst|switch)`), "switch"},
+	{regexp.MustCompile(`(?i)cisco (ios|nx-os|adaptive security)`), "router"},
+	{regexp.MustCompile(`(?i)juniper|junos|mikrotik|routeros|fortigate|pan-os`), "router"},
+	{regexp.MustCompile(`(?i)procurve|aruba|dell networking|netgear|hp switch`), "switch"},
+	{regexp.MustCompile(`(?i)jetdirect|laserjet|printer|ricoh|xerox|kyocera|lexmark|brother nc`), "printer"},
+	{regexp.MustCompile(`(?i)\bapc\b|\bups\b|powerware|eaton|liebert`), "power-device"},
+	{regexp.MustCompile(`(?i)vmware esxi`), "hypervisor"},
+	{regexp.MustCompile(`(?i)synology|qnap|netapp|diskstation`), "storage-misc"},
+	{regexp.MustCompile(`(?i)hardware:.*software: windows|windows`), "general purpose"},
+	{regexp.MustCompile(`(?i)linux|freebsd|sunos|hp-ux|aix`), "general purpose"},
+}
+
+// snmpCommunityLine matches a community string reported by snmp-brute in its
+// text output, e.g. "public - Valid credentials".
+var snmpCommunityLine = regexp.MustCompile(`^\s*(\S+)\s+-\s+Valid credentials`)
+
+// isSNMPPort reports whether port is the SNMP agent of a host.
+func isSNMPPort(port *NmapPort) bool {
+	return port.Service.Name == "snmp" || (port.Portid == "161" && port.Protocol == "udp")
+}
+
+// snmpDetails sets the SNMP findings of the scripts of port: the sysDescr of
+// snmp-sysdescr, the enterprise of snmp-info, the device type, reported by
+// nmap or guessed from the sysDescr, and the community strings snmp-brute
+// found valid.
+func snmpDetails(port *NmapPort, set func(key, value string)) {
+	var sysDescr string
+	var communities []string
+	for i := range port.Script {
+		script := &port.Script[i]
+		switch script.ID {
+		case "snmp-sysdescr":
+			// The first line is the sysDescr, followed by the uptime.
+			sysDescr, _, _ = strings.Cut(strings.TrimSpace(script.Output), "\n")
+			set("snmp-sysdescr", sysDescr)
+		case "snmp-info":
+			set("snmp-enterprise", elemValue(script.Elem, "enterprise"))
+		case "snmp-brute":
+			for _, table := range script.Table {
+				if strings.Contains(elemValue(table.Elem, "state"), "Valid") && !slices.Contains(communities, table.Key) {
+					communities = append(communities, table.Key)
+				}
+			}
+			// Older versions of nmap only have the text output.
+			for _, line := range strings.Split(script.Output, "\n") {
+				if match := snmpCommunityLine.FindStringSubmatch(line); match != nil && !slices.Contains(communities, match[1]) {
+					communities = append(communities, match[1])
+				}
+			}
+		}
+	}
+	set("snmp-communities", strings.Join(communities, ", "))
+	set("snmp-device", port.Service.Devicetype)
+	if sysDescr != "" {
+		for _, device := range snmpDevices {
+			if device.pattern.MatchString(sysDescr) {
+				set("snmp-device", device.device)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// splitKeys return the key of the output file each row belongs to for
+// --split-by.
+var splitKeys = map[string]func(row *ReportRow) string{
+	"product": func(row *ReportRow) string { return row.Product },
+	"version": func(row *ReportRow) string { return row.Version },
+}
+
+// reportPart is the report written to one of the files of --split-by.
+type reportPart struct {
+	key     string
+	reports []ReportData
+}
+
+// splitReports splits reports into one part per key of their rows, in order of
+// first appearance. Each part keeps the sections of every report with rows for
+// its key, with their summaries recounted.
+func splitReports(reports []ReportData, splitBy string) []reportPart {
+	key := splitKeys[splitBy]
+	var parts []reportPart
+	index := make(map[string]int)
+	partFor := func(name string) *reportPart {
+		i, ok := index[name]
+		if !ok {
+			i = len(parts)
+			index[name] = i
+			parts = append(parts, reportPart{key: name})
+		}
+		return &parts[i]
+	}
+
+	for _, report := range reports {
+		sections := make(map[string]*ReportData)
+		var order []string
+		section := func(name string) *ReportData {
+			if data, ok := sections[name]; ok {
+				return data
+			}
+			data := report
+			data.Rows, data.Unverified, data.Paths = nil, nil, nil
+			sections[name] = &data
+			order = append(order, name)
+			return &data
+		}
+		for _, row := range report.Rows {
+			data := section(key(&row))
+			data.Rows = append(data.Rows, row)
+		}
+		for _, row := range report.Unverified {
+			data := section(key(&row))
+			data.Unverified = append(data.Unverified, row)
+		}
+		for _, name := range order {
+			data := sections[name]
+			recountSummary(data)
+			part := partFor(name)
+			part.reports = append(part.reports, *data)
+		}
+	}
+	return parts
+}
+
+// recountSummary updates the matching port and version counts of data to its
+// rows.
+func recountSummary(data *ReportData) {
+	data.Summary.MatchingPorts = 0
+	versions := make(map[productVersion]struct{})
+	for _, row := range data.Rows {
+		data.Summary.MatchingPorts += len(row.Hosts)
+		for _, pv := range row.versions {
+			versions[pv] = struct{}{}
+		}
+	}
+	for _, row := range data.Unverified {
+		data.Summary.MatchingPorts += len(row.Hosts)
+	}
+	data.Summary.DistinctVersions = len(versions)
+}
+
+// splitPath returns the file of outputFilename for the part key, e.g.
+// http_apache-httpd.html for http.html and "Apache httpd".
+func splitPath(outputFilename, key string) string {
+	ext := filepath.Ext(outputFilename)
+	return fmt.Sprintf("%s_%s%s", strings.TrimSuffix(outputFilename, ext), fileSlug(key), ext)
+}
+
+// fileSlug turns name into a lower case file name component, e.g.
+// "microsoft-iis-httpd-10.0" for "Microsoft IIS httpd 10.0".
+func fileSlug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	if b.Len() == 0 {
+		return "unknown"
+	}
+	return b.String()
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// splunkSourcetype is the default sourcetype of the Splunk events.
+const splunkSourcetype = "nmaptables"
+
+// splunkBatchSize is the number of events posted to HEC per request.
+const splunkBatchSize = 500
+
+// splunkEvent is a Splunk HTTP Event Collector event: one finding with the
+// time of the scan and its metadata.
+type splunkEvent struct {
+	Time       int64         `json:"time,omitempty"`
+	Host       string        `json:"host"`
+	Source     string        `json:"source,omitempty"`
+	Sourcetype string        `json:"sourcetype"`
+	Index      string        `json:"index,omitempty"`
+	Event      splunkFinding `json:"event"`
+}
+
+// splunkFinding is a host port running the reported service.
+type splunkFinding struct {
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Protocol string `json:"protocol"`
+	Service  string `json:"service"`
+	Product  string `json:"product,omitempty"`
+	Version  string `json:"version,omitempty"`
+	CPE      string `json:"cpe,omitempty"`
+	Verified bool   `json:"verified"`
+	Hostname string `json:"hostname,omitempty"`
+	OS       string `json:"os,omitempty"`
+	Country  string `json:"country,omitempty"`
+	ASN      string `json:"asn,omitempty"`
+	Owner    string `json:"owner,omitempty"`
+	Risk     string `json:"risk,omitempty"`
+	EOL      string `json:"eol,omitempty"`
+}
+
+// splunkRenderer writes the host ports of a report as Splunk HEC events, one
+// JSON object per line, ready to be posted to /services/collector/event.
+type splunkRenderer struct {
+	index      string
+	sourcetype string
+}
+
+func (r splunkRenderer) Render(tableData ReportData, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	encoder := json.NewEncoder(bw)
+	for _, event := range r.events(tableData) {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// events returns an event per host port of the verified and unverified rows
+// of tableData, timed at the start of the scans unless the port has its own
+// time from --merge all.
+func (r splunkRenderer) events(tableData ReportData) []splunkEvent {
+	sourcetype := r.sourcetype
+	if sourcetype == "" {
+		sourcetype = splunkSourcetype
+	}
+	var events []splunkEvent
+	for _, rows := range [][]ReportRow{tableData.Rows, tableData.Unverified} {
+		for _, row := range rows {
+			for _, host := range row.Hosts {
+				seen := host.Seen
+				if seen.IsZero() {
+					seen = tableData.Summary.ScanStart
+				}
+				event := splunkEvent{
+					Host:       host.Addr,
+					Source:     host.Source,
+					Sourcetype: sourcetype,
+					Index:      r.index,
+					Event: splunkFinding{
+						Host:     host.Addr,
+						Port:     host.Port,
+						Protocol: row.Protocol,
+						Service:  row.Service,
+						Product:  row.Product,
+						Version:  row.ProductVersion,
+						CPE:      host.CPE,
+						Verified: row.Verified,
+						Hostname: host.Hostname,
+						OS:       host.OS,
+						Country:  host.Country,
+						ASN:      host.ASN,
+						Owner:    host.Owner,
+						Risk:     row.Risk,
+						EOL:      row.EOL,
+					},
+				}
+				if !seen.IsZero() {
+					event.Time = seen.Unix()
+				}
+				events = append(events, event)
+			}
+		}
+	}
+	return events
+}
+
+// splunkClient posts events to a Splunk HTTP Event Collector.
+type splunkClient struct {
+	endpoint string
+	token    string
+	client   *http.Client
+}
+
+// newSplunkClient returns a client of the HEC at rawURL, which defaults to the
+// /services/collector/event path; insecure skips verifying its certificate,
+// as HEC commonly runs with a self-signed one.
+func newSplunkClient(rawURL, token string, insecure bool) (*splunkClient, error) {
+	endpoint, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme: %q (use https or http)", endpoint.Scheme)
+	}
+	if endpoint.Path == "" || endpoint.Path == "/" {
+		endpoint.Path = "/services/collector/event"
+	}
+	if token == "" {
+		return nil, errors.New("no token (set --hec-token or SPLUNK_HEC_TOKEN)")
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &splunkClient{
+		endpoint: endpoint.String(),
+		token:    token,
+		client:   &http.Client{Transport: transport, Timeout: 30 * time.Second},
+	}, nil
+}
+
+// send posts events in batches of splunkBatchSize.
+func (c *splunkClient) send(events []splunkEvent) error {
+	for start := 0; start < len(events); start += splunkBatchSize {
+		var body bytes.Buffer
+		encoder := json.NewEncoder(&body)
+		for _, event := range events[start:min(start+splunkBatchSize, len(events))] {
+			if err := encoder.Encode(event); err != nil {
+				return err
+			}
+		}
+		if err := c.post(&body); err != nil {
+			return err
+		}
+		logger.Debug("posted events to Splunk HEC", "events", min(splunkBatchSize, len(events)-start), "endpoint", c.endpoint)
+	}
+	return nil
+}
+
+// post sends a single request of events to the collector.
+func (c *splunkClient) post(body io.Reader) error {
+	req, err := http.NewRequest(http.MethodPost, c.endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Splunk "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		// HEC explains errors as {"text": "...", "code": N}.
+		var reply struct {
+			Text string `json:"text"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(data, &reply) != nil || reply.Text == "" {
+			reply.Text = strings.TrimSpace(string(data))
+		}
+		return fmt.Errorf("Splunk HEC returned %s: %s", resp.Status, reply.Text)
+	}
+	return nil
+}
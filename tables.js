@@ -0,0 +1,144 @@
+// Search, column sorting, per-column filters and pagination for every
+// table.interactive of a report. The controls are created by the script, so the
+// plain HTML still imports cleanly into Word.
+(function () {
+    "use strict";
+
+    var pageSizes = [25, 50, 100, 0];
+
+    function text(cell) {
+        return cell.textContent.trim().toLowerCase();
+    }
+
+    function compare(a, b) {
+        return a.localeCompare(b, undefined, {numeric: true, sensitivity: "base"});
+    }
+
+    function enhance(table) {
+        var header = table.rows[0];
+        var rows = Array.prototype.slice.call(table.rows, 1);
+        if (!header || rows.length === 0) {
+            return;
+        }
+        var body = rows[0].parentNode;
+        var state = {query: "", filters: [], column: -1, ascending: true, page: 0, size: pageSizes[0]};
+
+        var controls = document.createElement("div");
+        controls.className = "table-controls";
+        var search = document.createElement("input");
+        search.type = "search";
+        search.placeholder = "Search";
+        search.addEventListener("input", function () {
+            state.query = search.value.toLowerCase();
+            state.page = 0;
+            render();
+        });
+        var size = document.createElement("select");
+        pageSizes.forEach(function (n) {
+            var option = document.createElement("option");
+            option.value = n;
+            option.textContent = n ? n + " rows" : "All rows";
+            size.appendChild(option);
+        });
+        size.addEventListener("change", function () {
+            state.size = parseInt(size.value, 10);
+            state.page = 0;
+            render();
+        });
+        controls.appendChild(search);
+        controls.appendChild(size);
+        table.parentNode.insertBefore(controls, table);
+
+        var filterRow = document.createElement("tr");
+        filterRow.className = "table-filters";
+        Array.prototype.forEach.call(header.cells, function (cell, i) {
+            var th = document.createElement("th");
+            var input = document.createElement("input");
+            input.type = "search";
+            input.placeholder = "Filter";
+            input.addEventListener("input", function () {
+                state.filters[i] = input.value.toLowerCase();
+                state.page = 0;
+                render();
+            });
+            th.appendChild(input);
+            filterRow.appendChild(th);
+
+            cell.classList.add("sortable");
+            cell.title = "Sort by " + cell.textContent;
+            cell.addEventListener("click", function () {
+                state.ascending = state.column === i ? !state.ascending : true;
+                state.column = i;
+                Array.prototype.forEach.call(header.cells, function (other) {
+                    other.classList.remove("sort-asc", "sort-desc");
+                });
+                cell.classList.add(state.ascending ? "sort-asc" : "sort-desc");
+                render();
+            });
+        });
+        header.parentNode.insertBefore(filterRow, header.nextSibling);
+
+        var pager = document.createElement("div");
+        pager.className = "table-pager";
+        var previous = document.createElement("button");
+        previous.textContent = "Previous";
+        previous.addEventListener("click", function () {
+            state.page--;
+            render();
+        });
+        var status = document.createElement("span");
+        var next = document.createElement("button");
+        next.textContent = "Next";
+        next.addEventListener("click", function () {
+            state.page++;
+            render();
+        });
+        pager.appendChild(previous);
+        pager.appendChild(status);
+        pager.appendChild(next);
+        table.parentNode.insertBefore(pager, table.nextSibling);
+
+        function matches(row) {
+            if (state.query && row.textContent.toLowerCase().indexOf(state.query) < 0) {
+                return false;
+            }
+            return state.filters.every(function (filter, i) {
+                return !filter || (row.cells[i] && text(row.cells[i]).indexOf(filter) >= 0);
+            });
+        }
+
+        function render() {
+            if (state.column >= 0) {
+                rows.sort(function (a, b) {
+                    var result = compare(text(a.cells[state.column]), text(b.cells[state.column]));
+                    return state.ascending ? result : -result;
+                });
+                rows.forEach(function (row) {
+                    body.appendChild(row);
+                });
+            }
+            var visible = rows.filter(matches);
+            var pages = state.size ? Math.max(1, Math.ceil(visible.length / state.size)) : 1;
+            state.page = Math.min(Math.max(state.page, 0), pages - 1);
+            var first = state.size ? state.page * state.size : 0;
+            var last = state.size ? Math.min(first + state.size, visible.length) : visible.length;
+
+            rows.forEach(function (row) {
+                row.style.display = "none";
+            });
+            visible.slice(first, last).forEach(function (row) {
+                row.style.display = "";
+            });
+            status.textContent = visible.length
+                ? " " + (first + 1) + "-" + last + " of " + visible.length + " "
+                : " No matching rows ";
+            previous.disabled = state.page === 0;
+            next.disabled = state.page >= pages - 1;
+            pager.style.display = pages > 1 ? "" : "none";
+        }
+
+        render();
+    }
+
+    document.querySelectorAll("table.interactive").forEach(enhance);
+})();
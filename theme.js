@@ -0,0 +1,38 @@
+// Theme toggle of the HTML reports. The chosen theme is remembered in the
+// browser; the --theme of the report is used until one is chosen.
+(function () {
+    "use strict";
+
+    var themes = ["light", "dark", "print"];
+    var key = "nmaptables-theme";
+    var root = document.documentElement;
+    var saved = null;
+    try {
+        saved = window.localStorage.getItem(key);
+    } catch (e) {
+        // Storage is unavailable for some file:// pages.
+    }
+    if (themes.indexOf(saved) >= 0) {
+        root.setAttribute("data-theme", saved);
+    }
+
+    var toggle = document.createElement("select");
+    toggle.className = "theme-toggle";
+    toggle.title = "Theme";
+    themes.forEach(function (theme) {
+        var option = document.createElement("option");
+        option.value = theme;
+        option.textContent = theme.charAt(0).toUpperCase() + theme.slice(1);
+        toggle.appendChild(option);
+    });
+    toggle.value = root.getAttribute("data-theme") || "light";
+    toggle.addEventListener("change", function () {
+        root.setAttribute("data-theme", toggle.value);
+        try {
+            window.localStorage.setItem(key, toggle.value);
+        } catch (e) {
+            // The choice just is not remembered.
+        }
+    });
+    document.body.insertBefore(toggle, document.body.firstChild);
+})();
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// PortHistory is the timeline of a host port across scans taken over time:
+// when it was first and last seen and every version detected on it.
+type PortHistory struct {
+	Host      HostPort  `json:"host"`
+	Protocol  string    `json:"protocol"`
+	Service   string    `json:"service"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	// Versions are the product versions of the port in the order they were
+	// detected, each with the start of the scan that first saw it.
+	Versions []VersionChange `json:"versions"`
+	// Scans is the number of scans the port was seen in.
+	Scans int `json:"scans"`
+}
+
+// VersionChange is a product version detected on a host port and when.
+type VersionChange struct {
+	Seen    time.Time `json:"seen"`
+	Version string    `json:"version"`
+}
+
+// Changed reports whether more than one version was detected on the port.
+func (h PortHistory) Changed() bool {
+	return len(h.Versions) > 1
+}
+
+// BuildTimeline returns the history of every host port of runs matching opts,
+// an empty opts.ServiceName matching every service, ordered by host and port.
+func BuildTimeline(runs []Nmaprun, opts TableOptions) []PortHistory {
+	opts.Merge = mergeAll
+	var timeline []PortHistory
+	hosts := opts.mergeHosts(runs)
+	sort.SliceStable(hosts, func(i, j int) bool {
+		return lessAddr(hostAddress(&hosts[i], opts.PreferIPv6), hostAddress(&hosts[j], opts.PreferIPv6))
+	})
+	for i := range hosts {
+		host := &hosts[i]
+		addr := hostAddress(host, opts.PreferIPv6)
+		if opts.excluded(addr) {
+			continue
+		}
+		var current *PortHistory
+		for _, port := range host.Ports.Port {
+			if !opts.States[port.State.State] {
+				continue
+			}
+			if opts.ServiceName != "" && port.Service.Name != opts.ServiceName {
+				continue
+			}
+			if current == nil || current.Host.Port != port.Portid || current.Protocol != port.Protocol {
+				timeline = append(timeline, PortHistory{
+					Host:      HostPort{Addr: addr, Port: port.Portid},
+					Protocol:  port.Protocol,
+					Service:   port.Service.Name,
+					FirstSeen: port.Seen,
+				})
+				current = &timeline[len(timeline)-1]
+				if len(host.Hostnames.Hostname) > 0 {
+					current.Host.Hostname = host.Hostnames.Hostname[0].Name
+				}
+			}
+			current.LastSeen = port.Seen
+			current.Scans++
+			version := strings.TrimSpace(port.Service.Product + " " + port.Service.Version)
+			if n := len(current.Versions); n == 0 || current.Versions[n-1].Version != version {
+				current.Versions = append(current.Versions, VersionChange{Seen: port.Seen, Version: version})
+			}
+		}
+	}
+	return timeline
+}
+
+// writeTimeline writes timeline to w as a text table, as CSV with a record per
+// version of each port, or as JSON.
+func writeTimeline(w io.Writer, timeline []PortHistory, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if timeline == nil {
+			timeline = []PortHistory{}
+		}
+		return encoder.Encode(timeline)
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"host", "port", "protocol", "service", "first_seen", "last_seen", "scans", "version", "version_seen"}); err != nil {
+			return err
+		}
+		for _, history := range timeline {
+			for _, change := range history.Versions {
+				record := []string{history.Host.Addr, history.Host.Port, history.Protocol, history.Service,
+					formatSeen(history.FirstSeen), formatSeen(history.LastSeen), fmt.Sprint(history.Scans),
+					change.Version, formatSeen(change.Seen)}
+				if err := cw.Write(record); err != nil {
+					return err
+				}
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "", "text":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "HOST\tPROTOCOL\tSERVICE\tFIRST SEEN\tLAST SEEN\tSCANS\tVERSIONS")
+		for _, history := range timeline {
+			versions := make([]string, len(history.Versions))
+			for i, change := range history.Versions {
+				version := change.Version
+				if version == "" {
+					version = "unknown"
+				}
+				versions[i] = version
+				if history.Changed() {
+					versions[i] += fmt.Sprintf(" (%s)", formatDate(change.Seen, time.DateOnly))
+				}
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", history.Host, history.Protocol, history.Service,
+				formatDate(history.FirstSeen, time.DateOnly), formatDate(history.LastSeen, time.DateOnly),
+				history.Scans, strings.Join(versions, " -> "))
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unsupported timeline format: %s (use text, csv or json)", format)
+}
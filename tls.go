@@ -0,0 +1,57 @@
+package main
+
+import "strings"
+
+// tlsServices are the services that speak TLS without nmap reporting an SSL
+// tunnel.
+var tlsServices = map[string]bool{
+	"https":     true,
+	"https-alt": true,
+	"ssl":       true,
+	"imaps":     true,
+	"pop3s":     true,
+	"smtps":     true,
+	"ldaps":     true,
+	"ftps":      true,
+	"nntps":     true,
+	"ircs":      true,
+	"sips":      true,
+}
+
+// isTLSPort reports whether port speaks TLS: nmap detected the service
+// through an SSL tunnel, the service is one of tlsServices, or ssl-cert read a
+// certificate from it (e.g. RDP).
+func isTLSPort(port *NmapPort) bool {
+	if port.Service.Tunnel == "ssl" || tlsServices[port.Service.Name] {
+		return true
+	}
+	for _, script := range port.Script {
+		if script.ID == "ssl-cert" {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesService reports whether port runs service. As in nmap's output, an
+// ssl/ prefix selects the services detected through an SSL tunnel, e.g.
+// ssl/ms-sql-s, and https also selects HTTP servers behind one.
+func matchesService(port *NmapPort, service string) bool {
+	if port.Service.Name == service {
+		return true
+	}
+	if name, ok := strings.CutPrefix(service, "ssl/"); ok {
+		return port.Service.Name == name && port.Service.Tunnel == "ssl"
+	}
+	return service == "https" && port.Service.Name == "http" && port.Service.Tunnel == "ssl"
+}
+
+// tunnelService returns service as nmap displays the ports of a row with
+// tunnel: ssl/http for HTTP in an SSL tunnel. Services whose name already
+// says they are tunnelled are left alone.
+func tunnelService(service, tunnel string) string {
+	if tunnel == "" || tlsServices[service] || strings.HasPrefix(service, tunnel+"/") {
+		return service
+	}
+	return tunnel + "/" + service
+}
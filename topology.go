@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// topologyServices is the number of services listed in a host node of the
+// DOT topology.
+const topologyServices = 8
+
+// traceHops returns the routers of the traceroute to host, nearest first,
+// and whether it had a traceroute. The final hop, the host itself, is left
+// out.
+func traceHops(host *NmapHost) ([]graphHop, bool) {
+	hops := append([]NmapHop(nil), host.Trace.Hop...)
+	if len(hops) == 0 {
+		return nil, false
+	}
+	sort.SliceStable(hops, func(i, j int) bool {
+		return atoi(hops[i].Ttl) < atoi(hops[j].Ttl)
+	})
+	routers := make([]graphHop, 0, len(hops)-1)
+	for _, hop := range hops[:len(hops)-1] {
+		routers = append(routers, graphHop{Addr: hop.Ipaddr, Name: hop.Host})
+	}
+	return routers, true
+}
+
+// dotString quotes s as a DOT string, keeping the \n line breaks of labels.
+func dotString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// writeDOT writes g to w as a Graphviz graph of the network topology: the
+// scanner, the routers of the traceroutes and the hosts, clustered by subnet
+// and labelled with their services. Hosts without a traceroute hang off the
+// scanner with a dashed edge.
+func writeDOT(w io.Writer, g exposureGraph) error {
+	services := make(map[string]graphService, len(g.Services))
+	for _, service := range g.Services {
+		services[service.Key] = service
+	}
+	hosts := make(map[string]bool, len(g.Hosts))
+	for _, host := range g.Hosts {
+		hosts[host.Addr] = true
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph topology {")
+	fmt.Fprintln(bw, "  rankdir=LR;")
+	fmt.Fprintln(bw, "  node [shape=box, fontname=\"Helvetica\", fontsize=10];")
+	fmt.Fprintln(bw, "  scanner [label=\"scanner\", shape=doublecircle];")
+
+	for _, subnet := range g.Subnets {
+		fmt.Fprintf(bw, "  subgraph %s {\n    label=%s;\n    style=dashed;\n", dotString("cluster_"+subnet), dotString(subnet))
+		for _, host := range g.Hosts {
+			if host.Subnet == subnet {
+				writeDOTHost(bw, host, services)
+			}
+		}
+		fmt.Fprintln(bw, "  }")
+	}
+	for _, host := range g.Hosts {
+		if host.Subnet == "" {
+			writeDOTHost(bw, host, services)
+		}
+	}
+
+	routers := make(map[string]bool)
+	edges := make(map[string]bool)
+	edge := func(from, to, attrs string) {
+		line := fmt.Sprintf("  %s -> %s%s;", dotString(from), dotString(to), attrs)
+		if !edges[line] {
+			edges[line] = true
+			fmt.Fprintln(bw, line)
+		}
+	}
+	for _, host := range g.Hosts {
+		if !host.Traced {
+			edge("scanner", host.Addr, " [style=dashed]")
+			continue
+		}
+		previous := "scanner"
+		for _, hop := range host.Hops {
+			if !hosts[hop.Addr] && !routers[hop.Addr] {
+				routers[hop.Addr] = true
+				label := hop.Addr
+				if hop.Name != "" {
+					label += "\n" + hop.Name
+				}
+				fmt.Fprintf(bw, "  %s [label=%s, shape=ellipse];\n", dotString(hop.Addr), dotString(label))
+			}
+			edge(previous, hop.Addr, "")
+			previous = hop.Addr
+		}
+		edge(previous, host.Addr, "")
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// writeDOTHost writes the node of host, labelled with its address, hostname
+// and first topologyServices services.
+func writeDOTHost(w io.Writer, host graphHost, services map[string]graphService) {
+	lines := []string{host.Addr}
+	if host.Hostname != "" {
+		lines = append(lines, host.Hostname)
+	}
+	if host.DC {
+		lines = append(lines, "domain controller")
+	}
+	for i, port := range host.Ports {
+		if i == topologyServices {
+			lines = append(lines, fmt.Sprintf("and %d more", len(host.Ports)-i))
+			break
+		}
+		service := services[port.Service]
+		line := fmt.Sprintf("%d/%s %s", port.Port, port.Protocol, service.Name)
+		if product := strings.TrimSpace(service.Product + " " + service.Version); product != "" {
+			line += " (" + product + ")"
+		}
+		lines = append(lines, line)
+	}
+	attrs := ""
+	if host.DC {
+		attrs = ", penwidth=2"
+	}
+	fmt.Fprintf(w, "    %s [label=%s%s];\n", dotString(host.Addr), dotString(strings.Join(lines, "\n")), attrs)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/pflag"
+)
+
+// triageFlags are the flags of the commands writing a triage table of the
+// ports of several services, such as web and smb.
+type triageFlags struct {
+	input  inputFlags
+	table  tableFlags
+	output outputFlags
+	format string
+}
+
+// register registers the flags of the triage table called name, whose rows
+// are grouped by groupBy unless --group-by is given.
+func (f *triageFlags) register(flags *pflag.FlagSet, name, groupBy string) {
+	f.input.register(flags, "")
+	f.table.registerFilters(flags)
+	f.table.registerRedaction(flags)
+	f.output.register(flags, fmt.Sprintf("The output file path (default %[1]s.html, or %[1]s.pdf/.docx)", name))
+	flags.StringVar(&f.format, "format", "html", "The output format: html, pdf or docx, or an export format such as csv written to stdout")
+	flag := flags.Lookup("group-by")
+	flag.DefValue = groupBy
+	flag.Value.Set(groupBy)
+}
+
+// runTriage writes the triage table called name of the ports selected by
+// match, with columns unless --columns were chosen. Like report, documents
+// are written to name.<ext> and other formats to stdout.
+func runTriage(f *triageFlags, args []string, name string, match func(port *NmapPort) bool, columns []string) error {
+	renderer, err := lookupRenderer(f.format)
+	if err != nil {
+		return err
+	}
+	nmapFiles, err := f.input.nmapFiles(args)
+	if err != nil {
+		return err
+	}
+	opts, err := f.table.options()
+	if err != nil {
+		return err
+	}
+	opts.ServiceName = name
+	opts.Match = match
+	if len(opts.Columns) == 0 {
+		opts.Columns = columns
+	}
+	// Documents are written to files, other formats to stdout.
+	document, isDocument := renderer.(documentRenderer)
+	var outputFilename string
+	if isDocument {
+		if outputFilename, err = f.output.filename(fmt.Sprintf("%s.%s", name, document.Extension())); err != nil {
+			return fmt.Errorf("invalid output path: %w", err)
+		}
+		opts.Screenshots.linkFrom(outputFilename)
+	}
+	tableData := GenerateTableData(nmapFiles, opts)
+	tableData.RowRecords = opts.GroupBy == "host"
+	if err := checkParseErrors(tableData.Errors); err != nil {
+		return err
+	}
+	tableData = opts.Redact.report(tableData)
+
+	if !isDocument {
+		if err := renderer.Render(tableData, os.Stdout); err != nil {
+			return err
+		}
+		return checkMatches(tableData)
+	}
+	if err := writeRendered(outputFilename, f.output.force, f.format, renderer, []ReportData{tableData}); err != nil {
+		return err
+	}
+	return checkMatches(tableData)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WalkOptions select the files read from the input directories.
+type WalkOptions struct {
+	// Extensions are the file name suffixes to read, e.g. ".xml". Gzipped files
+	// with these suffixes and zip archives are read as well.
+	Extensions []string
+	// Patterns are shell glob patterns matched against file names. When set
+	// they are used instead of Extensions.
+	Patterns []string
+	// SkipHidden skips files and directories whose name starts with a dot.
+	SkipHidden bool
+	// SkipSymlinks skips symlinked files. Symlinked directories are never
+	// descended into.
+	SkipSymlinks bool
+}
+
+// matches reports whether a file called name should be read.
+func (o WalkOptions) matches(name string) bool {
+	if o.SkipHidden && isHidden(name) {
+		return false
+	}
+	if len(o.Patterns) > 0 {
+		for _, pattern := range o.Patterns {
+			if ok, _ := filepath.Match(pattern, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+	if isArchive(name) {
+		return true
+	}
+	name = strings.TrimSuffix(name, ".gz")
+	for _, extension := range o.Extensions {
+		if strings.HasSuffix(name, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHidden reports whether name is a dot file or directory.
+func isHidden(name string) bool {
+	return len(name) > 1 && strings.HasPrefix(name, ".") && name != ".."
+}
+
+// splitList splits a comma separated flag value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// stdinPath is the input path that reads a scan from standard input, e.g.
+// nmap -oX - ... | nmapTables --service http -
+const stdinPath = "-"
+
+// FindInputFiles returns the files to read from paths. Directories are walked
+// recursively and filtered with opts; files are always read, so individual scans
+// can be passed regardless of their name. stdinPath is passed through as is, and
+// files found through more than one path are listed once.
+func FindInputFiles(paths []string, opts WalkOptions) ([]string, error) {
+	var files []string
+	readStdin := false
+	for _, path := range paths {
+		if path == stdinPath {
+			if readStdin {
+				return nil, errors.New("standard input (-) can only be read once")
+			}
+			readStdin = true
+			files = append(files, stdinPath)
+			continue
+		}
+		absPath, err := resolveAbsPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path: %w", err)
+		}
+		info, err := os.Stat(absPath)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, absPath)
+			continue
+		}
+		dirFiles, err := walkDir(absPath, opts)
+		if err != nil {
+			return nil, fmt.Errorf("Error getting files\nError: %w", err)
+		}
+		files = append(files, dirFiles...)
+	}
+	// Overlapping inputs, e.g. a directory and one of its subdirectories,
+	// list a file once.
+	seen := make(map[string]bool, len(files))
+	unique := files[:0]
+	for _, file := range files {
+		if !seen[file] {
+			seen[file] = true
+			unique = append(unique, file)
+		}
+	}
+	return unique, nil
+}
+
+// walkDir walks through dirPath and returns the absolute paths of the files
+// selected by opts.
+func walkDir(dirPath string, opts WalkOptions) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err // Return the error to stop the walk.
+		}
+		if info.IsDir() {
+			if opts.SkipHidden && path != dirPath && isHidden(info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if opts.SkipSymlinks && info.Mode()&os.ModeSymlink != 0 {
+			return nil
+		}
+		if opts.matches(info.Name()) {
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				return err // Return the error to stop the walk.
+			}
+			files = append(files, absPath)
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long to wait after the last file event before regenerating,
+// so that a file being written in several chunks is only parsed once.
+const watchDebounce = 500 * time.Millisecond
+
+// watchNmapDir parses nmapFiles, calls onUpdate with the result and then watches
+// dir (recursively) for files selected by opts being created, changed or
+// removed. Only the affected files are re-parsed before onUpdate is called
+// again. watchNmapDir blocks until the watcher fails.
+func watchNmapDir(dir string, opts WalkOptions, nmapFiles []string, onUpdate func(runs []Nmaprun, parseErrors []ParseError)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if opts.SkipHidden && path != dir && isHidden(info.Name()) {
+				return filepath.SkipDir
+			}
+			return watcher.Add(path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	parsed := make(map[string][]parsedScan)
+	failed := make(map[string][]ParseError)
+	for _, filePath := range nmapFiles {
+		parseInto(parsed, failed, filePath)
+	}
+	onUpdate(sortedRuns(parsed), sortedErrors(failed))
+	statusf("Watching %s for changes\n", dir)
+
+	pending := make(map[string]fsnotify.Op)
+	timer := time.NewTimer(watchDebounce)
+	timer.Stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if opts.SkipHidden && isHidden(filepath.Base(event.Name)) {
+						continue
+					}
+					if err := watcher.Add(event.Name); err != nil {
+						logger.Error("could not watch directory", "dir", event.Name, "err", err)
+					}
+					continue
+				}
+			}
+			if !opts.matches(filepath.Base(event.Name)) {
+				continue
+			}
+			pending[event.Name] |= event.Op
+			timer.Reset(watchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		case <-timer.C:
+			for filePath, op := range pending {
+				if op.Has(fsnotify.Remove) || op.Has(fsnotify.Rename) {
+					if _, err := os.Stat(filePath); err != nil {
+						delete(parsed, filePath)
+						delete(failed, filePath)
+						continue
+					}
+				}
+				parseInto(parsed, failed, filePath)
+			}
+			clear(pending)
+			onUpdate(sortedRuns(parsed), sortedErrors(failed))
+		}
+	}
+}
+
+// parseInto parses filePath and stores its runs in parsed and its errors in
+// failed. Both are stored for a truncated file with recovered hosts.
+func parseInto(parsed map[string][]parsedScan, failed map[string][]ParseError, filePath string) {
+	outputManifest.input(filePath)
+	scans, parseErrors := parseInput(filePath)
+	for i := range parseErrors {
+		logParseError(&parseErrors[i])
+	}
+	parsed[filePath] = scans
+	failed[filePath] = parseErrors
+}
+
+// sortedRuns returns the runs in parsed ordered by file path, without
+// duplicates.
+func sortedRuns(parsed map[string][]parsedScan) []Nmaprun {
+	paths := make([]string, 0, len(parsed))
+	for path := range parsed {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var scans []parsedScan
+	for _, path := range paths {
+		scans = append(scans, parsed[path]...)
+	}
+	return uniqueRuns(scans)
+}
+
+// sortedErrors returns the errors in failed ordered by file path.
+func sortedErrors(failed map[string][]ParseError) []ParseError {
+	var parseErrors []ParseError
+	for _, fileErrors := range failed {
+		parseErrors = append(parseErrors, fileErrors...)
+	}
+	sort.Slice(parseErrors, func(i, j int) bool {
+		return parseErrors[i].File < parseErrors[j].File
+	})
+	return parseErrors
+}
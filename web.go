@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// webServices are the nmap service names of web servers.
+var webServices = map[string]bool{
+	"http":       true,
+	"https":      true,
+	"http-alt":   true,
+	"https-alt":  true,
+	"http-proxy": true,
+	"http-mgmt":  true,
+}
+
+// webColumns are the default columns of the web triage table.
+var webColumns = []string{"url", "title", "server", "redirect", "methods"}
+
+// isWebPort reports whether port runs a web server.
+func isWebPort(port *NmapPort) bool {
+	return webServices[port.Service.Name]
+}
+
+// webURL returns the URL of the web server on port of hostPort, named by the
+// hostname when the host has one. HTTPS is assumed for SSL tunnels and https
+// services.
+func webURL(hostPort *HostPort, port *NmapPort) string {
+	scheme := "http"
+	if port.Service.Tunnel == "ssl" || strings.HasPrefix(port.Service.Name, "https") {
+		scheme = "https"
+	}
+	host := hostPort.Hostname
+	if host == "" {
+		host = hostPort.Addr
+	}
+	if (scheme == "http" && port.Portid != "80") || (scheme == "https" && port.Portid != "443") {
+		host = net.JoinHostPort(host, port.Portid)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	return (&url.URL{Scheme: scheme, Host: host, Path: "/"}).String()
+}
+
+// writeNucleiTargets writes the distinct URLs of the web servers of tableData
+// to w, one per line, ready to feed nuclei (-l), httpx or a screenshot tool such
+// as gowitness or aquatone.
+func writeNucleiTargets(tableData ReportData, w io.Writer) error {
+	seen := make(map[string]struct{})
+	var urls []string
+	for _, rows := range [][]ReportRow{tableData.Rows, tableData.Unverified} {
+		for _, row := range rows {
+			for _, host := range row.Hosts {
+				if host.URL == "" {
+					continue
+				}
+				if _, ok := seen[host.URL]; ok {
+					continue
+				}
+				seen[host.URL] = struct{}{}
+				urls = append(urls, host.URL)
+			}
+		}
+	}
+	sort.Strings(urls)
+	for _, u := range urls {
+		if _, err := fmt.Fprintln(w, u); err != nil {
+			return err
+		}
+	}
+	return nil
+}